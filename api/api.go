@@ -0,0 +1,625 @@
+// Package api serves a REST API for planning cleanups, reviewing and approving their plans,
+// and applying them, so that tools such as a platform portal can drive the cleaner. Every
+// plan and apply runs the same ECSClient pipeline as `ecs-task`.
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quintilesims/go-ecs-cleaner/ecsclient"
+	"github.com/quintilesims/go-ecs-cleaner/notify"
+	"github.com/quintilesims/go-ecs-cleaner/planner"
+)
+
+// The Statuses of a Plan.
+const (
+	Planning = "planning"
+	Planned  = "planned"
+	Approved = "approved"
+	Applied  = "applied"
+)
+
+// The Statuses of a Run, and of a Plan that couldn't be made.
+const (
+	Running   = "running"
+	Succeeded = "success"
+	Failed    = "failure"
+)
+
+// maxRecords is how many plans, and how many runs, a Server remembers; older ones are
+// forgotten.
+const maxRecords = 100
+
+// maxBodySize bounds the request bodies a Server reads.
+const maxBodySize = 1 << 20
+
+// Overrides change a plan's retention policy and safety limits from those the server was
+// started with. Unset fields are left as they were; ProtectTags are added to the server's.
+type Overrides struct {
+	Cutoff             *int              `json:"cutoff,omitempty"`
+	KeepBeforeActive   *int              `json:"keepBeforeActive,omitempty"`
+	OrphanKeep         *int              `json:"orphanKeep,omitempty"`
+	OrphanKeepDays     *int              `json:"orphanKeepDays,omitempty"`
+	ProtectTags        map[string]string `json:"protectTags,omitempty"`
+	MaxDeregistrations *int              `json:"maxDeregistrations,omitempty"`
+	MaxFamilyFraction  *float64          `json:"maxFamilyFraction,omitempty"`
+	MaxFraction        *float64          `json:"maxFraction,omitempty"`
+}
+
+// Validate returns an error if any of the Overrides is out of range.
+func (o Overrides) Validate() error {
+	ints := map[string]*int{
+		"cutoff":             o.Cutoff,
+		"keepBeforeActive":   o.KeepBeforeActive,
+		"orphanKeep":         o.OrphanKeep,
+		"orphanKeepDays":     o.OrphanKeepDays,
+		"maxDeregistrations": o.MaxDeregistrations,
+	}
+
+	for name, value := range ints {
+		if value != nil && *value < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+
+	fractions := map[string]*float64{
+		"maxFamilyFraction": o.MaxFamilyFraction,
+		"maxFraction":       o.MaxFraction,
+	}
+
+	for name, value := range fractions {
+		if value != nil && (*value < 0 || *value > 1) {
+			return fmt.Errorf("%s must be between 0 and 1", name)
+		}
+	}
+
+	for key := range o.ProtectTags {
+		if key == "" {
+			return fmt.Errorf("protectTags must not have an empty key")
+		}
+	}
+
+	return nil
+}
+
+// Apply sets the Overrides on flags.
+func (o Overrides) Apply(flags *ecsclient.Flags) {
+	setInt := func(flag *int, value *int) {
+		if value != nil {
+			*flag = *value
+		}
+	}
+
+	setInt(&flags.Cutoff, o.Cutoff)
+	setInt(&flags.KeepBeforeActive, o.KeepBeforeActive)
+	setInt(&flags.OrphanKeep, o.OrphanKeep)
+	setInt(&flags.OrphanKeepDays, o.OrphanKeepDays)
+	setInt(&flags.MaxDeregistrations, o.MaxDeregistrations)
+
+	if o.MaxFamilyFraction != nil {
+		flags.MaxFamilyFraction = *o.MaxFamilyFraction
+	}
+
+	if o.MaxFraction != nil {
+		flags.MaxFraction = *o.MaxFraction
+	}
+
+	if len(o.ProtectTags) > 0 {
+		protectTags := make(map[string]string)
+		for key, value := range flags.ProtectTags {
+			protectTags[key] = value
+		}
+
+		for key, value := range o.ProtectTags {
+			protectTags[key] = value
+		}
+
+		flags.ProtectTags = protectTags
+	}
+}
+
+// Plan is what a cleanup would deregister, under its Overrides, as of when it was planned.
+type Plan struct {
+	ID         string     `json:"id"`
+	Status     string     `json:"status"`
+	Overrides  Overrides  `json:"overrides"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	PlannedAt  *time.Time `json:"plannedAt,omitempty"`
+	ApprovedAt *time.Time `json:"approvedAt,omitempty"`
+	ApprovedBy string     `json:"approvedBy,omitempty"`
+	RunID      string     `json:"runId,omitempty"`
+	Error      string     `json:"error,omitempty"`
+
+	// LimitTripped is the safety limit the plan trips, if any; such a plan can't be approved.
+	LimitTripped string `json:"limitTripped,omitempty"`
+
+	Discovered int `json:"discovered"`
+	Kept       int `json:"kept"`
+	Pending    int `json:"pending"`
+
+	// Deregister is the Decision for each task definition the plan would deregister.
+	Deregister []planner.Decision `json:"deregister"`
+}
+
+// Run is the apply of an approved Plan.
+type Run struct {
+	ID         string          `json:"id"`
+	PlanID     string          `json:"planId"`
+	Status     string          `json:"status"`
+	StartedAt  time.Time       `json:"startedAt"`
+	FinishedAt *time.Time      `json:"finishedAt,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Summary    *notify.Summary `json:"summary,omitempty"`
+}
+
+// Server plans and applies cleanups for the API's requests. Only one apply runs at a time.
+// Plans and runs are kept in memory, so a restart forgets them.
+type Server struct {
+	// Token is the bearer token that every request but /healthz must carry.
+	Token string
+
+	// NewClient creates the ECSClient for a plan, or for an apply if apply is set, configured
+	// as the server was started; the Server sets the Plan's Overrides on its Flags. done is
+	// called once the client is finished with, e.g. to close its audit log, and an apply
+	// fails if it returns an error.
+	NewClient func(apply bool) (e *ecsclient.ECSClient, done func() error, err error)
+
+	// Now stamps plans and runs; it defaults to the current UTC time.
+	Now func() time.Time
+
+	mu       sync.Mutex
+	plans    []*Plan
+	runs     []*Run
+	planning *Plan
+	applying *Run
+	wg       sync.WaitGroup
+}
+
+// New creates a Server that authorizes requests with token, and creates clients with
+// newClient.
+func New(token string, newClient func(apply bool) (*ecsclient.ECSClient, func() error, error)) *Server {
+	return &Server{
+		Token:     token,
+		NewClient: newClient,
+		Now:       func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Wait waits for any plan or apply in progress to finish.
+func (s *Server) Wait() {
+	s.wg.Wait()
+}
+
+// Handler serves the API:
+//
+//	GET  /healthz              200 while the process is up; needs no token
+//	POST /plans                start planning with the Overrides in the body; 202 with the Plan,
+//	                           or 409 while another plan is being made
+//	GET  /plans/{id}           the Plan
+//	POST /plans/{id}/approve   approve a planned Plan, optionally {"approvedBy": "..."}
+//	POST /plans/{id}/apply     start applying an approved Plan; 202 with the Run, or 409
+//	                           while another apply is running
+//	GET  /runs/{id}            the Run
+//	GET  /history              every Run, newest first
+func (s *Server) Handler() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+
+	mux.Handle("/plans", s.authorize(http.HandlerFunc(s.servePlans)))
+	mux.Handle("/plans/", s.authorize(http.HandlerFunc(s.servePlan)))
+	mux.Handle("/runs/", s.authorize(http.HandlerFunc(s.serveRun)))
+	mux.Handle("/history", s.authorize(http.HandlerFunc(s.serveHistory)))
+
+	return mux
+}
+
+// authorize refuses requests that don't carry the Token.
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		token := strings.TrimPrefix(header, "Bearer ")
+
+		if s.Token == "" || token == header || subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="go-ecs-cleaner"`)
+			writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) servePlans(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodPost) {
+		return
+	}
+
+	var overrides Overrides
+	if err := readJSON(r, &overrides); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := overrides.Validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	if s.planning != nil {
+		writeError(w, http.StatusConflict, fmt.Sprintf("plan %s is still planning", s.planning.ID))
+		s.mu.Unlock()
+		return
+	}
+
+	plan := &Plan{ID: newID(), Status: Planning, Overrides: overrides, CreatedAt: s.Now()}
+	s.planning = plan
+	s.plans = append(s.plans, plan)
+	s.forgetPlans()
+	response := *plan
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.plan(plan)
+
+	w.Header().Set("Location", "/plans/"+plan.ID)
+	writeJSON(w, http.StatusAccepted, response)
+}
+
+// servePlan serves /plans/{id} and the actions on it.
+func (s *Server) servePlan(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/plans/"), "/")
+	if len(parts) > 2 || parts[0] == "" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+
+	action := ""
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+
+	switch action {
+	case "":
+		if !allow(w, r, http.MethodGet) {
+			return
+		}
+
+		s.mu.Lock()
+		plan := s.findPlan(parts[0])
+		var response Plan
+		if plan != nil {
+			response = *plan
+		}
+		s.mu.Unlock()
+
+		if plan == nil {
+			writeError(w, http.StatusNotFound, fmt.Sprintf("no plan %s", parts[0]))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, response)
+
+	case "approve":
+		if allow(w, r, http.MethodPost) {
+			s.approve(w, r, parts[0])
+		}
+
+	case "apply":
+		if allow(w, r, http.MethodPost) {
+			s.apply(w, parts[0])
+		}
+
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func (s *Server) approve(w http.ResponseWriter, r *http.Request, id string) {
+	var body struct {
+		ApprovedBy string `json:"approvedBy"`
+	}
+
+	if err := readJSON(r, &body); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plan := s.findPlan(id)
+	switch {
+	case plan == nil:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no plan %s", id))
+
+	case plan.Status != Planned:
+		writeError(w, http.StatusConflict, fmt.Sprintf("plan %s is %s, not %s", id, plan.Status, Planned))
+
+	case plan.LimitTripped != "":
+		writeError(w, http.StatusConflict, fmt.Sprintf("plan %s trips a safety limit: %s", id, plan.LimitTripped))
+
+	default:
+		now := s.Now()
+		plan.Status, plan.ApprovedAt, plan.ApprovedBy = Approved, &now, body.ApprovedBy
+		writeJSON(w, http.StatusOK, *plan)
+	}
+}
+
+func (s *Server) apply(w http.ResponseWriter, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plan := s.findPlan(id)
+	switch {
+	case plan == nil:
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no plan %s", id))
+		return
+
+	case plan.Status != Approved:
+		writeError(w, http.StatusConflict, fmt.Sprintf("plan %s is %s, not %s", id, plan.Status, Approved))
+		return
+
+	case s.applying != nil:
+		writeError(w, http.StatusConflict, fmt.Sprintf("run %s is still applying plan %s", s.applying.ID, s.applying.PlanID))
+		return
+	}
+
+	run := &Run{ID: newID(), PlanID: plan.ID, Status: Running, StartedAt: s.Now()}
+	plan.Status, plan.RunID = Applied, run.ID
+	s.applying = run
+	s.runs = append(s.runs, run)
+	if len(s.runs) > maxRecords {
+		s.runs = s.runs[len(s.runs)-maxRecords:]
+	}
+
+	approved := make([]string, len(plan.Deregister))
+	for i, decision := range plan.Deregister {
+		approved[i] = decision.ARN
+	}
+
+	s.wg.Add(1)
+	go s.run(run, plan.Overrides, approved)
+
+	w.Header().Set("Location", "/runs/"+run.ID)
+	writeJSON(w, http.StatusAccepted, *run)
+}
+
+func (s *Server) serveRun(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/runs/")
+
+	s.mu.Lock()
+	var response *Run
+	for _, run := range s.runs {
+		if run.ID == id {
+			copied := *run
+			response = &copied
+		}
+	}
+	s.mu.Unlock()
+
+	if response == nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("no run %s", id))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) serveHistory(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+
+	s.mu.Lock()
+	runs := make([]Run, len(s.runs))
+	for i, run := range s.runs {
+		runs[len(runs)-1-i] = *run
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, struct {
+		Runs []Run `json:"runs"`
+	}{runs})
+}
+
+// plan makes the Plan in the background.
+func (s *Server) plan(p *Plan) {
+	defer s.wg.Done()
+
+	plan, discovered, limitErr, err := s.makePlan(p.Overrides)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.planning = nil
+
+	now := s.Now()
+	p.PlannedAt = &now
+
+	if err != nil {
+		p.Status, p.Error = Failed, err.Error()
+		return
+	}
+
+	p.Status = Planned
+	p.Discovered = discovered
+	p.Kept = len(plan.ARNs(planner.Keep))
+	p.Pending = len(plan.ARNs(planner.Pending))
+
+	p.Deregister = []planner.Decision{}
+	for _, decision := range plan.Decisions {
+		if decision.Action == planner.Deregister {
+			p.Deregister = append(p.Deregister, decision)
+		}
+	}
+
+	if limitErr != nil {
+		p.LimitTripped = limitErr.Error()
+	}
+}
+
+// makePlan discovers what's in use and plans a cleanup under the Overrides, as a dry run of
+// CleanupTaskDefinitions would, and checks the plan against the safety limits.
+func (s *Server) makePlan(overrides Overrides) (plan *planner.Plan, discovered int, limitErr error, err error) {
+	e, done, err := s.NewClient(false)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	defer done()
+
+	overrides.Apply(&e.Flags)
+
+	inv, err := e.Discover()
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	plan = e.PlanTaskDefinitions(inv)
+	return plan, len(inv.TaskDefinitionARNs), plan.CheckLimits(e.Limits()), nil
+}
+
+// run applies an approved plan in the background. The cleanup is planned afresh, and only
+// the approved task definitions that it would still deregister are deregistered.
+func (s *Server) run(run *Run, overrides Overrides, approved []string) {
+	defer s.wg.Done()
+
+	summary, err := s.cleanup(overrides, approved)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.Now()
+	run.FinishedAt, run.Summary, run.Status = &now, summary, Succeeded
+	if err != nil {
+		run.Status, run.Error = Failed, err.Error()
+	}
+
+	s.applying = nil
+}
+
+func (s *Server) cleanup(overrides Overrides, approved []string) (summary *notify.Summary, err error) {
+	e, done, err := s.NewClient(true)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		if doneErr := done(); err == nil {
+			err = doneErr
+		}
+	}()
+
+	overrides.Apply(&e.Flags)
+	e.Flags.Apply = true
+
+	isApproved := make(map[string]bool)
+	for _, arn := range approved {
+		isApproved[arn] = true
+	}
+
+	e.Review = func(taskDefinitionARNs []string) ([]string, error) {
+		var selected []string
+		for _, arn := range taskDefinitionARNs {
+			if isApproved[arn] {
+				selected = append(selected, arn)
+			}
+		}
+
+		return selected, nil
+	}
+
+	err = e.CleanupTaskDefinitions()
+	return e.Summary(), err
+}
+
+// findPlan returns the Plan with the id, or nil. The caller must hold mu.
+func (s *Server) findPlan(id string) *Plan {
+	for _, plan := range s.plans {
+		if plan.ID == id {
+			return plan
+		}
+	}
+
+	return nil
+}
+
+// forgetPlans drops the oldest plans beyond maxRecords, other than any still planning. The
+// caller must hold mu.
+func (s *Server) forgetPlans() {
+	excess := len(s.plans) - maxRecords
+	if excess <= 0 {
+		return
+	}
+
+	kept := s.plans[:0]
+	for _, plan := range s.plans {
+		if excess > 0 && plan.Status != Planning {
+			excess--
+			continue
+		}
+
+		kept = append(kept, plan)
+	}
+
+	s.plans = kept
+}
+
+// allow reports whether the request has the method, and refuses it if not.
+func allow(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+
+	w.Header().Set("Allow", method)
+	writeError(w, http.StatusMethodNotAllowed, fmt.Sprintf("%s not allowed", r.Method))
+	return false
+}
+
+// readJSON decodes the request's body, if it has one, into v, refusing unknown fields so that
+// a misspelt override isn't silently ignored.
+func readJSON(r *http.Request, v interface{}) error {
+	decoder := json.NewDecoder(io.LimitReader(r.Body, maxBodySize))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil && err != io.EOF {
+		return fmt.Errorf("invalid request body: %v", err)
+	}
+
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, struct {
+		Error string `json:"error"`
+	}{message})
+}
+
+// newID returns a random ID for a plan or run.
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
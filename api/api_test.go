@@ -0,0 +1,272 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jpillora/backoff"
+	"github.com/quintilesims/go-ecs-cleaner/ecsclient"
+	"github.com/quintilesims/go-ecs-cleaner/ecsfake"
+)
+
+const token = "secret"
+
+// setupServer returns a Server whose clients all clean up the same fake, with a cutoff of 5
+// unless a plan overrides it and families no service uses retired, and a stand-in serving it,
+// to be closed by the caller.
+func setupServer() (*Server, *ecsfake.ECS, *httptest.Server) {
+	fake := ecsfake.New("000000000000", "us-east-1")
+
+	s := New(token, func(apply bool) (*ecsclient.ECSClient, func() error, error) {
+		e := ecsclient.NewECSClient()
+		e.Flags.Quiet = true
+		e.Flags.Cutoff = 5
+		e.Flags.RetireFamilies = true
+		e.Backoff = &backoff.Backoff{Min: time.Millisecond, Max: 2 * time.Millisecond}
+		e.Svc = fake
+
+		return e, func() error { return nil }, nil
+	})
+
+	return s, fake, httptest.NewServer(s.Handler())
+}
+
+// do sends a request with the token, and decodes the response's body into v, if set.
+func do(t *testing.T, method, url, body string, v interface{}) int {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return resp.StatusCode
+}
+
+func Test_Authorize(t *testing.T) {
+	_, _, standIn := setupServer()
+	defer standIn.Close()
+
+	for _, header := range []string{"", "secret", "Bearer wrong", "Basic c2VjcmV0"} {
+		req, _ := http.NewRequest(http.MethodGet, standIn.URL+"/history", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Expected 401 for %q, got %d\n", header, resp.StatusCode)
+		}
+	}
+
+	resp, err := http.Get(standIn.URL + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected /healthz to need no token, got %d\n", resp.StatusCode)
+	}
+
+	if status := do(t, http.MethodGet, standIn.URL+"/history", "", nil); status != http.StatusOK {
+		t.Errorf("Expected 200 with the token, got %d\n", status)
+	}
+}
+
+func Test_PlanApproveApply(t *testing.T) {
+	s, fake, standIn := setupServer()
+	defer standIn.Close()
+
+	arns := fake.AddTaskDefinitions("web", 5)
+	fake.AddService("cluster", "web", arns[4], 1)
+
+	var plan Plan
+	if status := do(t, http.MethodPost, standIn.URL+"/plans", `{"cutoff": 2}`, &plan); status != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d\n", status)
+	}
+
+	s.Wait()
+	do(t, http.MethodGet, standIn.URL+"/plans/"+plan.ID, "", &plan)
+
+	if plan.Status != Planned || plan.Discovered != 5 || len(plan.Deregister) != 2 {
+		t.Fatalf("Expected a plan deregistering 2 of 5, got %+v\n", plan)
+	}
+
+	if status := do(t, http.MethodPost, standIn.URL+"/plans/"+plan.ID+"/apply", "", nil); status != http.StatusConflict {
+		t.Errorf("Expected an unapproved plan not to apply, got %d\n", status)
+	}
+
+	// revisions registered since aren't in the approved plan, so aren't deregistered
+	fake.AddTaskDefinitions("web", 2)
+
+	do(t, http.MethodPost, standIn.URL+"/plans/"+plan.ID+"/approve", `{"approvedBy": "alice"}`, &plan)
+	if plan.Status != Approved || plan.ApprovedBy != "alice" {
+		t.Fatalf("Expected the plan approved by alice, got %+v\n", plan)
+	}
+
+	var run Run
+	if status := do(t, http.MethodPost, standIn.URL+"/plans/"+plan.ID+"/apply", "", &run); status != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d\n", status)
+	}
+
+	s.Wait()
+	do(t, http.MethodGet, standIn.URL+"/runs/"+run.ID, "", &run)
+
+	if run.Status != Succeeded || run.Summary == nil || run.Summary.Counts.Deregistered != 2 {
+		t.Errorf("Expected a successful run deregistering 2, got %+v\n", run)
+	}
+
+	expected := []string{arns[0], arns[1]}
+	sort.Strings(expected)
+
+	if result := fake.TaskDefinitionARNs("INACTIVE"); !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, got %v\n", expected, result)
+	}
+
+	if status := do(t, http.MethodPost, standIn.URL+"/plans/"+plan.ID+"/apply", "", nil); status != http.StatusConflict {
+		t.Errorf("Expected an applied plan not to apply again, got %d\n", status)
+	}
+
+	var history struct {
+		Runs []Run `json:"runs"`
+	}
+
+	do(t, http.MethodGet, standIn.URL+"/history", "", &history)
+	if len(history.Runs) != 1 || history.Runs[0].ID != run.ID {
+		t.Errorf("Expected the run in the history, got %+v\n", history.Runs)
+	}
+}
+
+func Test_OneApplyAtATime(t *testing.T) {
+	s, fake, standIn := setupServer()
+	defer standIn.Close()
+	fake.AddTaskDefinitions("web", 3)
+
+	newClient := s.NewClient
+	release := make(chan struct{})
+	s.NewClient = func(apply bool) (*ecsclient.ECSClient, func() error, error) {
+		if apply {
+			<-release
+		}
+
+		return newClient(apply)
+	}
+
+	approvedPlan := func() string {
+		var plan Plan
+		do(t, http.MethodPost, standIn.URL+"/plans", "", &plan)
+		s.Wait()
+
+		if status := do(t, http.MethodPost, standIn.URL+"/plans/"+plan.ID+"/approve", "", nil); status != http.StatusOK {
+			t.Fatalf("Expected 200, got %d\n", status)
+		}
+
+		return plan.ID
+	}
+
+	first, second := approvedPlan(), approvedPlan()
+
+	if status := do(t, http.MethodPost, standIn.URL+"/plans/"+first+"/apply", "", nil); status != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d\n", status)
+	}
+
+	if status := do(t, http.MethodPost, standIn.URL+"/plans/"+second+"/apply", "", nil); status != http.StatusConflict {
+		t.Errorf("Expected a second apply to be refused, got %d\n", status)
+	}
+
+	close(release)
+	s.Wait()
+
+	if status := do(t, http.MethodPost, standIn.URL+"/plans/"+second+"/apply", "", nil); status != http.StatusAccepted {
+		t.Errorf("Expected an apply once the first finished, got %d\n", status)
+	}
+
+	s.Wait()
+}
+
+func Test_OnePlanAtATime(t *testing.T) {
+	s, fake, standIn := setupServer()
+	defer standIn.Close()
+	fake.AddTaskDefinitions("web", 3)
+
+	newClient := s.NewClient
+	release := make(chan struct{})
+	s.NewClient = func(apply bool) (*ecsclient.ECSClient, func() error, error) {
+		<-release
+		return newClient(apply)
+	}
+
+	if status := do(t, http.MethodPost, standIn.URL+"/plans", "", nil); status != http.StatusAccepted {
+		t.Fatalf("Expected 202, got %d\n", status)
+	}
+
+	if status := do(t, http.MethodPost, standIn.URL+"/plans", "", nil); status != http.StatusConflict {
+		t.Errorf("Expected a second plan to be refused, got %d\n", status)
+	}
+
+	close(release)
+	s.Wait()
+
+	if status := do(t, http.MethodPost, standIn.URL+"/plans", "", nil); status != http.StatusAccepted {
+		t.Errorf("Expected a plan once the first finished, got %d\n", status)
+	}
+
+	s.Wait()
+}
+
+func Test_PlanRefusals(t *testing.T) {
+	s, fake, standIn := setupServer()
+	defer standIn.Close()
+	fake.AddTaskDefinitions("web", 3)
+
+	for _, body := range []string{`{"cutoff": -1}`, `{"maxFraction": 2}`, `{"cutof": 2}`, `{"protectTags": {"": "x"}}`, `[`} {
+		if status := do(t, http.MethodPost, standIn.URL+"/plans", body, nil); status != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d\n", body, status)
+		}
+	}
+
+	var plan Plan
+	do(t, http.MethodPost, standIn.URL+"/plans", `{"maxDeregistrations": 1}`, &plan)
+	s.Wait()
+
+	do(t, http.MethodGet, standIn.URL+"/plans/"+plan.ID, "", &plan)
+	if plan.LimitTripped == "" {
+		t.Fatalf("Expected the plan to trip max-deregistrations, got %+v\n", plan)
+	}
+
+	if status := do(t, http.MethodPost, standIn.URL+"/plans/"+plan.ID+"/approve", "", nil); status != http.StatusConflict {
+		t.Errorf("Expected a plan that trips a limit not to be approved, got %d\n", status)
+	}
+
+	if status := do(t, http.MethodGet, standIn.URL+"/plans/missing", "", nil); status != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d\n", status)
+	}
+
+	if status := do(t, http.MethodGet, standIn.URL+"/plans", "", nil); status != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d\n", status)
+	}
+}
@@ -0,0 +1,198 @@
+// Package archive keeps a copy of each task definition the cleaner deregisters, so that it can
+// be inspected or restored after ECS has forgotten it. A task definition is stored as the
+// full `DescribeTaskDefinition` output, tags included, in the ECS API's own JSON shape, under
+// "<account>/<region>/<family>/<revision>.json". Each run also writes a manifest under
+// "<account>/<region>/runs/<run>.json" listing what it archived and what became of it.
+package archive
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/quintilesims/go-ecs-cleaner/planner"
+)
+
+// The Statuses of a manifest Entry.
+const (
+	// Archived: written to the archive, but not deregistered.
+	Archived = "archived"
+	// Deregistered: written to the archive, then deregistered.
+	Deregistered = "deregistered"
+	// Failed: written to the archive, but deregistering it failed.
+	Failed = "failed"
+)
+
+// Manifest records one run's archived task definitions.
+type Manifest struct {
+	Run        string    `json:"run"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	Entries    []Entry   `json:"entries"`
+}
+
+// Entry records one archived task definition.
+type Entry struct {
+	ARN    string `json:"arn"`
+	Key    string `json:"key"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Archiver writes task definitions and a manifest for one run to a Store.
+type Archiver struct {
+	Store Store
+
+	// Now stamps the manifest; it defaults to the current UTC time.
+	Now func() time.Time
+
+	mu       sync.Mutex
+	account  string
+	region   string
+	manifest Manifest
+}
+
+// New creates an Archiver for a run starting now.
+func New(store Store) *Archiver {
+	a := &Archiver{
+		Store: store,
+		Now:   func() time.Time { return time.Now().UTC() },
+	}
+
+	a.manifest.StartedAt = a.Now()
+	a.manifest.Run = RunName(a.manifest.StartedAt)
+
+	return a
+}
+
+// RunName names a run starting at `t`, to the millisecond, with a random suffix so that runs
+// starting together don't share a manifest. Names sort by start time.
+func RunName(t time.Time) string {
+	suffix := make([]byte, 3)
+	rand.Read(suffix)
+
+	return fmt.Sprintf("%s-%x", t.Format("20060102T150405.000Z"), suffix)
+}
+
+// Run returns the name of the Archiver's run, as its manifest records it.
+func (a *Archiver) Run() string {
+	return a.manifest.Run
+}
+
+// Key returns where a task definition is archived.
+func Key(arn string) (string, error) {
+	parsed, err := planner.ParseTaskDefinitionARN(arn)
+	if err != nil {
+		return "", err
+	}
+
+	return key(parsed), nil
+}
+
+func key(parsed planner.TaskDefinitionARN) string {
+	return fmt.Sprintf("%s/%s/%s/%d.json", parsed.Account, parsed.Region, parsed.Family, parsed.Revision)
+}
+
+// Save writes a task definition, as described by `DescribeTaskDefinition`, to the archive.
+func (a *Archiver) Save(output *ecs.DescribeTaskDefinitionOutput) error {
+	if output.TaskDefinition == nil {
+		return fmt.Errorf("archive: no task definition to save")
+	}
+
+	arn := aws.StringValue(output.TaskDefinition.TaskDefinitionArn)
+	parsed, err := planner.ParseTaskDefinitionARN(arn)
+	if err != nil {
+		return err
+	}
+
+	body, err := Encode(output)
+	if err != nil {
+		return err
+	}
+
+	if err := a.Store.Put(key(parsed), body); err != nil {
+		return fmt.Errorf("archive: unable to write %s: %v", key(parsed), err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.account, a.region = parsed.Account, parsed.Region
+	a.manifest.Entries = append(a.manifest.Entries, Entry{ARN: arn, Key: key(parsed), Status: Archived})
+
+	return nil
+}
+
+// Record notes what became of a task definition that was saved; `err` is recorded for the
+// Failed status.
+func (a *Archiver) Record(arn, status string, err error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for i := range a.manifest.Entries {
+		if a.manifest.Entries[i].ARN == arn {
+			a.manifest.Entries[i].Status = status
+			if err != nil {
+				a.manifest.Entries[i].Error = err.Error()
+			}
+		}
+	}
+}
+
+// WriteManifest writes the run's manifest, and returns its key. Nothing is written if nothing
+// was saved.
+func (a *Archiver) WriteManifest() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if len(a.manifest.Entries) == 0 {
+		return "", nil
+	}
+
+	a.manifest.FinishedAt = a.Now()
+
+	body, err := json.MarshalIndent(a.manifest, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	manifestKey := fmt.Sprintf("%s/%s/runs/%s.json", a.account, a.region, a.manifest.Run)
+	if err := a.Store.Put(manifestKey, append(body, '\n')); err != nil {
+		return "", fmt.Errorf("archive: unable to write %s: %v", manifestKey, err)
+	}
+
+	return manifestKey, nil
+}
+
+// Encode renders `DescribeTaskDefinition` output as indented JSON in the ECS API's shape, as
+// `aws ecs describe-task-definition` prints it.
+func Encode(output *ecs.DescribeTaskDefinitionOutput) ([]byte, error) {
+	body, err := jsonutil.BuildJSON(output)
+	if err != nil {
+		return nil, err
+	}
+
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, body, "", "  "); err != nil {
+		return nil, err
+	}
+
+	indented.WriteByte('\n')
+	return indented.Bytes(), nil
+}
+
+// Decode parses a task definition written by Encode.
+func Decode(body []byte) (*ecs.DescribeTaskDefinitionOutput, error) {
+	var output ecs.DescribeTaskDefinitionOutput
+	if err := jsonutil.UnmarshalJSON(&output, bytes.NewReader(body)); err != nil {
+		return nil, fmt.Errorf("archive: unable to decode task definition: %v", err)
+	}
+
+	return &output, nil
+}
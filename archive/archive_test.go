@@ -0,0 +1,245 @@
+package archive
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+const testARN = "arn:aws:ecs:us-west-2:000000000000:task-definition/web:3"
+
+func testOutput() *ecs.DescribeTaskDefinitionOutput {
+	return &ecs.DescribeTaskDefinitionOutput{
+		TaskDefinition: &ecs.TaskDefinition{
+			ContainerDefinitions: []*ecs.ContainerDefinition{{
+				Image:  aws.String("nginx:1.25"),
+				Memory: aws.Int64(256),
+				Name:   aws.String("web"),
+			}},
+			Family:            aws.String("web"),
+			RegisteredAt:      aws.Time(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)),
+			Revision:          aws.Int64(3),
+			Status:            aws.String(ecs.TaskDefinitionStatusActive),
+			TaskDefinitionArn: aws.String(testARN),
+		},
+		Tags: []*ecs.Tag{{Key: aws.String("team"), Value: aws.String("web")}},
+	}
+}
+
+func Test_EncodeDecode(t *testing.T) {
+	body, err := Encode(testOutput())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the ECS API's own field names, as `aws ecs describe-task-definition` prints them
+	for _, field := range []string{`"taskDefinitionArn"`, `"containerDefinitions"`, `"tags"`} {
+		if !bytes.Contains(body, []byte(field)) {
+			t.Errorf("Expected %s in:\n%s", field, body)
+		}
+	}
+
+	result, err := Decode(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := testOutput(); !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, got %v\n", expected, result)
+	}
+}
+
+func Test_Archiver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a := New(DirStore(dir))
+	a.manifest.Run = "run0"
+
+	if err := a.Save(testOutput()); err != nil {
+		t.Fatal(err)
+	}
+
+	a.Record(testARN, Failed, errors.New("boom"))
+
+	key, err := a.WriteManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "000000000000/us-west-2/runs/run0.json"; key != expected {
+		t.Errorf("Expected manifest at %s, got %s\n", expected, key)
+	}
+
+	body, err := DirStore(dir).Get("000000000000/us-west-2/web/3.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Decode(body); err != nil {
+		t.Error(err)
+	}
+
+	body, err = DirStore(dir).Get(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Entry{{ARN: testARN, Key: "000000000000/us-west-2/web/3.json", Status: Failed, Error: "boom"}}
+	if !reflect.DeepEqual(expected, manifest.Entries) {
+		t.Errorf("Expected %v, got %v\n", expected, manifest.Entries)
+	}
+}
+
+func Test_Archiver_Empty(t *testing.T) {
+	if key, err := New(DirStore("does-not-exist")).WriteManifest(); key != "" || err != nil {
+		t.Errorf("Expected no manifest for an empty run, got %q, %v\n", key, err)
+	}
+}
+
+func Test_RunName(t *testing.T) {
+	at := time.Date(2026, 1, 1, 0, 0, 0, 123456789, time.UTC)
+
+	// runs starting in the same millisecond still get their own manifests
+	first, second := RunName(at), RunName(at)
+	if first == second {
+		t.Errorf("Expected distinct run names, got %q twice\n", first)
+	}
+
+	if !strings.HasPrefix(first, "20260101T000000.123Z-") {
+		t.Errorf("Expected a millisecond timestamp, got %q\n", first)
+	}
+}
+
+type fakeS3 struct {
+	s3iface.S3API
+	objects map[string][]byte
+}
+
+func (f *fakeS3) PutObject(input *s3.PutObjectInput) (*s3.PutObjectOutput, error) {
+	body, err := ioutil.ReadAll(input.Body)
+	f.objects[*input.Bucket+"/"+*input.Key] = body
+	return &s3.PutObjectOutput{}, err
+}
+
+func (f *fakeS3) GetObject(input *s3.GetObjectInput) (*s3.GetObjectOutput, error) {
+	return &s3.GetObjectOutput{Body: ioutil.NopCloser(bytes.NewReader(f.objects[*input.Bucket+"/"+*input.Key]))}, nil
+}
+
+func Test_S3Store(t *testing.T) {
+	svc := &fakeS3{objects: make(map[string][]byte)}
+
+	store, err := NewS3Store(svc, "s3://bucket/some/prefix/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Put("a/b.json", []byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := svc.objects["bucket/some/prefix/a/b.json"]; !ok {
+		t.Errorf("Expected the object under the prefix, got %v\n", svc.objects)
+	}
+
+	if body, err := store.Get("a/b.json"); err != nil || string(body) != "{}" {
+		t.Errorf("Expected to read back {}, got %q, %v\n", body, err)
+	}
+
+	for _, rawURL := range []string{"bucket/prefix", "https://bucket/prefix", "s3:///prefix"} {
+		if _, err := NewS3Store(svc, rawURL); err == nil {
+			t.Errorf("Expected an error for %q\n", rawURL)
+		}
+	}
+}
+
+func Test_Load(t *testing.T) {
+	dir, err := ioutil.TempDir("", "archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	a := New(DirStore(dir))
+	a.manifest.Run = "run0"
+
+	if err := a.Save(testOutput()); err != nil {
+		t.Fatal(err)
+	}
+
+	a.Record(testARN, Deregistered, nil)
+
+	key, err := a.WriteManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Load(DirStore(dir), testARN)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := testOutput(); !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, got %v\n", expected, result)
+	}
+
+	manifest, err := LoadManifest(DirStore(dir), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(manifest.Entries) != 1 || manifest.Entries[0].Status != Deregistered {
+		t.Errorf("Expected one deregistered entry, got %v\n", manifest.Entries)
+	}
+
+	if _, err := Load(DirStore(dir), "arn:aws:ecs:us-west-2:000000000000:task-definition/web:4"); err == nil {
+		t.Error("Expected an error for a task definition not in the archive")
+	}
+}
+
+func Test_RegisterInput(t *testing.T) {
+	output := testOutput()
+	output.TaskDefinition.Cpu = aws.String("256")
+	output.Tags = append(output.Tags,
+		&ecs.Tag{Key: aws.String("ecs-cleaner:pending-deregistration"), Value: aws.String("2026-01-01T00:00:00Z")},
+		&ecs.Tag{Key: aws.String("aws:cloudformation:stack-name"), Value: aws.String("web")},
+	)
+
+	input := RegisterInput(output)
+
+	if aws.StringValue(input.Family) != "web" || aws.StringValue(input.Cpu) != "256" {
+		t.Errorf("Expected family web with cpu 256, got %v\n", input)
+	}
+
+	if !reflect.DeepEqual(output.TaskDefinition.ContainerDefinitions, input.ContainerDefinitions) {
+		t.Errorf("Expected %v, got %v\n", output.TaskDefinition.ContainerDefinitions, input.ContainerDefinitions)
+	}
+
+	expected := []*ecs.Tag{{Key: aws.String("team"), Value: aws.String("web")}}
+	if !reflect.DeepEqual(expected, input.Tags) {
+		t.Errorf("Expected %v, got %v\n", expected, input.Tags)
+	}
+
+	if err := input.Validate(); err != nil {
+		t.Error(err)
+	}
+}
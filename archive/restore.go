@@ -0,0 +1,79 @@
+package archive
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/quintilesims/go-ecs-cleaner/planner"
+)
+
+// Load reads an archived task definition from a Store by its ARN.
+func Load(store Store, arn string) (*ecs.DescribeTaskDefinitionOutput, error) {
+	key, err := Key(arn)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := store.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("archive: unable to read %s: %v", key, err)
+	}
+
+	return Decode(body)
+}
+
+// LoadManifest reads a run's manifest from a Store.
+func LoadManifest(store Store, key string) (*Manifest, error) {
+	body, err := store.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("archive: unable to read %s: %v", key, err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, fmt.Errorf("archive: unable to decode manifest %s: %v", key, err)
+	}
+
+	return &manifest, nil
+}
+
+// RegisterInput builds the `RegisterTaskDefinition` request that registers an archived task
+// definition again, with the same settings and tags. Registering it creates a new revision of
+// the family. The cleaner's own pending-deregistration tag and tags with the reserved "aws:"
+// prefix are left out.
+func RegisterInput(output *ecs.DescribeTaskDefinitionOutput) *ecs.RegisterTaskDefinitionInput {
+	td := output.TaskDefinition
+
+	input := &ecs.RegisterTaskDefinitionInput{
+		ContainerDefinitions:    td.ContainerDefinitions,
+		Cpu:                     td.Cpu,
+		EphemeralStorage:        td.EphemeralStorage,
+		ExecutionRoleArn:        td.ExecutionRoleArn,
+		Family:                  td.Family,
+		InferenceAccelerators:   td.InferenceAccelerators,
+		IpcMode:                 td.IpcMode,
+		Memory:                  td.Memory,
+		NetworkMode:             td.NetworkMode,
+		PidMode:                 td.PidMode,
+		PlacementConstraints:    td.PlacementConstraints,
+		ProxyConfiguration:      td.ProxyConfiguration,
+		RequiresCompatibilities: td.RequiresCompatibilities,
+		RuntimePlatform:         td.RuntimePlatform,
+		TaskRoleArn:             td.TaskRoleArn,
+		Volumes:                 td.Volumes,
+	}
+
+	for _, tag := range output.Tags {
+		key := aws.StringValue(tag.Key)
+		if key == planner.PendingTag || strings.HasPrefix(key, "aws:") {
+			continue
+		}
+
+		input.Tags = append(input.Tags, tag)
+	}
+
+	return input
+}
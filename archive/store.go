@@ -0,0 +1,83 @@
+package archive
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+// Store saves archived files under slash-separated keys.
+type Store interface {
+	Put(key string, body []byte) error
+	Get(key string) ([]byte, error)
+}
+
+// DirStore keeps archived files in a local directory.
+type DirStore string
+
+// Put writes a file, creating its parent directories.
+func (d DirStore) Put(key string, body []byte) error {
+	name := filepath.Join(string(d), filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(name, body, 0644)
+}
+
+// Get reads a file.
+func (d DirStore) Get(key string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(string(d), filepath.FromSlash(key)))
+}
+
+// S3Store keeps archived files in an S3 bucket, under an optional key prefix.
+type S3Store struct {
+	Svc    s3iface.S3API
+	Bucket string
+	Prefix string
+}
+
+// NewS3Store creates an S3Store from an "s3://bucket/prefix" URL.
+func NewS3Store(svc s3iface.S3API, rawURL string) (*S3Store, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "s3" || u.Host == "" {
+		return nil, fmt.Errorf("invalid S3 URL %q: expected s3://bucket/prefix", rawURL)
+	}
+
+	return &S3Store{Svc: svc, Bucket: u.Host, Prefix: strings.Trim(u.Path, "/")}, nil
+}
+
+// Put uploads an object.
+func (s *S3Store) Put(key string, body []byte) error {
+	_, err := s.Svc.PutObject(&s3.PutObjectInput{
+		Body:        bytes.NewReader(body),
+		Bucket:      aws.String(s.Bucket),
+		ContentType: aws.String("application/json"),
+		Key:         aws.String(path.Join(s.Prefix, key)),
+	})
+
+	return err
+}
+
+// Get downloads an object.
+func (s *S3Store) Get(key string) ([]byte, error) {
+	output, err := s.Svc.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(path.Join(s.Prefix, key)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer output.Body.Close()
+
+	return ioutil.ReadAll(output.Body)
+}
@@ -0,0 +1,160 @@
+// Package audit keeps an append-only record of what the cleaner does to task definitions: one
+// JSON line per planned, completed, failed or skipped deregistration, stamped with the run and
+// the AWS identity that made it. Unlike the rest of the cleaner's output, it is never
+// silenced.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/quintilesims/go-ecs-cleaner/archive"
+	"github.com/quintilesims/go-ecs-cleaner/planner"
+)
+
+// The Actions of an Event.
+const (
+	// Plan: the plan would deregister the task definition, now or once its grace period ends.
+	Plan = "plan"
+	// Deregister: the task definition was deregistered.
+	Deregister = "deregister"
+	// Failure: deregistering the task definition, or archiving it first, failed.
+	Failure = "failure"
+	// Skip: the task definition was planned for deregistration, then left alone.
+	Skip = "skip"
+)
+
+// Identity is the AWS principal a run acts as, as `sts get-caller-identity` reports it.
+type Identity struct {
+	Account string `json:"account"`
+	ARN     string `json:"arn"`
+	UserID  string `json:"userId"`
+}
+
+// CallerIdentity looks up the principal whose credentials svc signs requests with.
+func CallerIdentity(svc stsiface.STSAPI) (Identity, error) {
+	output, err := svc.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return Identity{}, fmt.Errorf("audit: unable to get caller identity: %v", err)
+	}
+
+	return Identity{
+		Account: aws.StringValue(output.Account),
+		ARN:     aws.StringValue(output.Arn),
+		UserID:  aws.StringValue(output.UserId),
+	}, nil
+}
+
+// Event is one line of the audit log.
+type Event struct {
+	Time      time.Time        `json:"time"`
+	Run       string           `json:"run"`
+	Caller    string           `json:"caller"`
+	Account   string           `json:"account"`
+	Region    string           `json:"region"`
+	Action    string           `json:"action"`
+	ARN       string           `json:"arn"`
+	Decision  planner.Action   `json:"decision,omitempty"`
+	Reasons   []planner.Reason `json:"reasons,omitempty"`
+	RequestID string           `json:"requestId,omitempty"`
+	Detail    string           `json:"detail,omitempty"`
+}
+
+// Logger writes one run's Events to a Sink. Every Event for a task definition carries the
+// decision and reasons that Plan recorded for it.
+type Logger struct {
+	Sink     Sink
+	Identity Identity
+	Region   string
+
+	// Run names the run; it defaults to the start time, in the form the archive uses.
+	Run string
+
+	// Now stamps each Event; it defaults to the current UTC time.
+	Now func() time.Time
+
+	mu        sync.Mutex
+	decisions map[string]planner.Decision
+}
+
+// New creates a Logger for a run starting now.
+func New(sink Sink, identity Identity, region string) *Logger {
+	l := &Logger{
+		Sink:      sink,
+		Identity:  identity,
+		Region:    region,
+		Now:       func() time.Time { return time.Now().UTC() },
+		decisions: make(map[string]planner.Decision),
+	}
+
+	l.Run = archive.RunName(l.Now())
+
+	return l
+}
+
+// Plan logs a Plan event for each decision that isn't to keep the task definition, and
+// remembers every decision for the Events that follow.
+func (l *Logger) Plan(decisions []planner.Decision) error {
+	l.mu.Lock()
+	for _, decision := range decisions {
+		l.decisions[decision.ARN] = decision
+	}
+	l.mu.Unlock()
+
+	for _, decision := range decisions {
+		if decision.Action == planner.Keep {
+			continue
+		}
+
+		if err := l.Log(Plan, decision.ARN, "", ""); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Log writes one Event. requestID is the ID AWS gave the request that carried out the
+// action, if any, and detail explains a Failure or Skip.
+func (l *Logger) Log(action, arn, requestID, detail string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	event := Event{
+		Time:      l.Now(),
+		Run:       l.Run,
+		Caller:    l.Identity.ARN,
+		Account:   l.Identity.Account,
+		Region:    l.Region,
+		Action:    action,
+		ARN:       arn,
+		RequestID: requestID,
+		Detail:    detail,
+	}
+
+	if decision, ok := l.decisions[arn]; ok {
+		event.Decision = decision.Action
+		event.Reasons = decision.Reasons
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if err := l.Sink.Write(line); err != nil {
+		return fmt.Errorf("audit: unable to write log: %v", err)
+	}
+
+	return nil
+}
+
+// Close closes the Logger's Sink.
+func (l *Logger) Close() error {
+	return l.Sink.Close()
+}
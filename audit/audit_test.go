@@ -0,0 +1,207 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/aws/aws-sdk-go/service/sts/stsiface"
+	"github.com/quintilesims/go-ecs-cleaner/archive"
+	"github.com/quintilesims/go-ecs-cleaner/planner"
+)
+
+var testIdentity = Identity{
+	Account: "000000000000",
+	ARN:     "arn:aws:sts::000000000000:assumed-role/cleaner/session",
+	UserID:  "AROAEXAMPLE:session",
+}
+
+func decode(t *testing.T, body []byte) []Event {
+	var events []Event
+	for _, line := range strings.Split(strings.TrimSpace(string(body)), "\n") {
+		var event Event
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("%v: %s", err, line)
+		}
+
+		events = append(events, event)
+	}
+
+	return events
+}
+
+func Test_Logger(t *testing.T) {
+	var buf bytes.Buffer
+
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := New(WriterSink{Writer: &buf}, testIdentity, "us-west-2")
+	l.Now = func() time.Time { return now }
+	l.Run = "run0"
+
+	reasons := []planner.Reason{{Code: planner.BeyondCutoff, Detail: "revision 1 is older than the 1 most recent"}}
+	decisions := []planner.Decision{
+		{ARN: "td:1", Action: planner.Deregister, Reasons: reasons},
+		{ARN: "td:2", Action: planner.Keep},
+	}
+
+	if err := l.Plan(decisions); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Log(Deregister, "td:1", "request0", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Log(Skip, "td:2", "", "became active"); err != nil {
+		t.Fatal(err)
+	}
+
+	base := Event{
+		Time:    now,
+		Run:     "run0",
+		Caller:  testIdentity.ARN,
+		Account: testIdentity.Account,
+		Region:  "us-west-2",
+	}
+
+	plan, deregister, skip := base, base, base
+	plan.Action, plan.ARN, plan.Decision, plan.Reasons = Plan, "td:1", planner.Deregister, reasons
+	deregister.Action, deregister.ARN, deregister.Decision, deregister.Reasons, deregister.RequestID = Deregister, "td:1", planner.Deregister, reasons, "request0"
+	skip.Action, skip.ARN, skip.Decision, skip.Detail = Skip, "td:2", planner.Keep, "became active"
+
+	expected := []Event{plan, deregister, skip}
+	if result := decode(t, buf.Bytes()); !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, got %v\n", expected, result)
+	}
+}
+
+type failingSink struct{}
+
+func (failingSink) Write(line []byte) error { return errors.New("disk full") }
+func (failingSink) Close() error            { return nil }
+
+func Test_Logger_SinkError(t *testing.T) {
+	l := New(failingSink{}, testIdentity, "us-west-2")
+
+	if err := l.Log(Deregister, "td:1", "", ""); err == nil {
+		t.Error("Expected a sink's error to be returned")
+	}
+}
+
+func Test_FileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "audit.jsonl")
+
+	// a second run appends to the first's lines
+	for _, line := range []string{`{"run":"run0"}`, `{"run":"run1"}`} {
+		sink, err := OpenFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := sink.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+
+		sink.Close()
+	}
+
+	body, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "{\"run\":\"run0\"}\n{\"run\":\"run1\"}\n"; string(body) != expected {
+		t.Errorf("Expected %q, got %q\n", expected, body)
+	}
+}
+
+func Test_ObjectSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store := &flakyStore{Store: archive.DirStore(dir), failures: 1}
+	sink := &ObjectSink{Store: store, Prefix: "audit/run0", PartLines: 2}
+
+	// the first full part fails to upload, and goes up with the next line instead
+	for i, line := range []string{"a", "b", "c", "d", "e"} {
+		if err := sink.Write([]byte(line)); (err != nil) != (i == 1) {
+			t.Fatalf("Line %d: unexpected error %v\n", i, err)
+		}
+	}
+
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{"audit/run0/00001.jsonl": "a\nb\nc\n", "audit/run0/00002.jsonl": "d\ne\n"}
+	if !reflect.DeepEqual(expected, store.puts) {
+		t.Errorf("Expected %v, got %v\n", expected, store.puts)
+	}
+
+	// each part is uploaded once
+	if err := sink.Close(); err != nil || len(store.puts) != 2 {
+		t.Errorf("Expected nothing more to upload, got %v, %v\n", store.puts, err)
+	}
+}
+
+// flakyStore fails its first `failures` Puts, and records the rest.
+type flakyStore struct {
+	archive.Store
+	failures int
+	puts     map[string]string
+}
+
+func (s *flakyStore) Put(key string, body []byte) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("boom")
+	}
+
+	if s.puts == nil {
+		s.puts = make(map[string]string)
+	}
+
+	s.puts[key] = string(body)
+	return s.Store.Put(key, body)
+}
+
+type fakeSTS struct {
+	stsiface.STSAPI
+}
+
+func (fakeSTS) GetCallerIdentity(*sts.GetCallerIdentityInput) (*sts.GetCallerIdentityOutput, error) {
+	return &sts.GetCallerIdentityOutput{
+		Account: aws.String(testIdentity.Account),
+		Arn:     aws.String(testIdentity.ARN),
+		UserId:  aws.String(testIdentity.UserID),
+	}, nil
+}
+
+func Test_CallerIdentity(t *testing.T) {
+	result, err := CallerIdentity(fakeSTS{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(testIdentity, result) {
+		t.Errorf("Expected %v, got %v\n", testIdentity, result)
+	}
+}
@@ -0,0 +1,127 @@
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/quintilesims/go-ecs-cleaner/archive"
+)
+
+// Sink receives the audit log one line at a time.
+type Sink interface {
+	Write(line []byte) error
+	Close() error
+}
+
+// FileSink appends lines to a local file, syncing each to disk before the cleaner moves on.
+type FileSink struct {
+	f *os.File
+}
+
+// OpenFile opens a FileSink, creating the file if it doesn't exist. Existing lines are kept.
+func OpenFile(name string) (*FileSink, error) {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileSink{f: f}, nil
+}
+
+// Write appends a line.
+func (s *FileSink) Write(line []byte) error {
+	if _, err := s.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+
+	return s.f.Sync()
+}
+
+// Close closes the file.
+func (s *FileSink) Close() error {
+	return s.f.Close()
+}
+
+// WriterSink writes lines to an io.Writer, e.g. os.Stdout.
+type WriterSink struct {
+	io.Writer
+}
+
+// Write writes a line.
+func (s WriterSink) Write(line []byte) error {
+	_, err := s.Writer.Write(append(line, '\n'))
+	return err
+}
+
+// Close does nothing; the Writer belongs to the caller.
+func (s WriterSink) Close() error {
+	return nil
+}
+
+// DefaultPartLines is how many lines an ObjectSink puts in each part when PartLines is 0.
+const DefaultPartLines = 100
+
+// ObjectSink keeps a run's lines in an archive.Store, e.g. an archive.S3Store. Objects can't
+// be appended to, so lines are buffered and uploaded in parts of `PartLines` lines, as
+// "<Prefix>/00001.jsonl", "<Prefix>/00002.jsonl" and so on; each part is written once,
+// complete. Close uploads the last, shorter part.
+type ObjectSink struct {
+	Store     archive.Store
+	Prefix    string
+	PartLines int
+
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	lines int
+	parts int
+}
+
+// Write adds a line, uploading a part once it's full. If the upload fails, the lines stay
+// buffered for the next attempt.
+func (s *ObjectSink) Write(line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.buf.Write(line)
+	s.buf.WriteByte('\n')
+	s.lines++
+
+	partLines := s.PartLines
+	if partLines <= 0 {
+		partLines = DefaultPartLines
+	}
+
+	if s.lines < partLines {
+		return nil
+	}
+
+	return s.flush()
+}
+
+// Close uploads any lines not yet uploaded.
+func (s *ObjectSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.flush()
+}
+
+func (s *ObjectSink) flush() error {
+	if s.lines == 0 {
+		return nil
+	}
+
+	key := fmt.Sprintf("%s/%05d.jsonl", s.Prefix, s.parts+1)
+	if err := s.Store.Put(key, s.buf.Bytes()); err != nil {
+		return err
+	}
+
+	s.parts++
+	s.lines = 0
+	s.buf.Reset()
+
+	return nil
+}
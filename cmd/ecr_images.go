@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/spf13/cobra"
+)
+
+var imageAgeDaysFlag int
+var imageRepositoryFlag []string
+var keepImagesFlag int
+
+func init() {
+	ecrImagesCmd.Flags().IntVar(&imageAgeDaysFlag, "age-days", 30, "only delete images pushed at least this many days ago")
+	ecrImagesCmd.Flags().BoolVarP(&applyFlag, "apply", "a", false, "actually delete unreferenced images")
+	ecrImagesCmd.Flags().BoolVarP(&debugFlag, "debug", "d", false, "enable for all the output")
+	ecrImagesCmd.Flags().IntVar(&keepImagesFlag, "keep-last", 10, "always keep this many of the most recently pushed tagged or multi-architecture images in each repository")
+	ecrImagesCmd.Flags().BoolVarP(&quietFlag, "quiet", "q", false, "minimize output")
+	ecrImagesCmd.Flags().StringArrayVar(&imageRepositoryFlag, "repository", nil, "clean up ECR repositories whose names match this glob, e.g. \"services/*\"; repeatable")
+	addTraceFlags(ecrImagesCmd)
+	ecrImagesCmd.Flags().BoolVarP(&verboseFlag, "verbose", "v", false, "enable for chattier output")
+	rootCmd.AddCommand(ecrImagesCmd)
+}
+
+var ecrImagesCmd = &cobra.Command{
+	Use:   "ecr-images",
+	Short: "Delete ECR images no task definition uses (dry run by default).",
+	Long: `Delete ECR images no task definition uses (dry run by default).
+
+Every ACTIVE task definition's container images, and every running task's, are
+collected by tag and digest. An image in a repository matching a --repository
+glob is deleted if none of them refers to it, it was pushed at least --age-days
+ago, and it isn't one of the repository's --keep-last most recently pushed
+tagged or multi-architecture images; untagged platform images don't count
+towards them. The platform images of a multi-architecture image that's kept are
+kept too.
+
+Only this account's task definitions and tasks in the current region are seen.
+Images referred to only by images outside ECR, by other accounts' task
+definitions, or by task definitions in other regions pulling across regions,
+can't be seen, so point --repository only at repositories this account's ECS
+in this region alone pulls from. If any task definition, cluster or task can't
+be listed, nothing is deleted. Deletion backs off when throttled.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(imageRepositoryFlag) == 0 {
+			fmt.Println("At least one repository flag is required; use --repository '*' for every repository.")
+			os.Exit(1)
+		}
+
+		for _, pattern := range imageRepositoryFlag {
+			if _, err := path.Match(pattern, ""); err != nil {
+				fmt.Printf("Invalid pattern %q: %v\n", pattern, err)
+				os.Exit(1)
+			}
+		}
+
+		if imageAgeDaysFlag < 0 || keepImagesFlag < 0 {
+			fmt.Println("The age-days and keep-last flags must not be negative.")
+			os.Exit(1)
+		}
+
+		opts := flagOptions()
+		ecsClient, err := opts.newECSClient()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		ecsClient.Flags.Apply = applyFlag
+		ecsClient.Flags.ImageAgeDays = imageAgeDaysFlag
+		ecsClient.Flags.ImageRepositories = imageRepositoryFlag
+		ecsClient.Flags.KeepImages = keepImagesFlag
+
+		if err := ecsClient.ConfigureSession(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		err = ecsClient.CleanupImages()
+		shutdownTracing()
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var emptyDaysFlag int
+
+func init() {
+	ecsClusterCmd.Flags().BoolVarP(&applyFlag, "apply", "a", false, "actually delete empty clusters")
+	ecsClusterCmd.Flags().BoolVarP(&debugFlag, "debug", "d", false, "enable for all the output")
+	ecsClusterCmd.Flags().IntVar(&emptyDaysFlag, "empty-days", 0, "only delete clusters that have been found empty for this many days, tagging them when first found so (0 to delete any empty cluster)")
+	ecsClusterCmd.Flags().BoolVarP(&quietFlag, "quiet", "q", false, "minimize output")
+	addTraceFlags(ecsClusterCmd)
+	ecsClusterCmd.Flags().BoolVarP(&verboseFlag, "verbose", "v", false, "enable for chattier output")
+	rootCmd.AddCommand(ecsClusterCmd)
+}
+
+var ecsClusterCmd = &cobra.Command{
+	Use:   "ecs-cluster",
+	Short: "Delete empty clusters (dry run by default).",
+	Long: `Delete empty clusters (dry run by default).
+
+A cluster is empty when it has no services, no running or pending tasks, no
+registered container instances and no capacity providers.
+
+ECS doesn't record when a cluster was created, so with --empty-days N an
+--apply run tags each cluster it finds empty with ` + "`ecs-cleaner:empty-since`" + `,
+and deletes it once it has carried the tag for N days; the tag is cleared if
+the cluster is used again meanwhile. Deletion backs off when throttled.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if emptyDaysFlag < 0 {
+			fmt.Println("The empty-days flag must not be negative.")
+			os.Exit(1)
+		}
+
+		opts := flagOptions()
+		ecsClient, err := opts.newECSClient()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		ecsClient.Flags.Apply = applyFlag
+		ecsClient.Flags.EmptyDays = emptyDaysFlag
+
+		if err := ecsClient.ConfigureSession(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		err = ecsClient.CleanupClusters()
+		shutdownTracing()
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/spf13/cobra"
+)
+
+var excludeServiceFlag []string
+var idleDaysFlag int
+var includeServiceFlag []string
+
+func init() {
+	ecsServiceCmd.Flags().BoolVarP(&applyFlag, "apply", "a", false, "actually delete idle services")
+	ecsServiceCmd.Flags().BoolVarP(&debugFlag, "debug", "d", false, "enable for all the output")
+	ecsServiceCmd.Flags().StringArrayVar(&excludeServiceFlag, "exclude", nil, "leave alone services matching this `cluster/service` glob, e.g. \"prod-*/*\"; repeatable")
+	ecsServiceCmd.Flags().IntVar(&idleDaysFlag, "idle-days", 90, "only delete services that have had no deployment or event for this many days")
+	ecsServiceCmd.Flags().StringArrayVar(&includeServiceFlag, "include", nil, "only consider services matching this `cluster/service` glob, e.g. \"staging-*/*\"; repeatable")
+	ecsServiceCmd.Flags().StringArrayVar(&protectTagFlag, "protect-tag", nil, "keep services tagged `key[=value]`; repeatable")
+	ecsServiceCmd.Flags().BoolVarP(&quietFlag, "quiet", "q", false, "minimize output")
+	addTraceFlags(ecsServiceCmd)
+	ecsServiceCmd.Flags().BoolVarP(&verboseFlag, "verbose", "v", false, "enable for chattier output")
+	rootCmd.AddCommand(ecsServiceCmd)
+}
+
+var ecsServiceCmd = &cobra.Command{
+	Use:   "ecs-service",
+	Short: "Delete services scaled to zero and idle (dry run by default).",
+	Long: `Delete services scaled to zero and idle (dry run by default).
+
+A service is idle when its desired count is 0, nothing of it is running or
+pending, and it hasn't been created, deployed to or had an event for
+--idle-days. The steady-state events ECS keeps reporting don't count.
+
+--include and --exclude match "cluster/service" names against globs; a
+service must match an --include, if any are given, and no --exclude. Services
+tagged with a --protect-tag are kept. With --apply, idle services are
+force-deleted, backing off when throttled.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if idleDaysFlag < 0 {
+			fmt.Println("The idle-days flag must not be negative.")
+			os.Exit(1)
+		}
+
+		for _, pattern := range append(includeServiceFlag, excludeServiceFlag...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				fmt.Printf("Invalid pattern %q: %v\n", pattern, err)
+				os.Exit(1)
+			}
+		}
+
+		opts := flagOptions()
+		ecsClient, err := opts.newECSClient()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		ecsClient.Flags.Apply = applyFlag
+		ecsClient.Flags.ExcludeServices = excludeServiceFlag
+		ecsClient.Flags.IdleDays = idleDaysFlag
+		ecsClient.Flags.IncludeServices = includeServiceFlag
+
+		if ecsClient.Flags.ProtectTags, err = opts.parseProtectTags(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if err := ecsClient.ConfigureSession(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		err = ecsClient.CleanupServices()
+		shutdownTracing()
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
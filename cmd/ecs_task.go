@@ -1,25 +1,88 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/quintilesims/go-ecs-cleaner/archive"
+	"github.com/quintilesims/go-ecs-cleaner/audit"
 	"github.com/quintilesims/go-ecs-cleaner/ecsclient"
+	"github.com/quintilesims/go-ecs-cleaner/ecsfake"
+	"github.com/quintilesims/go-ecs-cleaner/inventory"
+	"github.com/quintilesims/go-ecs-cleaner/lock"
+	"github.com/quintilesims/go-ecs-cleaner/metrics"
+	"github.com/quintilesims/go-ecs-cleaner/notify"
+	"github.com/quintilesims/go-ecs-cleaner/planner"
+	"github.com/quintilesims/go-ecs-cleaner/review"
+	"github.com/quintilesims/go-ecs-cleaner/tracing"
 	"github.com/spf13/cobra"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// tracerProvider exports the spans of every ECSClient newECSClient creates, once the trace
+// flags start it; see shutdownTracing. tracerProviderMu guards it, as the server creates
+// clients concurrently.
+var tracerProvider *sdktrace.TracerProvider
+var tracerProviderMu sync.Mutex
+
+// exitLimitTripped is the exit code when a plan trips a safety limit such as
+// `--max-deregistrations`, so automation can tell it apart from other failures.
+const exitLimitTripped = 3
+
 var applyFlag bool
+var archiveDirFlag string
+var archiveS3Flag string
+var auditLogFlag string
 var cutoffFlag int
 var debugFlag bool
+var forceUnlockFlag bool
+var fromSnapshotFlag string
+var gracePeriodFlag time.Duration
+var interactiveFlag bool
+var keepBeforeActiveFlag int
+var lockFileFlag string
+var lockHolderFlag string
+var lockTableFlag string
+var lockTTLFlag time.Duration
+var maxDeregistrationsFlag int
+var maxFamilyFractionFlag float64
+var maxFractionFlag float64
+var notifyEventBridgeFlag string
+var notifyOnFlag string
+var notifySlackFlag string
+var notifySNSFlag string
+var notifyWebhookFlag string
+var orphanKeepDaysFlag int
+var orphanKeepFlag int
+var protectTagFlag []string
+var pushgatewayFlag string
 var quietFlag bool
+var retireFamiliesFlag bool
+var revalidateEveryFlag int
+var traceFileFlag string
+var traceOTLPFlag string
 var verboseFlag bool
 
 func init() {
 	ecsTaskCmd.Flags().BoolVarP(&applyFlag, "apply", "a", false, "actually perform task definition deregistration")
-	ecsTaskCmd.Flags().IntVarP(&cutoffFlag, "cutoff", "c", 5, "how many most-recent task definitions to keep around")
-	ecsTaskCmd.Flags().BoolVarP(&debugFlag, "debug", "d", false, "enable for all the output")
-	ecsTaskCmd.Flags().BoolVarP(&quietFlag, "quiet", "q", false, "minimize output")
-	ecsTaskCmd.Flags().BoolVarP(&verboseFlag, "verbose", "v", false, "enable for chattier output")
+	addCleanupFlags(ecsTaskCmd)
+	ecsTaskCmd.PersistentFlags().BoolVarP(&debugFlag, "debug", "d", false, "enable for all the output")
+	ecsTaskCmd.Flags().BoolVarP(&interactiveFlag, "interactive", "i", false, "with --apply, review the task definitions by family, and confirm, before any are deregistered")
+	ecsTaskCmd.Flags().StringVar(&pushgatewayFlag, "pushgateway", "", "push the run's Prometheus metrics to the Pushgateway at this URL when it's done")
+	ecsTaskCmd.PersistentFlags().BoolVarP(&quietFlag, "quiet", "q", false, "minimize output")
+	ecsTaskCmd.PersistentFlags().BoolVarP(&verboseFlag, "verbose", "v", false, "enable for chattier output")
 	rootCmd.AddCommand(ecsTaskCmd)
 }
 
@@ -35,31 +98,534 @@ AWS_ACCESS_KEY
 AWS_SECRET_ACCESS_KEY
 AWS_REGION`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if debugFlag {
-			verboseFlag = true
+		var m *metrics.Metrics
+		if pushgatewayFlag != "" {
+			m = metrics.New()
 		}
 
-		if quietFlag && verboseFlag {
-			fmt.Println("Can't set quiet flag alongside verbose or debug flags.")
+		opts := flagOptions()
+		ecsClient, err := opts.newCleanupClient(m)
+		if err != nil {
+			fmt.Println(err)
 			os.Exit(1)
 		}
 
-		ecsClient := ecsclient.NewECSClient()
+		if interactiveFlag {
+			if !applyFlag {
+				fmt.Println("Can't set interactive flag without apply flag.")
+				os.Exit(1)
+			}
+
+			if !review.IsTerminal(os.Stdin) || !review.IsTerminal(os.Stdout) {
+				fmt.Println("Can't set interactive flag when stdin or stdout is not a terminal.")
+				os.Exit(1)
+			}
+
+			ecsClient.Review = func(arns []string) ([]string, error) {
+				return review.Run(os.Stdin, os.Stdout, arns)
+			}
+		}
+
+		if err := opts.configureAudit(ecsClient); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
 
-		if err := ecsClient.ConfigureSession(); err != nil {
+		if err := opts.configureLock(ecsClient); err != nil {
 			fmt.Println(err)
 			os.Exit(1)
 		}
 
-		ecsClient.Flags.Apply = applyFlag
-		ecsClient.Flags.Cutoff = cutoffFlag
-		ecsClient.Flags.Debug = debugFlag
-		ecsClient.Flags.Quiet = quietFlag
-		ecsClient.Flags.Verbose = verboseFlag
+		err = closeAudit(ecsClient, ecsClient.CleanupTaskDefinitions())
+
+		shutdownTracing()
 
-		if err := ecsClient.CleanupTaskDefinitions(); err != nil {
+		if pushgatewayFlag != "" {
+			if err := ecsClient.Metrics.Push(pushgatewayFlag, "go-ecs-cleaner"); err != nil {
+				fmt.Printf("Unable to push metrics: %v\n", err)
+			}
+		}
+
+		if err != nil {
 			fmt.Println(err)
+
+			if _, ok := err.(*planner.LimitError); ok {
+				os.Exit(exitLimitTripped)
+			}
+
 			os.Exit(1)
 		}
 	},
 }
+
+// addCleanupFlags adds the flags that configure a cleanup to a command that runs one. Whether
+// it applies is left to the command.
+func addCleanupFlags(cmd *cobra.Command) {
+	addArchiveFlags(cmd)
+	addAuditFlags(cmd)
+	cmd.Flags().IntVar(&maxDeregistrationsFlag, "max-deregistrations", 0, "refuse to deregister more than this many task definitions (0 for no limit)")
+	cmd.Flags().Float64Var(&maxFamilyFractionFlag, "max-family-fraction", 0, "refuse to deregister more than this share, or every one, of any family's task definitions (0 for no limit)")
+	cmd.Flags().Float64Var(&maxFractionFlag, "max-fraction", 0, "refuse to deregister more than this share of all task definitions, e.g. 0.5 (0 for no limit)")
+	addLockFlags(cmd)
+	addNotifyFlags(cmd)
+	addPlanFlags(cmd)
+	cmd.Flags().BoolVar(&retireFamiliesFlag, "retire-families", false, "allow deregistering every revision of a family no service uses, which is otherwise refused in case discovery missed its services")
+	cmd.Flags().IntVar(&revalidateEveryFlag, "revalidate-every", 100, "while deregistering, recheck which task definitions are in use after this many, and skip any that became active (0 to never recheck)")
+	addTraceFlags(cmd)
+}
+
+// newCleanupClient creates an ECSClient configured by the flags added by addCleanupFlags,
+// with its Archive if applying, or returns an error if they're invalid. Its API calls are
+// instrumented with m, if set. Its Audit log is left to configureAudit.
+func (o options) newCleanupClient(m *metrics.Metrics) (*ecsclient.ECSClient, error) {
+	ecsClient, err := o.newECSClient()
+	if err != nil {
+		return nil, err
+	}
+
+	ecsClient.Metrics = m
+
+	ecsClient.Flags.Apply = o.apply
+	ecsClient.Flags.MaxDeregistrations = o.maxDeregistrations
+	ecsClient.Flags.MaxFamilyFraction = o.maxFamilyFraction
+	ecsClient.Flags.MaxFraction = o.maxFraction
+	ecsClient.Flags.RetireFamilies = o.retireFamilies
+	ecsClient.Flags.RevalidateEvery = o.revalidateEvery
+
+	if o.maxFraction < 0 || o.maxFraction > 1 || o.maxFamilyFraction < 0 || o.maxFamilyFraction > 1 {
+		return nil, errors.New("the max-fraction and max-family-fraction flags must be between 0 and 1")
+	}
+
+	if o.fromSnapshot != "" && o.apply {
+		return nil, errors.New("can't set apply flag alongside from-snapshot flag")
+	}
+
+	if err := o.configurePlan(ecsClient); err != nil {
+		return nil, err
+	}
+
+	if o.apply {
+		if err := o.configureArchive(ecsClient); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := o.configureNotify(ecsClient); err != nil {
+		return nil, err
+	}
+
+	return ecsClient, nil
+}
+
+// newECSClient creates an ECSClient from the flags shared by the `ecs-task` commands, or
+// returns an error if they conflict. The client's session has not yet been configured.
+func (o options) newECSClient() (*ecsclient.ECSClient, error) {
+	if o.quiet && o.verbose {
+		return nil, errors.New("can't set quiet flag alongside verbose or debug flags")
+	}
+
+	ecsClient := ecsclient.NewECSClient()
+
+	ecsClient.Flags.Debug = o.debug
+	ecsClient.Flags.Quiet = o.quiet
+	ecsClient.Flags.Verbose = o.verbose
+
+	ecsClient.Flags.CABundle = o.caBundle
+	ecsClient.Flags.EndpointURL = o.endpointURL
+	ecsClient.Flags.NoVerifySSL = o.noVerifySSL
+	ecsClient.Flags.ServiceEndpoints = o.serviceEndpoints
+
+	tracer, err := o.newTracer()
+	if err != nil {
+		return nil, err
+	}
+
+	ecsClient.Tracer = tracer
+
+	return ecsClient, nil
+}
+
+// addTraceFlags adds the flags that choose where OpenTelemetry spans are exported.
+func addTraceFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&traceFileFlag, "trace-file", "", "append OpenTelemetry spans of each phase, AWS call and backoff to this file, as JSON lines")
+	cmd.Flags().StringVar(&traceOTLPFlag, "trace-otlp", "", "export OpenTelemetry spans of each phase, AWS call and backoff over OTLP/HTTP to this collector URL, e.g. http://localhost:4318")
+}
+
+// newTracer returns a Tracer exporting spans where the flags added by addTraceFlags choose,
+// or nil if they choose nowhere. Every Tracer it returns shares the tracerProvider.
+func (o options) newTracer() (trace.Tracer, error) {
+	if o.traceFile == "" && o.traceOTLP == "" {
+		return nil, nil
+	}
+
+	tracerProviderMu.Lock()
+	defer tracerProviderMu.Unlock()
+
+	if tracerProvider == nil {
+		var exporters []sdktrace.SpanExporter
+
+		if o.traceFile != "" {
+			exporter, err := tracing.NewFileExporter(o.traceFile)
+			if err != nil {
+				return nil, err
+			}
+
+			exporters = append(exporters, exporter)
+		}
+
+		if o.traceOTLP != "" {
+			exporter, err := tracing.NewOTLPExporter(o.traceOTLP)
+			if err != nil {
+				return nil, err
+			}
+
+			exporters = append(exporters, exporter)
+		}
+
+		tracerProvider = tracing.NewProvider(exporters...)
+	}
+
+	return tracerProvider.Tracer(tracing.Name), nil
+}
+
+// shutdownTracing exports any spans still buffered. It must be called before exiting, as
+// deferred calls don't survive os.Exit.
+func shutdownTracing() {
+	tracerProviderMu.Lock()
+	defer tracerProviderMu.Unlock()
+
+	if tracerProvider == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := tracerProvider.Shutdown(ctx); err != nil {
+		fmt.Printf("Unable to export spans: %v\n", err)
+	}
+}
+
+// addPlanFlags adds the flags that decide what a plan keeps, and where its inventory comes
+// from, to a command that builds one.
+func addPlanFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVarP(&cutoffFlag, "cutoff", "c", 5, "how many most-recent task definitions to keep around")
+	cmd.Flags().StringVar(&fromSnapshotFlag, "from-snapshot", "", "run offline against a snapshot written by `ecs-task snapshot` instead of AWS")
+	cmd.Flags().DurationVar(&gracePeriodFlag, "grace-period", 0, "mark task definitions pending deregistration first, and only deregister them once marked for this long (e.g. 168h)")
+	cmd.Flags().IntVar(&keepBeforeActiveFlag, "keep-before-active", 0, "how many task definitions immediately preceding each service's current one to keep as rollback targets")
+	cmd.Flags().IntVar(&orphanKeepFlag, "orphan-keep", 0, "how many most-recent task definitions to keep around for families no service uses")
+	cmd.Flags().IntVar(&orphanKeepDaysFlag, "orphan-keep-days", 0, "also keep task definitions registered in the last N days for families no service uses")
+	cmd.Flags().StringArrayVar(&protectTagFlag, "protect-tag", nil, "keep task definitions tagged `key[=value]`; repeatable")
+}
+
+// configurePlan applies the flags added by addPlanFlags to the ECSClient, then points it at
+// either the snapshot being replayed or the configured AWS account.
+func (o options) configurePlan(ecsClient *ecsclient.ECSClient) error {
+	ecsClient.Flags.Cutoff = o.cutoff
+	ecsClient.Flags.GracePeriod = o.gracePeriod
+	ecsClient.Flags.KeepBeforeActive = o.keepBeforeActive
+	ecsClient.Flags.OrphanKeep = o.orphanKeep
+	ecsClient.Flags.OrphanKeepDays = o.orphanKeepDays
+
+	protectTags, err := o.parseProtectTags()
+	if err != nil {
+		return err
+	}
+
+	ecsClient.Flags.ProtectTags = protectTags
+
+	if o.fromSnapshot == "" {
+		return ecsClient.ConfigureSession()
+	}
+
+	inv, err := inventory.Load(o.fromSnapshot)
+	if err != nil {
+		return err
+	}
+
+	replay, err := ecsfake.FromInventory(inv)
+	if err != nil {
+		return err
+	}
+
+	ecsClient.Svc = replay
+	ecsClient.Events = replay.Events()
+
+	ecsClient.Now = func() time.Time { return inv.CapturedAt }
+
+	if !o.quiet {
+		fmt.Printf("Replaying snapshot of %s captured at %s.\n", inv.Region, inv.CapturedAt)
+	}
+
+	return nil
+}
+
+// addArchiveFlags adds the flags that choose where deregistered task definitions are archived.
+func addArchiveFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&archiveDirFlag, "archive-dir", "", "before deregistering, save each task definition's full JSON, with tags, under this directory")
+	cmd.Flags().StringVar(&archiveS3Flag, "archive-s3", "", "before deregistering, save each task definition's full JSON, with tags, under this `s3://bucket/prefix`")
+}
+
+// parseProtectTags parses the protect-tag flags into tag values by key, where an empty value
+// matches any, or returns an error if one is invalid. It returns nil if there are none.
+func (o options) parseProtectTags() (map[string]string, error) {
+	if len(o.protectTags) == 0 {
+		return nil, nil
+	}
+
+	protectTags := make(map[string]string)
+	for _, tag := range o.protectTags {
+		kv := strings.SplitN(tag, "=", 2)
+		if kv[0] == "" {
+			return nil, fmt.Errorf("invalid protect-tag %q: missing key", tag)
+		}
+
+		kv = append(kv, "")
+		protectTags[kv[0]] = kv[1]
+	}
+
+	return protectTags, nil
+}
+
+// configureArchive gives the ECSClient the Archive chosen by the flags added by
+// addArchiveFlags, if any. The client's session must be configured.
+func (o options) configureArchive(ecsClient *ecsclient.ECSClient) error {
+	store, err := o.archiveStore(ecsClient)
+	if err != nil {
+		return err
+	}
+
+	if store != nil {
+		ecsClient.Archive = archive.New(store)
+	}
+
+	return nil
+}
+
+// archiveStore returns the archive.Store chosen by the archive flags, or nil if there is
+// none. The client's session must be configured.
+func (o options) archiveStore(ecsClient *ecsclient.ECSClient) (archive.Store, error) {
+	switch {
+	case o.archiveDir != "" && o.archiveS3 != "":
+		return nil, errors.New("can't set archive-dir flag alongside archive-s3 flag")
+
+	case o.archiveDir != "":
+		return archive.DirStore(o.archiveDir), nil
+
+	case o.archiveS3 != "":
+		return archive.NewS3Store(s3.New(ecsClient.Session), o.archiveS3)
+	}
+
+	return nil, nil
+}
+
+// addLockFlags adds the flags that choose where the lock against concurrent runs is kept.
+func addLockFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(&forceUnlockFlag, "force-unlock", false, "first release the lock on this account and region, whoever holds it; for a holder that died")
+	cmd.Flags().StringVar(&lockFileFlag, "lock-file", "", "refuse to run while another run holds a lock on this account and region in this local file")
+	cmd.Flags().StringVar(&lockHolderFlag, "lock-holder", "", "who to say holds the lock (default user@host (pid N))")
+	cmd.Flags().StringVar(&lockTableFlag, "lock-table", "", "refuse to run while another run holds a lock on this account and region in this DynamoDB table, keyed by a string LockKey")
+	cmd.Flags().DurationVar(&lockTTLFlag, "lock-ttl", 2*time.Minute, "how long the lock outlives its holder if the holder dies without releasing it; it's renewed every third of this")
+}
+
+// configureLock gives the ECSClient the Lock chosen by the flags added by addLockFlags, if
+// any, first forcibly unlocking it if asked. The client's session must be configured.
+func (o options) configureLock(ecsClient *ecsclient.ECSClient) error {
+	if o.fromSnapshot != "" && (o.lockFile != "" || o.lockTable != "") {
+		return errors.New("can't set lock-file or lock-table flags alongside from-snapshot flag")
+	}
+
+	if o.lockFile != "" && o.lockTable != "" {
+		return errors.New("can't set lock-file flag alongside lock-table flag")
+	}
+
+	if o.lockTTL < time.Second {
+		return errors.New("the lock-ttl flag must be at least 1s")
+	}
+
+	l, err := o.newLock(ecsClient)
+	if err != nil {
+		return err
+	}
+
+	if o.unlockFirst {
+		if err := o.forceUnlock(l); err != nil {
+			return err
+		}
+	}
+
+	ecsClient.Lock = l
+
+	return nil
+}
+
+// newLock creates the Lock chosen by the flags added by addLockFlags, on the client's
+// account and region, or nil if there is none. The flags must have been checked by
+// configureLock, and the client's session configured.
+func (o options) newLock(ecsClient *ecsclient.ECSClient) (*lock.Lock, error) {
+	if o.lockFile == "" && o.lockTable == "" {
+		return nil, nil
+	}
+
+	identity, err := audit.CallerIdentity(sts.New(ecsClient.Session))
+	if err != nil {
+		return nil, err
+	}
+
+	var backend lock.Backend = &lock.FileBackend{Path: o.lockFile}
+	if o.lockTable != "" {
+		backend = &lock.DynamoDBBackend{Svc: dynamodb.New(ecsClient.Session), Table: o.lockTable}
+	}
+
+	holder := o.lockHolder
+	if holder == "" {
+		holder = lock.Holder()
+	}
+
+	key := fmt.Sprintf("%s/%s", identity.Account, aws.StringValue(ecsClient.Session.Config.Region))
+	return lock.New(backend, key, holder, o.lockTTL), nil
+}
+
+// forceUnlock releases the Lock, whoever holds it.
+func (o options) forceUnlock(l *lock.Lock) error {
+	if l == nil {
+		return errors.New("can't set force-unlock flag without lock-file or lock-table flag")
+	}
+
+	lease, err := l.ForceUnlock()
+	if err != nil {
+		return err
+	}
+
+	if lease != nil && !o.quiet {
+		fmt.Printf("Released the lock on %s held by %s since %s.\n", lease.Key, lease.Holder, lease.AcquiredAt.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// addNotifyFlags adds the flags that choose where, and after which runs, run summaries are sent.
+func addNotifyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&notifyEventBridgeFlag, "notify-eventbridge", "", "put a summary of the run on this EventBridge bus, by name or ARN")
+	cmd.Flags().StringVar(&notifyOnFlag, "notify-on", notify.Always, "send run summaries after every run (always), runs with --apply (apply), or runs that fail or fail to deregister anything (failure)")
+	cmd.Flags().StringVar(&notifySlackFlag, "notify-slack", "", "post a summary of the run to this Slack incoming webhook URL")
+	cmd.Flags().StringVar(&notifySNSFlag, "notify-sns", "", "publish a summary of the run, as JSON, to this SNS topic ARN")
+	cmd.Flags().StringVar(&notifyWebhookFlag, "notify-webhook", "", "post a summary of the run, as JSON, to this URL")
+}
+
+// configureNotify gives the ECSClient a Notifier for the sinks chosen by the flags added by
+// addNotifyFlags, if any. The client's session must be configured for SNS and EventBridge.
+func (o options) configureNotify(ecsClient *ecsclient.ECSClient) error {
+	on, err := notify.ParseOn(o.notifyOn)
+	if err != nil {
+		return err
+	}
+
+	if o.fromSnapshot != "" && (o.notifySNS != "" || o.notifyEventBridge != "") {
+		return errors.New("can't set notify-sns or notify-eventbridge flags alongside from-snapshot flag")
+	}
+
+	var sinks []notify.Sink
+	if o.notifySlack != "" {
+		sinks = append(sinks, &notify.SlackSink{URL: o.notifySlack})
+	}
+
+	if o.notifyWebhook != "" {
+		sinks = append(sinks, &notify.WebhookSink{URL: o.notifyWebhook})
+	}
+
+	if o.notifySNS != "" {
+		sinks = append(sinks, &notify.SNSSink{Svc: sns.New(ecsClient.Session), TopicARN: o.notifySNS})
+	}
+
+	if o.notifyEventBridge != "" {
+		sinks = append(sinks, &notify.EventBridgeSink{Svc: eventbridge.New(ecsClient.Session), EventBus: o.notifyEventBridge})
+	}
+
+	if len(sinks) > 0 {
+		ecsClient.Notifier = &notify.Notifier{On: on, Sinks: sinks}
+	}
+
+	return nil
+}
+
+// addAuditFlags adds the flag that chooses where the audit log is written.
+func addAuditFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&auditLogFlag, "audit-log", "", "append a JSON line for every planned, completed, failed and skipped deregistration to this file, `-` for stdout, or an s3://bucket/prefix; never silenced by --quiet")
+}
+
+// closeAudit closes the ECSClient's Audit log, if it has one, which uploads any lines still
+// buffered. It returns err, or the error closing the log if err is nil.
+func closeAudit(ecsClient *ecsclient.ECSClient, err error) error {
+	if ecsClient.Audit == nil {
+		return err
+	}
+
+	if closeErr := ecsClient.Audit.Close(); closeErr != nil && err == nil {
+		return fmt.Errorf("unable to write the audit log: %v", closeErr)
+	}
+
+	return err
+}
+
+// configureAudit gives the ECSClient the Audit log chosen by the flag added by addAuditFlags,
+// if any. The client's session, and its Archive if it has one, must be configured.
+func (o options) configureAudit(ecsClient *ecsclient.ECSClient) error {
+	if o.auditLog != "" && o.fromSnapshot != "" {
+		return errors.New("can't set audit-log flag alongside from-snapshot flag")
+	}
+
+	logger, err := o.newAuditLogger(ecsClient)
+	if err != nil {
+		return err
+	}
+
+	ecsClient.Audit = logger
+
+	return nil
+}
+
+// newAuditLogger creates the audit.Logger chosen by the flag added by addAuditFlags, or nil
+// if there is none. The client's session, and its Archive if it has one, must be configured.
+func (o options) newAuditLogger(ecsClient *ecsclient.ECSClient) (*audit.Logger, error) {
+	if o.auditLog == "" {
+		return nil, nil
+	}
+
+	identity, err := audit.CallerIdentity(sts.New(ecsClient.Session))
+	if err != nil {
+		return nil, err
+	}
+
+	region := aws.StringValue(ecsClient.Session.Config.Region)
+	logger := audit.New(nil, identity, region)
+
+	// an archived run's audit log and manifest share a name
+	if ecsClient.Archive != nil {
+		logger.Run = ecsClient.Archive.Run()
+	}
+
+	switch {
+	case o.auditLog == "-":
+		logger.Sink = audit.WriterSink{Writer: os.Stdout}
+
+	case strings.HasPrefix(o.auditLog, "s3://"):
+		store, err := archive.NewS3Store(s3.New(ecsClient.Session), o.auditLog)
+		if err != nil {
+			return nil, err
+		}
+
+		prefix := fmt.Sprintf("%s/%s/audit/%s", identity.Account, region, logger.Run)
+		logger.Sink = &audit.ObjectSink{Store: store, Prefix: prefix}
+
+	default:
+		sink, err := audit.OpenFile(o.auditLog)
+		if err != nil {
+			return nil, err
+		}
+
+		logger.Sink = sink
+	}
+
+	return logger, nil
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var allRevisionsFlag bool
+var familyFlag []string
+var forceFlag bool
+var fromFileFlag string
+
+func init() {
+	ecsTaskDeregisterCmd.Flags().BoolVarP(&applyFlag, "apply", "a", false, "actually perform task definition deregistration")
+	ecsTaskDeregisterCmd.Flags().BoolVar(&allRevisionsFlag, "all-revisions", false, "confirm that --family deregisters every ACTIVE revision of the family")
+	addArchiveFlags(ecsTaskDeregisterCmd)
+	addAuditFlags(ecsTaskDeregisterCmd)
+	ecsTaskDeregisterCmd.Flags().StringArrayVar(&familyFlag, "family", nil, "deregister a family's revisions; requires --all-revisions; repeatable")
+	ecsTaskDeregisterCmd.Flags().BoolVar(&forceFlag, "force", false, "deregister targets even when a service, task or schedule uses them")
+	addLockFlags(ecsTaskDeregisterCmd)
+	ecsTaskDeregisterCmd.Flags().StringVar(&fromFileFlag, "from-file", "", "deregister the task definitions listed in this file, one ARN or family:revision per line")
+	ecsTaskDeregisterCmd.Flags().IntVar(&revalidateEveryFlag, "revalidate-every", 100, "while deregistering, recheck which task definitions are in use after this many, and skip any that became active (0 to never recheck)")
+	addTraceFlags(ecsTaskDeregisterCmd)
+	ecsTaskCmd.AddCommand(ecsTaskDeregisterCmd)
+}
+
+var ecsTaskDeregisterCmd = &cobra.Command{
+	Use:   "deregister",
+	Short: "Deregister specific task definitions (dry run by default).",
+	Long: `Deregister specific task definitions (dry run by default).
+
+Deregisters the task definitions listed with --from-file, and every ACTIVE revision of
+each --family given with --all-revisions, whatever the cleanup policy would keep. Blank
+lines and lines starting with # in the file are ignored.
+
+Nothing is deregistered if a service, running task or schedule uses any of them, unless
+--force is given. Schedules are the ECS targets of EventBridge rules on the default event
+bus; a target naming just a family uses its latest ACTIVE revision. Deregistration backs off when throttled, and can archive and audit
+exactly as ` + "`ecs-task --apply`" + ` does.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if fromFileFlag == "" && len(familyFlag) == 0 {
+			fmt.Println("Specify task definitions with the from-file or family flag.")
+			os.Exit(1)
+		}
+
+		if len(familyFlag) > 0 != allRevisionsFlag {
+			fmt.Println("The family and all-revisions flags must be set together.")
+			os.Exit(1)
+		}
+
+		targets := familyFlag
+		if fromFileFlag != "" {
+			listed, err := readTargets(fromFileFlag)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+			targets = append(targets, listed...)
+		}
+
+		opts := flagOptions()
+		ecsClient, err := opts.newECSClient()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		ecsClient.Flags.Apply = applyFlag
+		ecsClient.Flags.Force = forceFlag
+		ecsClient.Flags.RevalidateEvery = revalidateEveryFlag
+
+		if err := ecsClient.ConfigureSession(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if applyFlag {
+			if err := opts.configureArchive(ecsClient); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+
+		if err := opts.configureAudit(ecsClient); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if err := opts.configureLock(ecsClient); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		err = closeAudit(ecsClient, ecsClient.DeregisterTargets(targets))
+
+		shutdownTracing()
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+// readTargets reads the task definitions listed in a file. Each must name a single revision,
+// so a whole family is only ever deregistered through --family and --all-revisions.
+func readTargets(name string) ([]string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var targets []string
+	scanner := bufio.NewScanner(f)
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if !strings.Contains(line, ":") {
+			return nil, fmt.Errorf("%s:%d: %q names a whole family; use the family and all-revisions flags for that", name, n, line)
+		}
+
+		targets = append(targets, line)
+	}
+
+	return targets, scanner.Err()
+}
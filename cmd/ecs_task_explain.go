@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/quintilesims/go-ecs-cleaner/planner"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	addPlanFlags(ecsTaskExplainCmd)
+	ecsTaskCmd.AddCommand(ecsTaskExplainCmd)
+}
+
+var ecsTaskExplainCmd = &cobra.Command{
+	Use:   "explain <family[:revision]|arn>",
+	Short: "Explain why task definitions would be kept or deregistered.",
+	Long: `Explain why task definitions would be kept or deregistered.
+
+Runs discovery, plans exactly as ` + "`ecs-task`" + ` would with the same flags, and prints
+the decision for each matching ACTIVE revision along with every rule that fired
+for it. Pass a family name to explain all of its revisions.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := flagOptions()
+		ecsClient, err := opts.newECSClient()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if err := opts.configurePlan(ecsClient); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		inv, err := ecsClient.Discover()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		matches, err := planner.Build(inv, ecsClient.Policy()).Match(args[0])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if len(matches) == 0 {
+			fmt.Printf("No ACTIVE task definitions match %s.\n", args[0])
+			os.Exit(1)
+		}
+
+		for _, decision := range matches {
+			fmt.Printf("%s: %s\n", decision.ARN, decision.Action)
+			for _, reason := range decision.Reasons {
+				fmt.Printf("  - %s: %s\n", reason.Code, reason.Detail)
+			}
+		}
+	},
+}
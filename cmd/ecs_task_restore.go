@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/quintilesims/go-ecs-cleaner/archive"
+	"github.com/spf13/cobra"
+)
+
+var manifestFlag string
+
+func init() {
+	ecsTaskRestoreCmd.Flags().BoolVarP(&applyFlag, "apply", "a", false, "actually register the task definitions")
+	ecsTaskRestoreCmd.Flags().StringVar(&archiveDirFlag, "archive-dir", "", "read the archive from this directory")
+	ecsTaskRestoreCmd.Flags().StringVar(&archiveS3Flag, "archive-s3", "", "read the archive from this `s3://bucket/prefix`")
+	ecsTaskRestoreCmd.Flags().StringVar(&manifestFlag, "manifest", "", "restore every task definition a run deregistered, given its manifest's key in the archive")
+	ecsTaskCmd.AddCommand(ecsTaskRestoreCmd)
+}
+
+var ecsTaskRestoreCmd = &cobra.Command{
+	Use:   "restore [arn...]",
+	Short: "Register archived task definitions again (dry run by default).",
+	Long: `Register archived task definitions again (dry run by default).
+
+Reads task definitions from an archive written with --archive-dir or --archive-s3,
+either by ARN or, with --manifest, every one a run deregistered. Each is registered
+as a new revision of its family with the same settings and tags, and the new ARN
+is printed next to the old one.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if (len(args) == 0) == (manifestFlag == "") {
+			fmt.Println("Specify either task definition ARNs or the manifest flag.")
+			os.Exit(1)
+		}
+
+		opts := flagOptions()
+		ecsClient, err := opts.newECSClient()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		ecsClient.Flags.Apply = applyFlag
+
+		if err := ecsClient.ConfigureSession(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		store, err := opts.archiveStore(ecsClient)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if store == nil {
+			fmt.Println("Specify the archive with the archive-dir or archive-s3 flag.")
+			os.Exit(1)
+		}
+
+		arns := args
+		if manifestFlag != "" {
+			manifest, err := archive.LoadManifest(store, manifestFlag)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+			for _, entry := range manifest.Entries {
+				if entry.Status == archive.Deregistered {
+					arns = append(arns, entry.ARN)
+				}
+			}
+		}
+
+		var outputs []*ecs.DescribeTaskDefinitionOutput
+		for _, arn := range arns {
+			output, err := archive.Load(store, arn)
+			if err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+
+			outputs = append(outputs, output)
+		}
+
+		restorations, err := ecsClient.RestoreTaskDefinitions(outputs)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		for _, restoration := range restorations {
+			if restoration.Err != nil {
+				os.Exit(1)
+			}
+		}
+	},
+}
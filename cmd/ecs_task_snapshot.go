@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var includeTagsFlag bool
+var outFlag string
+
+func init() {
+	ecsTaskSnapshotCmd.Flags().BoolVar(&includeTagsFlag, "include-tags", false, "also record task definition tags, for replaying --protect-tag (one call per task definition)")
+	ecsTaskSnapshotCmd.Flags().StringVarP(&outFlag, "out", "o", "", "file to write the snapshot to (required)")
+	ecsTaskSnapshotCmd.MarkFlagRequired("out")
+	ecsTaskCmd.AddCommand(ecsTaskSnapshotCmd)
+}
+
+var ecsTaskSnapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Capture everything discovery reads into a snapshot file.",
+	Long: `Capture everything discovery reads into a snapshot file.
+
+The snapshot records the account's clusters, services, task definitions and running
+tasks, the task definitions EventBridge rules run, the registration dates of task definitions in families no service uses, and
+with --include-tags, task definition tags. Replay it offline, with no AWS calls, using:
+
+  go-ecs-cleaner ecs-task --from-snapshot FILE`,
+	Run: func(cmd *cobra.Command, args []string) {
+		opts := flagOptions()
+		ecsClient, err := opts.newECSClient()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		ecsClient.Flags.CollectRegistrations = true
+		ecsClient.Flags.CollectTags = includeTagsFlag
+
+		if err := ecsClient.ConfigureSession(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		inv, err := ecsClient.Discover()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if err := inv.Save(outFlag); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if !quietFlag {
+			fmt.Printf("Wrote snapshot to %s.\n", outFlag)
+		}
+	},
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var logGroupPrefixFlag string
+var logRetentionDaysFlag int
+
+// retentionDays are the retention periods, in days, CloudWatch Logs accepts.
+var retentionDays = []int{1, 3, 5, 7, 14, 30, 60, 90, 120, 150, 180, 365, 400, 545, 731, 1827, 2192, 2557, 2922, 3288, 3653}
+
+func init() {
+	logGroupsCmd.Flags().BoolVarP(&applyFlag, "apply", "a", false, "actually delete, or set the retention of, unreferenced log groups")
+	logGroupsCmd.Flags().BoolVarP(&debugFlag, "debug", "d", false, "enable for all the output")
+	logGroupsCmd.Flags().IntVar(&idleDaysFlag, "idle-days", 90, "only touch log groups that have had no events for this many days")
+	logGroupsCmd.Flags().StringVar(&logGroupPrefixFlag, "prefix", "", "only consider log groups whose names start with this, e.g. \"/ecs/\"")
+	logGroupsCmd.Flags().BoolVarP(&quietFlag, "quiet", "q", false, "minimize output")
+	logGroupsCmd.Flags().IntVar(&logRetentionDaysFlag, "set-retention", 0, "rather than delete log groups, set them to keep events for this many days")
+	addTraceFlags(logGroupsCmd)
+	logGroupsCmd.Flags().BoolVarP(&verboseFlag, "verbose", "v", false, "enable for chattier output")
+	rootCmd.AddCommand(logGroupsCmd)
+}
+
+var logGroupsCmd = &cobra.Command{
+	Use:   "log-groups",
+	Short: "Delete log groups no task definition logs to (dry run by default).",
+	Long: `Delete log groups no task definition logs to (dry run by default).
+
+The awslogs-group options of the containers of every ACTIVE task definition,
+and of the task definitions of running tasks, are collected. A log group whose
+name starts with --prefix is deleted if none of them names it and it has had
+no events, and wasn't created, in the last --idle-days.
+
+With --set-retention N, such log groups are set to keep events for N days
+instead, so their history ages out rather than going at once; those that
+already keep events for N days or fewer are left alone. If any task
+definition, cluster or task can't be listed, nothing is changed. Changes back
+off when throttled.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if logGroupPrefixFlag == "" {
+			fmt.Println("The prefix flag is required, e.g. --prefix /ecs/.")
+			os.Exit(1)
+		}
+
+		if idleDaysFlag < 0 {
+			fmt.Println("The idle-days flag must not be negative.")
+			os.Exit(1)
+		}
+
+		if logRetentionDaysFlag != 0 && !validRetentionDays(logRetentionDaysFlag) {
+			fmt.Printf("The set-retention flag must be one of %v.\n", retentionDays)
+			os.Exit(1)
+		}
+
+		opts := flagOptions()
+		ecsClient, err := opts.newECSClient()
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		ecsClient.Flags.Apply = applyFlag
+		ecsClient.Flags.IdleDays = idleDaysFlag
+		ecsClient.Flags.LogGroupPrefix = logGroupPrefixFlag
+		ecsClient.Flags.LogRetentionDays = logRetentionDaysFlag
+
+		if err := ecsClient.ConfigureSession(); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		err = ecsClient.CleanupLogGroups()
+		shutdownTracing()
+
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	},
+}
+
+// validRetentionDays reports whether CloudWatch Logs accepts the retention period.
+func validRetentionDays(days int) bool {
+	for _, valid := range retentionDays {
+		if days == valid {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import "time"
+
+// options are the flags that ECSClients are built from, copied out of the flag variables
+// once. The server builds a client for each request, concurrently, from the same options, so
+// building one must never read or write the flag variables.
+type options struct {
+	apply        bool
+	debug        bool
+	fromSnapshot string
+	quiet        bool
+	verbose      bool
+
+	// session
+	caBundle         string
+	endpointURL      string
+	noVerifySSL      bool
+	serviceEndpoints map[string]string
+
+	// plan
+	cutoff           int
+	gracePeriod      time.Duration
+	keepBeforeActive int
+	orphanKeep       int
+	orphanKeepDays   int
+	protectTags      []string
+
+	// limits
+	maxDeregistrations int
+	maxFamilyFraction  float64
+	maxFraction        float64
+	retireFamilies     bool
+	revalidateEvery    int
+
+	archiveDir string
+	archiveS3  string
+	auditLog   string
+
+	unlockFirst bool
+	lockFile    string
+	lockHolder  string
+	lockTable   string
+	lockTTL     time.Duration
+
+	notifyEventBridge string
+	notifyOn          string
+	notifySlack       string
+	notifySNS         string
+	notifyWebhook     string
+
+	traceFile string
+	traceOTLP string
+}
+
+// flagOptions copies the options out of the flag variables. Debug output implies verbose.
+func flagOptions() options {
+	return options{
+		apply:        applyFlag,
+		debug:        debugFlag,
+		fromSnapshot: fromSnapshotFlag,
+		quiet:        quietFlag,
+		verbose:      verboseFlag || debugFlag,
+
+		caBundle:         caBundleFlag,
+		endpointURL:      endpointURLFlag,
+		noVerifySSL:      noVerifySSLFlag,
+		serviceEndpoints: serviceEndpointsFlag,
+
+		cutoff:           cutoffFlag,
+		gracePeriod:      gracePeriodFlag,
+		keepBeforeActive: keepBeforeActiveFlag,
+		orphanKeep:       orphanKeepFlag,
+		orphanKeepDays:   orphanKeepDaysFlag,
+		protectTags:      protectTagFlag,
+
+		maxDeregistrations: maxDeregistrationsFlag,
+		maxFamilyFraction:  maxFamilyFractionFlag,
+		maxFraction:        maxFractionFlag,
+		retireFamilies:     retireFamiliesFlag,
+		revalidateEvery:    revalidateEveryFlag,
+
+		archiveDir: archiveDirFlag,
+		archiveS3:  archiveS3Flag,
+		auditLog:   auditLogFlag,
+
+		unlockFirst: forceUnlockFlag,
+		lockFile:    lockFileFlag,
+		lockHolder:  lockHolderFlag,
+		lockTable:   lockTableFlag,
+		lockTTL:     lockTTLFlag,
+
+		notifyEventBridge: notifyEventBridgeFlag,
+		notifyOn:          notifyOnFlag,
+		notifySlack:       notifySlackFlag,
+		notifySNS:         notifySNSFlag,
+		notifyWebhook:     notifyWebhookFlag,
+
+		traceFile: traceFileFlag,
+		traceOTLP: traceOTLPFlag,
+	}
+}
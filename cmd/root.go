@@ -4,6 +4,18 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var caBundleFlag string
+var endpointURLFlag string
+var noVerifySSLFlag bool
+var serviceEndpointsFlag map[string]string
+
+func init() {
+	rootCmd.PersistentFlags().StringVar(&caBundleFlag, "ca-bundle", "", "path to a PEM bundle of CA certificates to trust when connecting to AWS")
+	rootCmd.PersistentFlags().StringVar(&endpointURLFlag, "endpoint-url", "", "send all AWS requests to this URL (e.g. LocalStack or a VPC interface endpoint)")
+	rootCmd.PersistentFlags().BoolVar(&noVerifySSLFlag, "no-verify-ssl", false, "skip verification of AWS endpoints' TLS certificates")
+	rootCmd.PersistentFlags().StringToStringVar(&serviceEndpointsFlag, "service-endpoint", nil, "per-service endpoint override as service=URL, keyed by endpoint ID (e.g. ecs=http://localhost:4566)")
+}
+
 // Execute executes the root command.
 func Execute() error {
 	return rootCmd.Execute()
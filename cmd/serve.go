@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/quintilesims/go-ecs-cleaner/daemon"
+	"github.com/quintilesims/go-ecs-cleaner/metrics"
+	"github.com/spf13/cobra"
+)
+
+var listenFlag string
+var runOnStartFlag bool
+var scheduleFlag string
+
+func init() {
+	serveCmd.Flags().BoolVarP(&applyFlag, "apply", "a", false, "actually perform task definition deregistration")
+	addCleanupFlags(serveCmd)
+	serveCmd.Flags().BoolVarP(&debugFlag, "debug", "d", false, "enable for all the output")
+	serveCmd.Flags().StringVar(&listenFlag, "listen", ":8080", "address to serve /healthz, /readyz, /last-run and /metrics on")
+	serveCmd.Flags().BoolVarP(&quietFlag, "quiet", "q", false, "minimize output")
+	serveCmd.Flags().BoolVar(&runOnStartFlag, "run-on-start", false, "also run a cleanup as soon as the server starts")
+	serveCmd.Flags().StringVar(&scheduleFlag, "schedule", "", "cron schedule to run cleanups on, in UTC, e.g. \"0 3 * * *\" or \"@every 6h\" (required)")
+	serveCmd.Flags().BoolVarP(&verboseFlag, "verbose", "v", false, "enable for chattier output")
+	rootCmd.AddCommand(serveCmd)
+}
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run task definition cleanups on a schedule, as a long-lived process.",
+	Long: `Run task definition cleanups on a schedule, as a long-lived process.
+
+Each run is configured by the same flags as ecs-task, and is a dry run unless
+--apply is set. A run that comes due while the previous one is still going is
+skipped. While running, serve answers on --listen:
+
+/healthz   liveness
+/readyz    readiness; 503 once shutting down
+/last-run  the last run's result, as JSON
+/metrics   Prometheus metrics
+
+On SIGINT or SIGTERM it stops scheduling runs, stops any in progress before
+its next AWS call or deregistration, cutting short any backoff, and exits.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if scheduleFlag == "" {
+			fmt.Println("The schedule flag is required.")
+			os.Exit(1)
+		}
+
+		if fromSnapshotFlag != "" {
+			fmt.Println("Can't set from-snapshot flag when serving.")
+			os.Exit(1)
+		}
+
+		m := metrics.New()
+		opts := flagOptions()
+
+		// fail fast on flags that would fail every run, and release a stale lock only once
+		ecsClient, err := opts.newCleanupClient(m)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if err := opts.configureLock(ecsClient); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		d, err := daemon.New(scheduleFlag, func() error { return runCleanup(ctx, opts, m) })
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		mux := d.Handler()
+		mux.Handle("/metrics", m.Handler())
+		server := &http.Server{Addr: listenFlag, Handler: mux}
+
+		go func() {
+			if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}()
+
+		signals := make(chan os.Signal, 2)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+		go func() {
+			<-signals
+			fmt.Println("Shutting down once any run in progress stops; signal again to stop now.")
+			cancel()
+
+			<-signals
+			os.Exit(1)
+		}()
+
+		if !opts.quiet {
+			fmt.Printf("Serving on %s; cleaning up on schedule %q.\n", listenFlag, scheduleFlag)
+		}
+
+		if runOnStartFlag {
+			d.Trigger()
+		}
+
+		d.Run(ctx)
+		shutdownTracing()
+
+		shutdownCtx, stop := context.WithTimeout(context.Background(), 10*time.Second)
+		defer stop()
+		server.Shutdown(shutdownCtx)
+	},
+}
+
+// runCleanup runs one scheduled cleanup with a client of its own, so each run has a fresh
+// archive manifest and audit run. Once ctx is done, the run stops before its next
+// deregistration.
+func runCleanup(ctx context.Context, opts options, m *metrics.Metrics) error {
+	ecsClient, err := opts.newCleanupClient(m)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	ecsClient.Context = ctx
+
+	l, err := opts.newLock(ecsClient)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	ecsClient.Lock = l
+
+	if ecsClient.Audit, err = opts.newAuditLogger(ecsClient); err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	err = closeAudit(ecsClient, ecsClient.CleanupTaskDefinitions())
+
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	return err
+}
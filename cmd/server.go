@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/quintilesims/go-ecs-cleaner/api"
+	"github.com/quintilesims/go-ecs-cleaner/ecsclient"
+	"github.com/quintilesims/go-ecs-cleaner/metrics"
+	"github.com/spf13/cobra"
+)
+
+// tokenEnv names the environment variable that holds the server's bearer token, so the token
+// stays out of the process list.
+const tokenEnv = "API_TOKEN"
+
+func init() {
+	addCleanupFlags(serverCmd)
+	serverCmd.Flags().BoolVarP(&debugFlag, "debug", "d", false, "enable for all the output")
+	serverCmd.Flags().StringVar(&listenFlag, "listen", ":8080", "address to serve the API, /healthz and /metrics on")
+	serverCmd.Flags().BoolVarP(&quietFlag, "quiet", "q", false, "minimize output")
+	serverCmd.Flags().BoolVarP(&verboseFlag, "verbose", "v", false, "enable for chattier output")
+	rootCmd.AddCommand(serverCmd)
+}
+
+var serverCmd = &cobra.Command{
+	Use:   "server",
+	Short: "Serve a REST API for planning, approving and applying cleanups.",
+	Long: `Serve a REST API for planning, approving and applying cleanups.
+
+Plans are configured by the same flags as ecs-task, which a plan's overrides
+may change. Every request but /healthz must carry the bearer token in the
+API_TOKEN environment variable. On --listen, server answers:
+
+POST /plans                plan a cleanup with the JSON overrides in the body, one at a time
+GET  /plans/{id}           the plan, and what it would deregister
+POST /plans/{id}/approve   approve a plan
+POST /plans/{id}/apply     apply an approved plan; one apply runs at a time
+GET  /runs/{id}            an apply's status and summary
+GET  /history              every apply, newest first
+GET  /healthz              liveness
+GET  /metrics              Prometheus metrics
+
+Applying a plan plans afresh and deregisters only those of the approved task
+definitions that would still be deregistered. On SIGINT or SIGTERM it stops
+serving, waits for any plan or apply in progress to finish, and exits.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		token := os.Getenv(tokenEnv)
+		if token == "" {
+			fmt.Printf("The %s environment variable is required.\n", tokenEnv)
+			os.Exit(1)
+		}
+
+		if fromSnapshotFlag != "" {
+			fmt.Println("Can't set from-snapshot flag when serving.")
+			os.Exit(1)
+		}
+
+		m := metrics.New()
+		opts := flagOptions()
+
+		// fail fast on flags that would fail every plan, and release a stale lock only once
+		ecsClient, err := opts.newCleanupClient(m)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		if err := opts.configureLock(ecsClient); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		s := api.New(token, func(apply bool) (*ecsclient.ECSClient, func() error, error) {
+			return newServerClient(opts, m, apply)
+		})
+
+		mux := s.Handler()
+		mux.Handle("/metrics", m.Handler())
+		server := &http.Server{Addr: listenFlag, Handler: mux}
+
+		signals := make(chan os.Signal, 2)
+		signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+		go func() {
+			<-signals
+			fmt.Println("Shutting down after any plan or apply in progress; signal again to stop now.")
+
+			ctx, stop := context.WithTimeout(context.Background(), 10*time.Second)
+			defer stop()
+			server.Shutdown(ctx)
+
+			<-signals
+			os.Exit(1)
+		}()
+
+		if !opts.quiet {
+			fmt.Printf("Serving the API on %s.\n", listenFlag)
+		}
+
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+
+		s.Wait()
+		shutdownTracing()
+	},
+}
+
+// newServerClient creates the ECSClient for one of the server's plans, or, if apply is set,
+// for one of its applies, with its own archive manifest, audit run and lock. The returned
+// func closes its audit log. It's called concurrently, so it builds the client from opts
+// alone.
+func newServerClient(opts options, m *metrics.Metrics, apply bool) (*ecsclient.ECSClient, func() error, error) {
+	opts.apply = apply
+
+	ecsClient, err := opts.newCleanupClient(m)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !apply {
+		return ecsClient, func() error { return nil }, nil
+	}
+
+	l, err := opts.newLock(ecsClient)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ecsClient.Lock = l
+
+	if ecsClient.Audit, err = opts.newAuditLogger(ecsClient); err != nil {
+		return nil, nil, err
+	}
+
+	return ecsClient, func() error { return closeAudit(ecsClient, nil) }, nil
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/quintilesims/go-ecs-cleaner/metrics"
+	"github.com/quintilesims/go-ecs-cleaner/notify"
+)
+
+// Run with -race: the server's handlers build clients concurrently.
+func Test_newServerClient_Concurrent(t *testing.T) {
+	opts := options{
+		archiveDir:  t.TempDir(),
+		cutoff:      5,
+		debug:       true,
+		endpointURL: "http://127.0.0.1:1",
+		lockTTL:     time.Minute,
+		notifyOn:    notify.Always,
+		verbose:     true,
+	}
+
+	m := metrics.New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		apply := i%2 == 0
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ecsClient, done, err := newServerClient(opts, m, apply)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer done()
+
+			if ecsClient.Flags.Apply != apply || (ecsClient.Archive != nil) != apply {
+				t.Errorf("Expected apply %v with an archive only if applying, got %v and %v\n", apply, ecsClient.Flags.Apply, ecsClient.Archive)
+			}
+
+			if !ecsClient.Flags.Verbose || !ecsClient.Flags.Debug {
+				t.Errorf("Expected a verbose, debug client, got %+v\n", ecsClient.Flags)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if verboseFlag || applyFlag {
+		t.Error("Expected the flag variables untouched")
+	}
+}
@@ -0,0 +1,193 @@
+// Package daemon runs a job, such as a cleanup, on a cron schedule in a long-lived process,
+// one run at a time, and reports on it over HTTP for health checks and monitoring.
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// The Results of a Run.
+const (
+	Running   = "running"
+	Succeeded = "success"
+	Failed    = "failure"
+)
+
+// Run records one run of the job.
+type Run struct {
+	StartedAt       time.Time  `json:"startedAt"`
+	FinishedAt      *time.Time `json:"finishedAt,omitempty"`
+	DurationSeconds float64    `json:"durationSeconds,omitempty"`
+	Result          string     `json:"result"`
+	Error           string     `json:"error,omitempty"`
+}
+
+// Status is what /last-run reports.
+type Status struct {
+	LastRun *Run      `json:"lastRun"`
+	NextRun time.Time `json:"nextRun"`
+
+	// SkippedRuns counts scheduled runs that were skipped because a run was in progress.
+	SkippedRuns int `json:"skippedRuns"`
+}
+
+// Daemon triggers a Job on a Schedule. A run that comes due while another is in progress is
+// skipped, so runs never overlap.
+type Daemon struct {
+	Schedule cron.Schedule
+	Job      func() error
+
+	// Now is the clock the Schedule is read against; it defaults to the current UTC time.
+	Now func() time.Time
+
+	mu      sync.Mutex
+	ready   bool
+	running bool
+	last    *Run
+	next    time.Time
+	skipped int
+	wg      sync.WaitGroup
+}
+
+// New creates a Daemon that runs job on a standard five-field cron schedule, such as
+// "0 3 * * *", or a descriptor such as "@daily" or "@every 6h". Times are in UTC unless the
+// spec starts with "CRON_TZ=".
+func New(spec string, job func() error) (*Daemon, error) {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %v", spec, err)
+	}
+
+	return &Daemon{
+		Schedule: schedule,
+		Job:      job,
+		Now:      func() time.Time { return time.Now().UTC() },
+	}, nil
+}
+
+// Trigger starts a run in the background unless one is in progress, and reports whether it
+// did.
+func (d *Daemon) Trigger() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.running {
+		d.skipped++
+		return false
+	}
+
+	run := &Run{StartedAt: d.Now(), Result: Running}
+	d.running, d.last = true, run
+	d.wg.Add(1)
+
+	go func() {
+		defer d.wg.Done()
+		err := d.Job()
+
+		d.mu.Lock()
+		defer d.mu.Unlock()
+
+		finished := d.Now()
+		run.FinishedAt = &finished
+		run.DurationSeconds = finished.Sub(run.StartedAt).Seconds()
+		run.Result = Succeeded
+
+		if err != nil {
+			run.Result, run.Error = Failed, err.Error()
+		}
+
+		d.running = false
+	}()
+
+	return true
+}
+
+// Run triggers the Job on the Schedule until ctx is done, then waits for any run in progress
+// to finish. The Daemon is ready from when Run starts until ctx is done.
+func (d *Daemon) Run(ctx context.Context) {
+	d.setReady(true)
+
+	for {
+		next := d.Schedule.Next(d.Now())
+
+		d.mu.Lock()
+		d.next = next
+		d.mu.Unlock()
+
+		timer := time.NewTimer(next.Sub(d.Now()))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			d.setReady(false)
+			d.wg.Wait()
+			return
+
+		case <-timer.C:
+			if !d.Trigger() {
+				fmt.Printf("Skipping the run due at %s: the previous run is still in progress.\n", next.Format(time.RFC3339))
+			}
+		}
+	}
+}
+
+// Status reports on the last run and the next one.
+func (d *Daemon) Status() Status {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	status := Status{NextRun: d.next, SkippedRuns: d.skipped}
+	if d.last != nil {
+		last := *d.last
+		status.LastRun = &last
+	}
+
+	return status
+}
+
+func (d *Daemon) setReady(ready bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.ready = ready
+}
+
+// Handler serves the Daemon's endpoints:
+//
+//	/healthz   200 while the process is up
+//	/readyz    200 while the Daemon is scheduling runs, 503 before and while shutting down
+//	/last-run  the Status, as JSON
+func (d *Daemon) Handler() *http.ServeMux {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		d.mu.Lock()
+		ready := d.ready
+		d.mu.Unlock()
+
+		if !ready {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+
+		fmt.Fprintln(w, "ok")
+	})
+
+	mux.HandleFunc("/last-run", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(d.Status())
+	})
+
+	return mux
+}
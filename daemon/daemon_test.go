@@ -0,0 +1,146 @@
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_New(t *testing.T) {
+	for _, spec := range []string{"0 3 * * *", "@daily", "@every 6h", "CRON_TZ=Europe/London 0 3 * * *"} {
+		if _, err := New(spec, nil); err != nil {
+			t.Errorf("Expected %q to parse, got %v\n", spec, err)
+		}
+	}
+
+	for _, spec := range []string{"", "0 3 * *", "@sometimes"} {
+		if _, err := New(spec, nil); err == nil {
+			t.Errorf("Expected an error for %q\n", spec)
+		}
+	}
+}
+
+func Test_Trigger(t *testing.T) {
+	release := make(chan struct{})
+	d, err := New("@daily", func() error {
+		<-release
+		return errors.New("boom")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !d.Trigger() {
+		t.Fatal("Expected the first trigger to start a run")
+	}
+
+	if d.Trigger() {
+		t.Error("Expected a trigger during a run to be skipped")
+	}
+
+	status := d.Status()
+	if status.LastRun == nil || status.LastRun.Result != Running || status.SkippedRuns != 1 {
+		t.Errorf("Expected a running run and one skipped, got %+v\n", status)
+	}
+
+	close(release)
+	d.wg.Wait()
+
+	status = d.Status()
+	if status.LastRun.Result != Failed || status.LastRun.Error != "boom" || status.LastRun.FinishedAt == nil {
+		t.Errorf("Expected a finished, failed run, got %+v\n", status.LastRun)
+	}
+
+	if !d.Trigger() {
+		t.Error("Expected a trigger after the run finished to start another")
+	}
+
+	d.wg.Wait()
+}
+
+func Test_Run(t *testing.T) {
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	d, err := New("@every 10ms", func() error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+
+		<-release
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		d.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected a scheduled run")
+	}
+
+	cancel()
+
+	select {
+	case <-done:
+		t.Fatal("Expected Run to wait for the run in progress")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-done
+
+	if status := d.Status(); status.LastRun.Result != Succeeded || status.NextRun.IsZero() {
+		t.Errorf("Expected a successful run and a next run, got %+v\n", status)
+	}
+}
+
+func Test_Handler(t *testing.T) {
+	d, err := New("@daily", func() error { return nil })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		d.Handler().ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w
+	}
+
+	if w := get("/healthz"); w.Code != http.StatusOK {
+		t.Errorf("Expected /healthz to be OK, got %d\n", w.Code)
+	}
+
+	if w := get("/readyz"); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected /readyz to be unavailable before Run, got %d\n", w.Code)
+	}
+
+	d.setReady(true)
+	if w := get("/readyz"); w.Code != http.StatusOK {
+		t.Errorf("Expected /readyz to be OK once running, got %d\n", w.Code)
+	}
+
+	d.Trigger()
+	d.wg.Wait()
+
+	var status Status
+	if err := json.NewDecoder(get("/last-run").Body).Decode(&status); err != nil {
+		t.Fatal(err)
+	}
+
+	if status.LastRun == nil || status.LastRun.Result != Succeeded {
+		t.Errorf("Expected the last run to have succeeded, got %+v\n", status)
+	}
+}
@@ -0,0 +1,121 @@
+package ecsclient
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/quintilesims/go-ecs-cleaner/archive"
+)
+
+// archiveTaskDefinition saves a task definition, with its tags, to the ECSClient's Archive,
+// unless there is no Archive or it's already in `archived`.
+func (e *ECSClient) archiveTaskDefinition(arn string, archived map[string]bool) error {
+	if e.Archive == nil || archived[arn] {
+		return nil
+	}
+
+	var output *ecs.DescribeTaskDefinitionOutput
+
+	err := e.retry(func() error {
+		var err error
+		output, err = e.describeTaskDefinition(arn, true)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := e.Archive.Save(output); err != nil {
+		return err
+	}
+
+	archived[arn] = true
+	return nil
+}
+
+// recordArchive notes in the Archive's manifest what became of an archived task definition.
+func (e *ECSClient) recordArchive(arn, status string, err error) {
+	if e.Archive != nil {
+		e.Archive.Record(arn, status, err)
+	}
+}
+
+// writeArchiveManifest writes the Archive's manifest for the run, reporting any failure.
+func (e *ECSClient) writeArchiveManifest() {
+	key, err := e.Archive.WriteManifest()
+
+	switch {
+	case err != nil:
+		fmt.Println("Error writing archive manifest:", err)
+
+	case key != "" && !e.Flags.Quiet:
+		fmt.Printf("Wrote archive manifest %s.\n", key)
+	}
+}
+
+// Restoration pairs an archived task definition with the revision that registered it again.
+type Restoration struct {
+	OldARN string
+	NewARN string
+	Err    error
+}
+
+// RestoreTaskDefinitions registers archived task definitions again, oldest revision of each
+// family first, and prints the old ARN each new revision replaces. Without `--apply` it only
+// prints what it would register.
+func (e *ECSClient) RestoreTaskDefinitions(outputs []*ecs.DescribeTaskDefinitionOutput) ([]Restoration, error) {
+	sorted := make([]*ecs.DescribeTaskDefinitionOutput, len(outputs))
+	copy(sorted, outputs)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].TaskDefinition, sorted[j].TaskDefinition
+		if aws.StringValue(a.Family) != aws.StringValue(b.Family) {
+			return aws.StringValue(a.Family) < aws.StringValue(b.Family)
+		}
+
+		return aws.Int64Value(a.Revision) < aws.Int64Value(b.Revision)
+	})
+
+	var restorations []Restoration
+
+	for _, output := range sorted {
+		restoration := Restoration{OldARN: aws.StringValue(output.TaskDefinition.TaskDefinitionArn)}
+
+		if !e.Flags.Apply {
+			fmt.Printf("%s -> new revision of %s\n", restoration.OldARN, aws.StringValue(output.TaskDefinition.Family))
+			restorations = append(restorations, restoration)
+			continue
+		}
+
+		var registered *ecs.RegisterTaskDefinitionOutput
+
+		restoration.Err = e.retry(func() error {
+			var err error
+			registered, err = e.Svc.RegisterTaskDefinition(archive.RegisterInput(output))
+			return err
+		})
+
+		switch {
+		case restoration.Err != nil && e.isStopworthyError(restoration.Err):
+			return restorations, restoration.Err
+
+		case restoration.Err != nil:
+			fmt.Printf("%s -> failed: %v\n", restoration.OldARN, restoration.Err)
+
+		default:
+			restoration.NewARN = aws.StringValue(registered.TaskDefinition.TaskDefinitionArn)
+			fmt.Printf("%s -> %s\n", restoration.OldARN, restoration.NewARN)
+		}
+
+		restorations = append(restorations, restoration)
+	}
+
+	if !e.Flags.Apply && !e.Flags.Quiet {
+		fmt.Println("This is a dry run.")
+		fmt.Println("Use the `--apply` flag to register these task definitions.")
+	}
+
+	return restorations, nil
+}
@@ -0,0 +1,20 @@
+package ecsclient
+
+import (
+	"github.com/aws/aws-sdk-go/aws/request"
+)
+
+// recordRequestID remembers the ID AWS gave a completed request, successful or not, so the
+// audit log can cite it. ConfigureSession installs it on Svc.
+func (e *ECSClient) recordRequestID(r *request.Request) {
+	e.requestID = r.RequestID
+}
+
+// auditLog writes an Event for the last request to the ECSClient's Audit log, if it has one.
+func (e *ECSClient) auditLog(action, arn, detail string) error {
+	if e.Audit == nil {
+		return nil
+	}
+
+	return e.Audit.Log(action, arn, e.requestID, detail)
+}
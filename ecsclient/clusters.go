@@ -0,0 +1,237 @@
+package ecsclient
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// EmptySinceTag marks a cluster found empty with when it was first found so, as an RFC 3339
+// time. ECS doesn't record when a cluster was created, so `EmptyDays` count from it instead.
+const EmptySinceTag = "ecs-cleaner:empty-since"
+
+// describeClustersChunkSize is the most clusters DescribeClusters takes at once.
+const describeClustersChunkSize = 100
+
+// DescribeClusters describes the clusters, with their tags. Clusters deleted since they were
+// collected are left out.
+func (e *ECSClient) DescribeClusters(clusterARNs []string) ([]*ecs.Cluster, error) {
+	defer e.startSpan("DescribeClusters", attribute.Int("count", len(clusterARNs)))()
+
+	var clusters []*ecs.Cluster
+
+	for start := 0; start < len(clusterARNs); start += describeClustersChunkSize {
+		end := start + describeClustersChunkSize
+		if end > len(clusterARNs) {
+			end = len(clusterARNs)
+		}
+
+		var output *ecs.DescribeClustersOutput
+		err := e.retry(func() error {
+			var err error
+			output, err = e.Svc.DescribeClusters(&ecs.DescribeClustersInput{
+				Clusters: aws.StringSlice(clusterARNs[start:end]),
+				Include:  aws.StringSlice([]string{ecs.ClusterFieldTags}),
+			})
+
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		clusters = append(clusters, output.Clusters...)
+	}
+
+	return clusters, nil
+}
+
+// IsEmptyCluster reports whether nothing runs in, or is attached to, the cluster.
+func IsEmptyCluster(c *ecs.Cluster) bool {
+	return aws.Int64Value(c.ActiveServicesCount) == 0 &&
+		aws.Int64Value(c.RunningTasksCount) == 0 &&
+		aws.Int64Value(c.PendingTasksCount) == 0 &&
+		aws.Int64Value(c.RegisteredContainerInstancesCount) == 0 &&
+		len(c.CapacityProviders) == 0 &&
+		len(c.DefaultCapacityProviderStrategy) == 0
+}
+
+// CleanupClusters deletes empty clusters: with `EmptyDays`, only those whose EmptySinceTag is
+// at least that old. An `--apply` run tags clusters newly found empty, and untags those no
+// longer empty; a dry run only reports what it would do.
+func (e *ECSClient) CleanupClusters() (err error) {
+	endSpan := e.startSpan("CleanupClusters", attribute.Bool("apply", e.Flags.Apply))
+	defer func() {
+		e.recordSpanError(err)
+		endSpan()
+	}()
+
+	clusterARNs, err := e.CollectClusters()
+	if err != nil {
+		return err
+	}
+
+	clusters, err := e.DescribeClusters(clusterARNs)
+	if err != nil {
+		return err
+	}
+
+	now := e.Now()
+	var toDelete, toMark, toClear []string
+
+	for _, c := range clusters {
+		arn := aws.StringValue(c.ClusterArn)
+		value, tagged := clusterTag(c, EmptySinceTag)
+
+		if !IsEmptyCluster(c) {
+			if tagged {
+				toClear = append(toClear, arn)
+			}
+
+			continue
+		}
+
+		if e.Flags.EmptyDays == 0 {
+			toDelete = append(toDelete, arn)
+			continue
+		}
+
+		emptySince, err := time.Parse(time.RFC3339, value)
+		if !tagged || err != nil {
+			toMark = append(toMark, arn)
+			continue
+		}
+
+		deletableAt := emptySince.AddDate(0, 0, e.Flags.EmptyDays)
+		if now.Before(deletableAt) {
+			if e.Flags.Verbose {
+				fmt.Printf("%s has been empty since %s; deletable from %s.\n", arn, emptySince.Format(time.RFC3339), deletableAt.Format(time.RFC3339))
+			}
+
+			continue
+		}
+
+		toDelete = append(toDelete, arn)
+	}
+
+	if !e.Flags.Quiet {
+		fmt.Printf("Found %d empty clusters to delete.\n", len(toDelete))
+		for _, arn := range toDelete {
+			fmt.Println(arn)
+		}
+	}
+
+	if !e.Flags.Apply {
+		if !e.Flags.Quiet {
+			if len(toMark)+len(toClear) > 0 {
+				fmt.Printf("Would mark %d clusters empty and clear %d.\n", len(toMark), len(toClear))
+			}
+
+			if len(toDelete) > 0 {
+				fmt.Println("This is a dry run.")
+				fmt.Println("Use the `--apply` flag to delete these clusters.")
+			}
+		}
+
+		return nil
+	}
+
+	if err := e.updateEmptySinceTags(toMark, toClear, now); err != nil {
+		return err
+	}
+
+	if err := e.DeleteClusters(toDelete); err != nil {
+		return err
+	}
+
+	if !e.Flags.Quiet {
+		fmt.Println("Process finished.")
+	}
+
+	return nil
+}
+
+// DeleteClusters deletes the clusters, backing off when throttled. A cluster that can't be
+// deleted, e.g. because something started in it since it was found empty, is reported and
+// left alone.
+func (e *ECSClient) DeleteClusters(clusterARNs []string) error {
+	defer e.startSpan("DeleteClusters", attribute.Int("count", len(clusterARNs)))()
+
+	var deleted, failed int
+
+	for _, arn := range clusterARNs {
+		err := e.retry(func() error {
+			_, err := e.Svc.DeleteCluster(&ecs.DeleteClusterInput{Cluster: aws.String(arn)})
+			return err
+		})
+
+		switch {
+		case err == nil:
+			deleted++
+
+		case e.isStopworthyError(err):
+			return err
+
+		default:
+			failed++
+
+			if !e.Flags.Quiet {
+				fmt.Printf("Error deleting %s: %v\n", arn, err)
+			}
+		}
+	}
+
+	if !e.Flags.Quiet {
+		fmt.Printf("%d deleted clusters, %d errored\n", deleted, failed)
+	}
+
+	return nil
+}
+
+// updateEmptySinceTags tags the clusters in toMark as empty since now, and untags those in
+// toClear.
+func (e *ECSClient) updateEmptySinceTags(toMark, toClear []string, now time.Time) error {
+	if !e.Flags.Quiet && len(toMark) > 0 {
+		fmt.Printf("Marking %d clusters empty; they'll be deleted once they've been empty for %d days...\n", len(toMark), e.Flags.EmptyDays)
+	}
+
+	for _, arn := range toMark {
+		err := e.retry(func() error {
+			return e.tagResource(arn, EmptySinceTag, now.Format(time.RFC3339))
+		})
+
+		if err := e.handleTaggingError(arn, err); err != nil {
+			return err
+		}
+	}
+
+	if !e.Flags.Quiet && len(toClear) > 0 {
+		fmt.Printf("Clearing the empty tag from %d clusters back in use...\n", len(toClear))
+	}
+
+	for _, arn := range toClear {
+		err := e.retry(func() error {
+			return e.untagResource(arn, EmptySinceTag)
+		})
+
+		if err := e.handleTaggingError(arn, err); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// clusterTag returns the value of the cluster's tag with the key, and whether it has one.
+func clusterTag(c *ecs.Cluster, key string) (string, bool) {
+	for _, tag := range c.Tags {
+		if aws.StringValue(tag.Key) == key {
+			return aws.StringValue(tag.Value), true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,396 @@
+package ecsclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/quintilesims/go-ecs-cleaner/audit"
+	"github.com/quintilesims/go-ecs-cleaner/ecsfake"
+	"github.com/quintilesims/go-ecs-cleaner/metrics"
+	"github.com/quintilesims/go-ecs-cleaner/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newStandIn creates the fake ECS account that the end-to-end tests below drive a real
+// `ecs.ECS` client against, through ConfigureSession.
+func newStandIn() *ecsfake.ECS {
+	standIn := ecsfake.New("000000000000", "us-west-2")
+	standIn.AddTaskDefinitions("family0", 3)
+	standIn.AddTaskDefinitions("family1", 1)
+	standIn.AddService("cluster0", "service0", "family0:3", 1)
+
+	return standIn
+}
+
+// requests counts the calls a stand-in has received.
+func requests(standIn *ecsfake.ECS) int {
+	var n int
+	for _, operation := range []string{"ListClusters", "ListServices", "DescribeServices", "ListTaskDefinitions", "DeregisterTaskDefinition"} {
+		n += standIn.Calls(operation)
+	}
+
+	return n
+}
+
+// setupE2E points the AWS SDK at static credentials so the tests never read the local
+// machine's AWS configuration. The returned function restores the environment.
+func setupE2E(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "ecsclient-e2e")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{
+		"AWS_ACCESS_KEY_ID":           "AKIDSTANDIN",
+		"AWS_SECRET_ACCESS_KEY":       "standin",
+		"AWS_SESSION_TOKEN":           "",
+		"AWS_REGION":                  "us-west-2",
+		"AWS_PROFILE":                 "",
+		"AWS_CA_BUNDLE":               "",
+		"AWS_CONFIG_FILE":             filepath.Join(dir, "config"),
+		"AWS_SHARED_CREDENTIALS_FILE": filepath.Join(dir, "credentials"),
+	}
+
+	previous := make(map[string]string)
+	for key, value := range env {
+		previous[key] = os.Getenv(key)
+		os.Setenv(key, value)
+	}
+
+	return func() {
+		for key, value := range previous {
+			os.Setenv(key, value)
+		}
+
+		os.RemoveAll(dir)
+	}
+}
+
+func Test_E2E_EndpointURL(t *testing.T) {
+	defer setupE2E(t)()
+
+	standIn := newStandIn()
+	server := httptest.NewServer(standIn.Handler())
+	defer server.Close()
+
+	e := NewECSClient()
+	e.Flags.RetireFamilies = true
+	e.Flags.Apply = true
+	e.Flags.Cutoff = 1
+	e.Flags.Quiet = true
+	e.Flags.EndpointURL = server.URL
+
+	if err := e.ConfigureSession(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.CleanupTaskDefinitions(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"arn:aws:ecs:us-west-2:000000000000:task-definition/family0:1",
+		"arn:aws:ecs:us-west-2:000000000000:task-definition/family1:1",
+	}
+
+	result := standIn.TaskDefinitionARNs("INACTIVE")
+	if equal := reflect.DeepEqual(expected, result); !equal {
+		t.Errorf("Expected %v, got %v\n", expected, result)
+	}
+}
+
+func Test_E2E_Audit(t *testing.T) {
+	defer setupE2E(t)()
+
+	standIn := newStandIn()
+	server := httptest.NewServer(standIn.Handler())
+	defer server.Close()
+
+	var buf bytes.Buffer
+
+	e := NewECSClient()
+	e.Flags.RetireFamilies = true
+	e.Flags.Apply = true
+	e.Flags.Cutoff = 1
+	e.Flags.Quiet = true
+	e.Flags.EndpointURL = server.URL
+	e.Audit = audit.New(audit.WriterSink{Writer: &buf}, audit.Identity{Account: "000000000000"}, "us-west-2")
+
+	if err := e.ConfigureSession(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.CleanupTaskDefinitions(); err != nil {
+		t.Fatal(err)
+	}
+
+	actions := make(map[string][]string)
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var event audit.Event
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatal(err)
+		}
+
+		actions[event.Action] = append(actions[event.Action], event.ARN)
+
+		// quiet or not, a deregistration is logged with the request that made it
+		if event.Action == audit.Deregister && !strings.HasPrefix(event.RequestID, "ecsfake-") {
+			t.Errorf("Expected a request ID for %s, got %q\n", event.ARN, event.RequestID)
+		}
+	}
+
+	deregistered := standIn.TaskDefinitionARNs("INACTIVE")
+	sort.Strings(actions[audit.Deregister])
+
+	if !reflect.DeepEqual(deregistered, actions[audit.Plan]) || !reflect.DeepEqual(deregistered, actions[audit.Deregister]) {
+		t.Errorf("Expected plan and deregister events for %v, got %v\n", deregistered, actions)
+	}
+}
+
+func Test_E2E_Metrics(t *testing.T) {
+	defer setupE2E(t)()
+
+	standIn := newStandIn()
+	standIn.Throttle("ListClusters", 1)
+
+	server := httptest.NewServer(standIn.Handler())
+	defer server.Close()
+
+	e := NewECSClient()
+	e.Flags.RetireFamilies = true
+	e.Flags.Apply = true
+	e.Flags.Cutoff = 1
+	e.Flags.Quiet = true
+	e.Flags.EndpointURL = server.URL
+	e.Metrics = metrics.New()
+
+	if err := e.ConfigureSession(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.CleanupTaskDefinitions(); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := httptest.NewRecorder()
+	e.Metrics.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	// the SDK retries the throttled call on its own, and each attempt is counted
+	for _, expected := range []string{
+		`ecs_cleaner_api_calls_total{error_code="ThrottlingException",operation="ListClusters",service="ecs"} 1`,
+		`ecs_cleaner_api_calls_total{error_code="",operation="ListClusters",service="ecs"} 1`,
+		`ecs_cleaner_api_throttles_total{operation="ListClusters",service="ecs"} 1`,
+		`ecs_cleaner_task_definitions{outcome="discovered"} 4`,
+		`ecs_cleaner_task_definitions{outcome="deregistered"} 2`,
+		`ecs_cleaner_deregistrations_total 2`,
+		`ecs_cleaner_run_duration_seconds_count{result="success"} 1`,
+	} {
+		if !strings.Contains(recorder.Body.String(), expected) {
+			t.Errorf("Expected %q in:\n%s", expected, recorder.Body.String())
+		}
+	}
+}
+
+func Test_E2E_Tracing(t *testing.T) {
+	defer setupE2E(t)()
+
+	standIn := newStandIn()
+	standIn.Throttle("ListClusters", 1)
+
+	server := httptest.NewServer(standIn.Handler())
+	defer server.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	e := NewECSClient()
+	e.Flags.RetireFamilies = true
+	e.Flags.Apply = true
+	e.Flags.Cutoff = 1
+	e.Flags.Quiet = true
+	e.Flags.EndpointURL = server.URL
+	e.Tracer = provider.Tracer(tracing.Name)
+
+	if err := e.ConfigureSession(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.CleanupTaskDefinitions(); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := make(map[string][]sdktrace.ReadOnlySpan)
+	names := make(map[trace.SpanID]string)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = append(spans[span.Name()], span)
+		names[span.SpanContext().SpanID()] = span.Name()
+	}
+
+	valueOf := func(span sdktrace.ReadOnlySpan, key attribute.Key) string {
+		for _, kv := range span.Attributes() {
+			if kv.Key == key {
+				return kv.Value.Emit()
+			}
+		}
+
+		return ""
+	}
+
+	// each API call is a child of the phase that made it, and covers the SDK's own retries
+	expectedParents := map[string]string{
+		"Discover":                     "CleanupTaskDefinitions",
+		"CollectClusters":              "Discover",
+		"ecs.ListClusters":             "CollectClusters",
+		"ecs.ListServices":             "CollectServices",
+		"ecs.DescribeServices":         "DescribeServices",
+		"ecs.DeregisterTaskDefinition": "DeregisterTaskDefinitions",
+		"DeregisterTaskDefinitions":    "CleanupTaskDefinitions",
+	}
+
+	for name, parent := range expectedParents {
+		if len(spans[name]) == 0 {
+			t.Errorf("Expected a %s span\n", name)
+			continue
+		}
+
+		if result := names[spans[name][0].Parent().SpanID()]; result != parent {
+			t.Errorf("Expected %s to be a child of %s, got %q\n", name, parent, result)
+		}
+	}
+
+	if len(spans["ecs.ListClusters"]) > 0 {
+		if result := valueOf(spans["ecs.ListClusters"][0], tracing.RetryCount); result != "1" {
+			t.Errorf("Expected ListClusters to be retried once, got %q\n", result)
+		}
+	}
+
+	if len(spans["ecs.ListServices"]) > 0 {
+		if result := valueOf(spans["ecs.ListServices"][0], tracing.Cluster); !strings.HasSuffix(result, "cluster0") {
+			t.Errorf("Expected ListServices to name cluster0, got %q\n", result)
+		}
+	}
+
+	var families []string
+	for _, span := range spans["ecs.DeregisterTaskDefinition"] {
+		families = append(families, valueOf(span, tracing.Family))
+	}
+
+	sort.Strings(families)
+
+	// the cutoff keeps family0:2 as well as the service's family0:3, and no service uses family1
+	if expected := []string{"family0", "family1"}; !reflect.DeepEqual(expected, families) {
+		t.Errorf("Expected deregistrations from %v, got %v\n", expected, families)
+	}
+}
+
+func Test_E2E_ServiceEndpoint(t *testing.T) {
+	defer setupE2E(t)()
+
+	standIn := newStandIn()
+	server := httptest.NewServer(standIn.Handler())
+	defer server.Close()
+
+	e := NewECSClient()
+	e.Flags.RetireFamilies = true
+	e.Flags.Quiet = true
+	e.Flags.EndpointURL = "http://127.0.0.1:1"
+	e.Flags.ServiceEndpoints = map[string]string{"ecs": server.URL, "events": server.URL}
+
+	if err := e.ConfigureSession(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.CleanupTaskDefinitions(); err != nil {
+		t.Fatal(err)
+	}
+
+	if requests(standIn) == 0 {
+		t.Error("Expected the per-service endpoint to take priority over --endpoint-url")
+	}
+
+	if deregistered := standIn.TaskDefinitionARNs("INACTIVE"); len(deregistered) > 0 {
+		t.Errorf("Expected a dry run, got deregistrations %v\n", deregistered)
+	}
+}
+
+func Test_E2E_TLS(t *testing.T) {
+	defer setupE2E(t)()
+
+	standIn := newStandIn()
+	server := httptest.NewTLSServer(standIn.Handler())
+	defer server.Close()
+
+	bundle, err := ioutil.TempFile("", "ecsclient-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(bundle.Name())
+
+	pem.Encode(bundle, &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	bundle.Close()
+
+	testCases := map[string]func(*ECSClient){
+		"untrusted":     func(e *ECSClient) {},
+		"ca-bundle":     func(e *ECSClient) { e.Flags.CABundle = bundle.Name() },
+		"env-ca-bundle": func(e *ECSClient) { os.Setenv("AWS_CA_BUNDLE", bundle.Name()) },
+		"no-verify":     func(e *ECSClient) { e.Flags.NoVerifySSL = true },
+	}
+
+	for name, configure := range testCases {
+		before := requests(standIn)
+		os.Setenv("AWS_CA_BUNDLE", "")
+
+		e := NewECSClient()
+		e.Flags.Quiet = true
+		e.Flags.EndpointURL = server.URL
+		configure(e)
+
+		if err := e.ConfigureSession(); err != nil {
+			t.Fatalf("TestCase '%s': %v", name, err)
+		}
+
+		e.CollectClusters()
+
+		expected := name != "untrusted"
+		if reached := requests(standIn) > before; reached != expected {
+			t.Errorf("TestCase '%s': expected server reached %t, got %t\n", name, expected, reached)
+		}
+	}
+
+	// sessions are created concurrently by the server, so none may change the shared client
+	if http.DefaultClient.Transport != nil {
+		t.Error("Expected http.DefaultClient untouched")
+	}
+}
+
+func Test_ConfigureSession_InvalidEndpoint(t *testing.T) {
+	defer setupE2E(t)()
+
+	testCases := map[string]Flags{
+		"endpoint-url":     Flags{EndpointURL: "localhost:4566"},
+		"service-endpoint": Flags{ServiceEndpoints: map[string]string{"ecs": "not a url"}},
+		"ca-bundle":        Flags{CABundle: "/does/not/exist.pem"},
+	}
+
+	for name, flags := range testCases {
+		e := NewECSClient()
+		e.Flags = flags
+
+		if err := e.ConfigureSession(); err == nil {
+			t.Errorf("TestCase '%s': expected an error\n", name)
+		}
+	}
+}
@@ -1,8 +1,8 @@
 package ecsclient
 
 import (
+	"context"
 	"fmt"
-	"regexp"
 	"sort"
 	"strings"
 	"time"
@@ -10,26 +10,126 @@ import (
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/aws/aws-sdk-go/service/ecr/ecriface"
 	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
 	"github.com/golang-collections/collections/stack"
 	"github.com/jpillora/backoff"
+	"github.com/quintilesims/go-ecs-cleaner/archive"
+	"github.com/quintilesims/go-ecs-cleaner/audit"
+	"github.com/quintilesims/go-ecs-cleaner/inventory"
+	"github.com/quintilesims/go-ecs-cleaner/lock"
+	"github.com/quintilesims/go-ecs-cleaner/metrics"
+	"github.com/quintilesims/go-ecs-cleaner/notify"
+	"github.com/quintilesims/go-ecs-cleaner/planner"
+	"github.com/quintilesims/go-ecs-cleaner/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Flags hold user-defined operational parameters for the ECSClient.
 // They are specified at the command line when `go-ecs-client ecs-task` is run.
 type Flags struct {
-	Apply   bool
-	Cutoff  int
-	Debug   bool
-	Quiet   bool
-	Verbose bool
+	Apply                bool
+	CollectRegistrations bool
+	CollectTags          bool
+	Cutoff               int
+	Debug                bool
+	EmptyDays            int
+	ExcludeServices      []string
+	Force                bool
+	GracePeriod          time.Duration
+	IdleDays             int
+	ImageAgeDays         int
+	ImageRepositories    []string
+	IncludeServices      []string
+	KeepBeforeActive     int
+	KeepImages           int
+	LogGroupPrefix       string
+	LogRetentionDays     int
+	MaxDeregistrations   int
+	MaxFamilyFraction    float64
+	MaxFraction          float64
+	OrphanKeep           int
+	OrphanKeepDays       int
+	ProtectTags          map[string]string
+	Quiet                bool
+	RetireFamilies       bool
+	RevalidateEvery      int
+	Verbose              bool
+
+	// Session parameters; see ConfigureSession.
+	CABundle         string
+	EndpointURL      string
+	NoVerifySSL      bool
+	ServiceEndpoints map[string]string
 }
 
 // ECSClient is the object through which the `ecs-task` command interacts with AWS.
 type ECSClient struct {
 	Backoff *backoff.Backoff
 	Flags   Flags
+	Session *session.Session
 	Svc     ECSSvc
+
+	// ECR is what CleanupImages works against; ConfigureSession sets it from the same session
+	// as Svc.
+	ECR ecriface.ECRAPI
+
+	// Logs is what CleanupLogGroups works against; ConfigureSession sets it from the same
+	// session as Svc.
+	Logs cloudwatchlogsiface.CloudWatchLogsAPI
+
+	// Events is where Discover finds the task definitions that scheduled rules run;
+	// ConfigureSession sets it from the same session as Svc. Without it, schedules aren't
+	// looked for.
+	Events eventbridgeiface.EventBridgeAPI
+
+	// Archive, when set, receives a copy of each task definition before it's deregistered.
+	Archive *archive.Archiver
+
+	// Audit, when set, records every planned, completed, failed and skipped deregistration.
+	Audit *audit.Logger
+
+	// Metrics, when set, are updated as the ECSClient works; ConfigureSession instruments the
+	// session's API calls with them.
+	Metrics *metrics.Metrics
+
+	// Notifier, when set, is sent a summary of each CleanupTaskDefinitions run.
+	Notifier *notify.Notifier
+
+	// Lock, when set, is held for the whole of each CleanupTaskDefinitions and
+	// DeregisterTargets run; if it's lost partway, the run stops before changing anything else.
+	Lock *lock.Lock
+
+	// Tracer, when set, records a span for each phase of a run and each wait after
+	// throttling; ConfigureSession gives the session's API calls spans of their own.
+	Tracer trace.Tracer
+
+	// Review, when set, is shown the task definitions an `--apply` run would deregister, and
+	// returns the ones to go ahead with.
+	Review func(taskDefinitionARNs []string) ([]string, error)
+
+	// Context, when set, stops a run before its next AWS call or deregistration once it's
+	// done, cutting short any backoff, e.g. when the process is asked to shut down.
+	Context context.Context
+
+	// Now stamps the Inventory that Discover returns; the orphan policy measures ages from
+	// it. Replaying a snapshot sets it to the snapshot's capture time.
+	Now func() time.Time
+
+	// requestID is the ID of the last request Svc completed; see recordRequestID.
+	requestID string
+
+	// summary is the last CleanupTaskDefinitions run's; see Summary.
+	summary *notify.Summary
+
+	// ctx holds the current span; see startSpan.
+	ctx context.Context
 }
 
 // NewECSClient creates an ECSClient and returns a pointer to it.
@@ -42,44 +142,104 @@ func NewECSClient() *ECSClient {
 
 	return &ECSClient{
 		Backoff: &b,
+		Now:     func() time.Time { return time.Now().UTC() },
 	}
 }
 
-// CleanupTaskDefinitions defines the overarching logic workflow for cleaning up task definitions.
-func (e *ECSClient) CleanupTaskDefinitions() error {
-	allTaskDefinitionARNs, err := e.CollectTaskDefinitions()
-	if err != nil {
-		return err
+// Policy returns the retention policy described by the ECSClient's Flags.
+func (e *ECSClient) Policy() planner.Policy {
+	return planner.Policy{
+		Cutoff:           e.Flags.Cutoff,
+		GracePeriod:      e.Flags.GracePeriod,
+		KeepBeforeActive: e.Flags.KeepBeforeActive,
+		OrphanKeep:       e.Flags.OrphanKeep,
+		OrphanKeepDays:   e.Flags.OrphanKeepDays,
+		ProtectTags:      e.Flags.ProtectTags,
 	}
+}
 
-	clusterARNs, err := e.CollectClusters()
-	if err != nil {
-		return err
+// Limits returns the safety limits described by the ECSClient's Flags.
+func (e *ECSClient) Limits() planner.Limits {
+	return planner.Limits{
+		MaxDeregistrations: e.Flags.MaxDeregistrations,
+		MaxFamilyFraction:  e.Flags.MaxFamilyFraction,
+		MaxFraction:        e.Flags.MaxFraction,
+		RetireFamilies:     e.Flags.RetireFamilies,
 	}
+}
 
-	serviceARNsByClusterARN, err := e.CollectServices(clusterARNs)
-	if err != nil {
+// CleanupTaskDefinitions defines the overarching logic workflow for cleaning up task definitions.
+func (e *ECSClient) CleanupTaskDefinitions() (err error) {
+	start := time.Now()
+	e.startSummary(start.UTC())
+	e.Metrics.ResetTaskDefinitions()
+	endSpan := e.startSpan("CleanupTaskDefinitions", attribute.Bool("apply", e.Flags.Apply))
+	defer func() {
+		e.recordSpanError(err)
+		endSpan()
+		e.Metrics.ObserveRun(start, err)
+		e.sendSummary(err)
+	}()
+
+	if err := e.acquireLock(); err != nil {
 		return err
 	}
+	defer e.releaseLock()
 
-	ecsServices, err := e.DescribeServices(serviceARNsByClusterARN)
+	inv, err := e.Discover()
 	if err != nil {
 		return err
 	}
 
-	filteredTaskDefinitionARNs, err := e.FilterTaskDefinitions(allTaskDefinitionARNs, ecsServices)
-	if err != nil {
+	endPlan := e.startSpan("PlanTaskDefinitions")
+	plan := e.PlanTaskDefinitions(inv)
+	endPlan()
+	filteredTaskDefinitionARNs := plan.ARNs(planner.Deregister)
+
+	e.Metrics.SetTaskDefinitions(metrics.Discovered, len(inv.TaskDefinitionARNs))
+	e.Metrics.SetTaskDefinitions(metrics.Kept, len(plan.ARNs(planner.Keep)))
+	e.Metrics.SetTaskDefinitions(metrics.Pending, len(plan.ARNs(planner.Pending)))
+	e.Metrics.SetTaskDefinitions(metrics.Planned, len(filteredTaskDefinitionARNs))
+	e.summarizePlan(len(inv.TaskDefinitionARNs), plan, filteredTaskDefinitionARNs)
+
+	if e.Audit != nil {
+		if err := e.Audit.Plan(plan.Decisions); err != nil {
+			return err
+		}
+	}
+
+	// a plan that trips a safety limit fails dry runs too, so they catch it before `--apply`
+	if err := plan.CheckLimits(e.Limits()); err != nil {
 		return err
 	}
 
 	if len(filteredTaskDefinitionARNs) > 0 {
 		if e.Flags.Apply {
-			if !e.Flags.Quiet {
-				fmt.Printf("`--apply` flag present, deregistering %d task definitions...\n", len(filteredTaskDefinitionARNs))
+			if e.Review != nil {
+				endReview := e.startSpan("ReviewTaskDefinitions")
+				filteredTaskDefinitionARNs, err = e.reviewTaskDefinitions(filteredTaskDefinitionARNs)
+				endReview()
+
+				if err != nil {
+					return err
+				}
+
+				e.summarizePlan(len(inv.TaskDefinitionARNs), plan, filteredTaskDefinitionARNs)
 			}
 
-			if err = e.DeregisterTaskDefinitions(filteredTaskDefinitionARNs); err != nil {
-				return err
+			if len(filteredTaskDefinitionARNs) == 0 {
+				if !e.Flags.Quiet {
+					fmt.Println("No task definitions were selected for deregistration.")
+				}
+
+			} else {
+				if !e.Flags.Quiet {
+					fmt.Printf("`--apply` flag present, deregistering %d task definitions...\n", len(filteredTaskDefinitionARNs))
+				}
+
+				if err = e.DeregisterTaskDefinitions(filteredTaskDefinitionARNs); err != nil {
+					return err
+				}
 			}
 
 		} else {
@@ -94,6 +254,103 @@ func (e *ECSClient) CleanupTaskDefinitions() error {
 		}
 	}
 
+	if e.Flags.GracePeriod > 0 {
+		if err := e.UpdatePendingTags(plan, inv.CapturedAt); err != nil {
+			return err
+		}
+	}
+
+	if !e.Flags.Quiet {
+		fmt.Println("Process finished.")
+	}
+
+	return nil
+}
+
+// DeregisterTargets deregisters the task definitions that the targets name: ARNs,
+// "family:revision" or whole families. It refuses to deregister anything if a service, task
+// or reference uses one of them, unless `Force` is set.
+func (e *ECSClient) DeregisterTargets(targets []string) (err error) {
+	endSpan := e.startSpan("DeregisterTargets", attribute.Bool("apply", e.Flags.Apply))
+	defer func() {
+		e.recordSpanError(err)
+		endSpan()
+	}()
+
+	if err := e.acquireLock(); err != nil {
+		return err
+	}
+	defer e.releaseLock()
+
+	inv, err := e.Discover()
+	if err != nil {
+		return err
+	}
+
+	plan, unmatched, err := planner.Target(inv, targets, e.Flags.Force)
+	if err != nil {
+		return err
+	}
+
+	if !e.Flags.Quiet {
+		for _, target := range unmatched {
+			fmt.Printf("%s matches no ACTIVE task definitions.\n", target)
+		}
+	}
+
+	if e.Audit != nil {
+		if err := e.Audit.Plan(plan.Decisions); err != nil {
+			return err
+		}
+	}
+
+	if err := plan.CheckInUse(); err != nil {
+		return err
+	}
+
+	targetedARNs := plan.ARNs(planner.Deregister)
+
+	if !e.Flags.Quiet {
+		for _, decision := range plan.Decisions {
+			fmt.Println(decision.ARN)
+
+			for _, reason := range decision.Reasons {
+				if reason.Code != planner.Targeted {
+					fmt.Printf("  - in use, deregistering anyway: %s: %s\n", reason.Code, reason.Detail)
+				}
+			}
+		}
+	}
+
+	switch {
+	case len(targetedARNs) == 0:
+		if !e.Flags.Quiet {
+			fmt.Println("No task definitions remain to be deregistered.")
+		}
+
+	case e.Flags.Apply:
+		if !e.Flags.Quiet {
+			fmt.Printf("`--apply` flag present, deregistering %d task definitions...\n", len(targetedARNs))
+		}
+
+		// forced targets are in use already, and revalidating would skip them
+		if e.Flags.Force {
+			revalidateEvery := e.Flags.RevalidateEvery
+			e.Flags.RevalidateEvery = 0
+			defer func() { e.Flags.RevalidateEvery = revalidateEvery }()
+		}
+
+		if err := e.DeregisterTaskDefinitions(targetedARNs); err != nil {
+			return err
+		}
+
+	default:
+		if !e.Flags.Quiet {
+			fmt.Println("This is a dry run.")
+			fmt.Println("Use the `--apply` flag to deregister these task definitions.")
+		}
+	}
+
 	if !e.Flags.Quiet {
 		fmt.Println("Process finished.")
 	}
@@ -102,7 +359,10 @@ func (e *ECSClient) CleanupTaskDefinitions() error {
 }
 
 // CollectClusters gathers the ARNs of all the clusters for the configured account and region.
+// A page that can't be listed, even after retries, stops the collection with an error.
 func (e *ECSClient) CollectClusters() ([]string, error) {
+	defer e.startSpan("CollectClusters")()
+
 	if !e.Flags.Quiet {
 		fmt.Println("Collecting clusters...")
 	}
@@ -111,33 +371,42 @@ func (e *ECSClient) CollectClusters() ([]string, error) {
 	var nextToken *string
 	var needToResetPrinter bool
 
-	runPaginatedLoop := func() {
+	runPaginatedLoop := func() error {
 		var listedARNs []string
-		var err error
 
-		listedARNs, nextToken, err = e.listClusters(nextToken)
-		if err != nil && !e.Flags.Quiet {
-			if needToResetPrinter {
-				fmt.Println()
-				needToResetPrinter = false
+		err := e.retry(func() error {
+			var err error
+			var next *string
+
+			listedARNs, next, err = e.listClusters(nextToken)
+			if err == nil {
+				nextToken = next
 			}
 
-			fmt.Println("Error listing clusters:", err)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("unable to list clusters: %v", err)
 		}
 
-		for _, arn := range listedARNs {
-			clusterARNs = append(clusterARNs, arn)
-		}
+		clusterARNs = append(clusterARNs, listedARNs...)
 
 		if !e.Flags.Quiet {
 			fmt.Printf("\r(found %d)", len(clusterARNs))
 			needToResetPrinter = true
 		}
+
+		return nil
 	}
 
-	runPaginatedLoop()
-	for nextToken != nil {
-		runPaginatedLoop()
+	for first := true; first || nextToken != nil; first = false {
+		if err := runPaginatedLoop(); err != nil {
+			if needToResetPrinter {
+				fmt.Println()
+			}
+
+			return nil, err
+		}
 	}
 
 	if needToResetPrinter {
@@ -148,8 +417,11 @@ func (e *ECSClient) CollectClusters() ([]string, error) {
 }
 
 // CollectServices gathers the ARNs of all the services associated with the clusters
-// that are passed in for the configured account and region.
+// that are passed in for the configured account and region. A page that can't be listed,
+// even after retries, stops the collection with an error.
 func (e *ECSClient) CollectServices(clusterARNs []string) (map[string][]string, error) {
+	defer e.startSpan("CollectServices")()
+
 	if !e.Flags.Quiet {
 		fmt.Println("Collecting services...")
 	}
@@ -159,18 +431,22 @@ func (e *ECSClient) CollectServices(clusterARNs []string) (map[string][]string,
 	var nextToken *string
 	var needToResetPrinter bool
 
-	runPaginatedLoop := func(clusterARN string) {
+	runPaginatedLoop := func(clusterARN string) error {
 		var listedServiceARNs []string
-		var err error
 
-		listedServiceARNs, nextToken, err = e.listServices(clusterARN, nextToken)
-		if err != nil && !e.Flags.Quiet {
-			if needToResetPrinter {
-				fmt.Println()
-				needToResetPrinter = false
+		err := e.retry(func() error {
+			var err error
+			var next *string
+
+			listedServiceARNs, next, err = e.listServices(clusterARN, nextToken)
+			if err == nil {
+				nextToken = next
 			}
 
-			fmt.Println("Error listing services:", err)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("unable to list services: %v", err)
 		}
 
 		for _, serviceARN := range listedServiceARNs {
@@ -182,12 +458,19 @@ func (e *ECSClient) CollectServices(clusterARNs []string) (map[string][]string,
 			fmt.Printf("\r(found %d)", numServices)
 			needToResetPrinter = true
 		}
+
+		return nil
 	}
 
 	for _, clusterARN := range clusterARNs {
-		runPaginatedLoop(clusterARN)
-		for nextToken != nil {
-			runPaginatedLoop(clusterARN)
+		for first := true; first || nextToken != nil; first = false {
+			if err := runPaginatedLoop(clusterARN); err != nil {
+				if needToResetPrinter {
+					fmt.Println()
+				}
+
+				return nil, err
+			}
 		}
 	}
 
@@ -199,8 +482,11 @@ func (e *ECSClient) CollectServices(clusterARNs []string) (map[string][]string,
 }
 
 // CollectTaskDefinitions gathers the ARNs of all the task definitions for the configured
-// account and region.
+// account and region. A page that can't be listed, even after retries, stops the collection
+// with an error.
 func (e *ECSClient) CollectTaskDefinitions() ([]string, error) {
+	defer e.startSpan("CollectTaskDefinitions")()
+
 	if !e.Flags.Quiet {
 		fmt.Println("Collecting task definitions...")
 	}
@@ -209,74 +495,390 @@ func (e *ECSClient) CollectTaskDefinitions() ([]string, error) {
 	var nextToken *string
 	var needToResetPrinter bool
 
-	runPaginatedLoop := func() {
+	runPaginatedLoop := func() error {
 		var listedTaskDefinitionARNs []string
-		var err error
 
-		listedTaskDefinitionARNs, nextToken, err = e.listTaskDefinitions("", "", nextToken)
-		if err != nil && !e.Flags.Quiet {
+		err := e.retry(func() error {
+			var err error
+			var next *string
+
+			listedTaskDefinitionARNs, next, err = e.listTaskDefinitions("", "", nextToken)
+			if err == nil {
+				nextToken = next
+			}
+
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("unable to list task definitions: %v", err)
+		}
+
+		taskDefinitionARNs = append(taskDefinitionARNs, listedTaskDefinitionARNs...)
+
+		if !e.Flags.Quiet {
+			fmt.Printf("\r(found %d)", len(taskDefinitionARNs))
+			needToResetPrinter = true
+		}
+
+		return nil
+	}
+
+	for first := true; first || nextToken != nil; first = false {
+		if err := runPaginatedLoop(); err != nil {
 			if needToResetPrinter {
 				fmt.Println()
-				needToResetPrinter = false
 			}
 
-			fmt.Println("Error listing task definitions:", err)
+			return nil, err
+		}
+	}
+
+	if needToResetPrinter {
+		fmt.Println()
+	}
+
+	return taskDefinitionARNs, nil
+}
+
+// CollectRegistrationDates gathers when the given task definitions were registered, one call
+// per task definition. Task definitions without a registration date are left out of the
+// returned map.
+func (e *ECSClient) CollectRegistrationDates(taskDefinitionARNs []string) (map[string]time.Time, error) {
+	defer e.startSpan("CollectRegistrationDates")()
+
+	if !e.Flags.Quiet {
+		fmt.Println("Collecting task definition registration dates...")
+	}
+
+	registeredAtByARN := make(map[string]time.Time)
+	var needToResetPrinter bool
+
+	for i, arn := range taskDefinitionARNs {
+		var taskDefinition *ecs.TaskDefinition
+
+		err := e.retry(func() error {
+			output, err := e.describeTaskDefinition(arn, false)
+			if err == nil {
+				taskDefinition = output.TaskDefinition
+			}
+
+			return err
+		})
+
+		if err != nil {
+			if e.isStopworthyError(err) {
+				return nil, err
+			}
+
+			if !e.Flags.Quiet {
+				if needToResetPrinter {
+					fmt.Println()
+					needToResetPrinter = false
+				}
+
+				fmt.Println("Error describing task definition:", err)
+			}
 		}
 
-		for _, taskDefinitionARN := range listedTaskDefinitionARNs {
-			taskDefinitionARNs = append(taskDefinitionARNs, taskDefinitionARN)
+		if taskDefinition != nil && taskDefinition.RegisteredAt != nil {
+			registeredAtByARN[arn] = taskDefinition.RegisteredAt.UTC()
 		}
 
 		if !e.Flags.Quiet {
-			fmt.Printf("\r(found %d)", len(taskDefinitionARNs))
+			fmt.Printf("\r(checked %d of %d)", i+1, len(taskDefinitionARNs))
+			needToResetPrinter = true
+		}
+	}
+
+	if needToResetPrinter {
+		fmt.Println()
+	}
+
+	return registeredAtByARN, nil
+}
+
+// CollectTaskDefinitionTags gathers the tags of the given task definitions, one call per task
+// definition. Task definitions without tags are left out of the returned map.
+func (e *ECSClient) CollectTaskDefinitionTags(taskDefinitionARNs []string) (map[string]map[string]string, error) {
+	defer e.startSpan("CollectTaskDefinitionTags")()
+
+	if !e.Flags.Quiet {
+		fmt.Println("Collecting task definition tags...")
+	}
+
+	tagsByARN := make(map[string]map[string]string)
+	var needToResetPrinter bool
+
+	for i, arn := range taskDefinitionARNs {
+		var tags map[string]string
+
+		err := e.retry(func() error {
+			var err error
+			tags, err = e.listTagsForResource(arn)
+			return err
+		})
+
+		if err != nil {
+			if e.isStopworthyError(err) {
+				return nil, err
+			}
+
+			if !e.Flags.Quiet {
+				if needToResetPrinter {
+					fmt.Println()
+					needToResetPrinter = false
+				}
+
+				fmt.Println("Error listing tags:", err)
+			}
+		}
+
+		if len(tags) > 0 {
+			tagsByARN[arn] = tags
+		}
+
+		if !e.Flags.Quiet {
+			fmt.Printf("\r(checked %d of %d)", i+1, len(taskDefinitionARNs))
 			needToResetPrinter = true
 		}
 	}
 
-	runPaginatedLoop()
-	for nextToken != nil {
-		runPaginatedLoop()
+	if needToResetPrinter {
+		fmt.Println()
+	}
+
+	return tagsByARN, nil
+}
+
+// CollectContainerDefinitions gathers the container definitions of the given task
+// definitions, one call per task definition. A task definition that can't be described would
+// leave what its containers use unaccounted for, so it stops the process.
+func (e *ECSClient) CollectContainerDefinitions(taskDefinitionARNs []string) ([]*ecs.ContainerDefinition, error) {
+	defer e.startSpan("CollectContainerDefinitions")()
+
+	if !e.Flags.Quiet {
+		fmt.Println("Collecting container definitions...")
+	}
+
+	var containers []*ecs.ContainerDefinition
+	var needToResetPrinter bool
+
+	for i, arn := range taskDefinitionARNs {
+		var output *ecs.DescribeTaskDefinitionOutput
+		err := e.retry(func() error {
+			var err error
+			output, err = e.describeTaskDefinition(arn, false)
+			return err
+		})
+		if err != nil {
+			if needToResetPrinter {
+				fmt.Println()
+			}
+
+			return nil, fmt.Errorf("unable to describe %s: %v", arn, err)
+		}
+
+		containers = append(containers, output.TaskDefinition.ContainerDefinitions...)
+
+		if !e.Flags.Quiet {
+			fmt.Printf("\r(checked %d of %d)", i+1, len(taskDefinitionARNs))
+			needToResetPrinter = true
+		}
 	}
 
 	if needToResetPrinter {
 		fmt.Println()
 	}
 
-	return taskDefinitionARNs, nil
+	return containers, nil
+}
+
+// CollectTasks gathers the running tasks in the clusters that are passed in for the configured
+// account and region. Tasks are collected whether or not they belong to a service. Any page
+// that can't be listed or described, even after retries, stops the collection with an error,
+// since a missing task would leave its task definition out of the in-use set.
+func (e *ECSClient) CollectTasks(clusterARNs []string) ([]ecs.Task, error) {
+	defer e.startSpan("CollectTasks")()
+
+	if !e.Flags.Quiet {
+		fmt.Println("Collecting tasks...")
+	}
+
+	var tasks []ecs.Task
+	var nextToken *string
+	var needToResetPrinter bool
+
+	runPaginatedLoop := func(clusterARN string) error {
+		var listedTaskARNs []string
+
+		err := e.retry(func() error {
+			var err error
+			var next *string
+
+			listedTaskARNs, next, err = e.listTasks(clusterARN, nextToken)
+			if err == nil {
+				nextToken = next
+			}
+
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("unable to list tasks: %v", err)
+		}
+
+		if len(listedTaskARNs) > 0 {
+			var describedTasks []ecs.Task
+
+			err := e.retry(func() error {
+				var err error
+				describedTasks, err = e.describeTasks(clusterARN, listedTaskARNs)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("unable to describe tasks: %v", err)
+			}
+
+			tasks = append(tasks, describedTasks...)
+		}
+
+		if !e.Flags.Quiet {
+			fmt.Printf("\r(found %d)", len(tasks))
+			needToResetPrinter = true
+		}
+
+		return nil
+	}
+
+	for _, clusterARN := range clusterARNs {
+		for first := true; first || nextToken != nil; first = false {
+			if err := runPaginatedLoop(clusterARN); err != nil {
+				if needToResetPrinter {
+					fmt.Println()
+				}
+
+				return nil, err
+			}
+		}
+	}
+
+	if needToResetPrinter {
+		fmt.Println()
+	}
+
+	return tasks, nil
 }
 
 // ConfigureSession configures and instantiates an `ecs.ECS` object into the ECSClient's
 // `Svc` field. This `ecs.ECS` object satisfies the `ECSSvc` interface defined in this package.
+// `ecr.ECR` and `cloudwatchlogs.CloudWatchLogs` objects for the same session go into the `ECR`
+// and `Logs` fields.
+// Endpoint overrides and TLS settings are taken from the ECSClient's Flags.
 func (e *ECSClient) ConfigureSession() error {
-	sess, err := session.NewSession(&aws.Config{})
+	sess, err := e.newSession()
 	if err != nil {
 		return err
 	}
 
-	e.Svc = ecs.New(sess)
+	e.Metrics.Instrument(&sess.Handlers)
+	if e.Tracer != nil {
+		tracing.Instrument(&sess.Handlers, e.Tracer, e.spanContext)
+	}
+
+	svc := ecs.New(sess)
+	svc.Handlers.Complete.PushBack(e.recordRequestID)
+
+	e.Session = sess
+	e.Svc = svc
+	e.ECR = ecr.New(sess)
+	e.Logs = cloudwatchlogs.New(sess)
+	e.Events = eventbridge.New(sess)
 	return nil
 }
 
 // DeregisterTaskDefinitions creates a stack of ARNs and handles calling ecs.DeregisterTaskDefinition()
-// for all these ARNs.
+// for all these ARNs. Every `RevalidateEvery` ARNs it refreshes which task definitions services
+// and running tasks use, and skips any ARN that has come into use since it was planned. With
+// an Archive, each task definition is archived first, and one that can't be is not
+// deregistered. With an Audit log, every outcome is logged, and a failure to log stops the
+// process.
 func (e *ECSClient) DeregisterTaskDefinitions(taskDefinitionARNs []string) error {
+	defer e.startSpan("DeregisterTaskDefinitions", attribute.Int("count", len(taskDefinitionARNs)))()
+
 	arns := stack.New()
 	for _, taskDefinitionARN := range taskDefinitionARNs {
 		arns.Push(taskDefinitionARN)
 	}
 
 	var failedDeregistrations []FailedDeregistration
+	var skippedDeregistrations []string
 	var numCompletedDeregistrations int
 	numTasksToDeregister := len(taskDefinitionARNs)
 	var needToResetPrinter bool
 
+	// revalidate before the first deregistration too; the plan may already be stale
+	inUse := make(map[string]bool)
+	numSinceRevalidation := e.Flags.RevalidateEvery
+
+	archived := make(map[string]bool)
+	if e.Archive != nil {
+		defer e.writeArchiveManifest()
+	}
+
+	defer func() {
+		e.Metrics.SetTaskDefinitions(metrics.Deregistered, numCompletedDeregistrations)
+		e.Metrics.SetTaskDefinitions(metrics.Failed, len(failedDeregistrations))
+		e.Metrics.SetTaskDefinitions(metrics.Skipped, len(skippedDeregistrations))
+		e.summarizeDeregistrations(numCompletedDeregistrations, failedDeregistrations, skippedDeregistrations)
+	}()
+
 	for numCompletedDeregistrations < numTasksToDeregister {
+		if err := e.Lock.Err(); err != nil {
+			return err
+		}
+
+		if e.Context != nil && e.Context.Err() != nil {
+			return fmt.Errorf("stopped with %d of %d task definitions left to deregister: %v", numTasksToDeregister-numCompletedDeregistrations, numTasksToDeregister, e.Context.Err())
+		}
+
+		if e.Flags.RevalidateEvery > 0 && numSinceRevalidation >= e.Flags.RevalidateEvery {
+			var err error
+			if inUse, err = e.CollectInUse(); err != nil {
+				return err
+			}
+
+			numSinceRevalidation = 0
+		}
+
 		arn := arns.Pop().(string)
+		numSinceRevalidation++
+		e.requestID = ""
+
 		input := &ecs.DeregisterTaskDefinitionInput{
 			TaskDefinition: aws.String(arn),
 		}
 
-		if _, err := e.Svc.DeregisterTaskDefinition(input); err != nil {
+		if inUse[arn] {
+			skippedDeregistrations = append(skippedDeregistrations, arn)
+			numTasksToDeregister--
+
+			if err := e.auditLog(audit.Skip, arn, "became active"); err != nil {
+				return err
+			}
+
+		} else if err := e.archiveTaskDefinition(arn, archived); err != nil {
+			if e.isStopworthyError(err) {
+				return err
+			}
+
+			failedDeregistration := FailedDeregistration{Arn: arn, Err: err}
+			failedDeregistrations = append(failedDeregistrations, failedDeregistration)
+			numTasksToDeregister--
+
+			if err := e.auditLog(audit.Failure, arn, err.Error()); err != nil {
+				return err
+			}
+
+		} else if _, err := e.Svc.DeregisterTaskDefinition(input); err != nil {
 			switch {
 
 			case e.isThrottlingError(err):
@@ -297,7 +899,7 @@ func (e *ECSClient) DeregisterTaskDefinitions(taskDefinitionARNs []string) error
 					fmt.Printf("Waiting for %v\n", t)
 				}
 
-				time.Sleep(t)
+				e.sleepBackoff(t, tracing.TaskDefinitionAttributes(arn)...)
 				arns.Push(arn)
 
 			case e.isExpiredTokenError(err):
@@ -323,21 +925,36 @@ func (e *ECSClient) DeregisterTaskDefinitions(taskDefinitionARNs []string) error
 					fmt.Println("Encountered stopworthy error, halting process.")
 				}
 
+				if auditErr := e.auditLog(audit.Failure, arn, err.Error()); auditErr != nil {
+					return auditErr
+				}
+
 				return err
 
 			default:
 				failedDeregistration := FailedDeregistration{Arn: arn, Err: err}
 				failedDeregistrations = append(failedDeregistrations, failedDeregistration)
 				numTasksToDeregister--
+				e.recordArchive(arn, archive.Failed, err)
+
+				if err := e.auditLog(audit.Failure, arn, err.Error()); err != nil {
+					return err
+				}
 			}
 
 		} else {
 			e.Backoff.Reset()
 			numCompletedDeregistrations++
+			e.Metrics.AddDeregistrations(1)
+			e.recordArchive(arn, archive.Deregistered, nil)
+
+			if err := e.auditLog(audit.Deregister, arn, ""); err != nil {
+				return err
+			}
 		}
 
 		if !e.Flags.Quiet {
-			fmt.Printf("\r%d deregistered task definitions, %d errored", numCompletedDeregistrations, len(failedDeregistrations))
+			fmt.Printf("\r%d deregistered task definitions, %d errored, %d skipped", numCompletedDeregistrations, len(failedDeregistrations), len(skippedDeregistrations))
 			needToResetPrinter = true
 		}
 	}
@@ -347,6 +964,12 @@ func (e *ECSClient) DeregisterTaskDefinitions(taskDefinitionARNs []string) error
 		needToResetPrinter = false
 	}
 
+	if !e.Flags.Quiet {
+		for _, arn := range skippedDeregistrations {
+			fmt.Printf("%s skipped: became active\n", arn)
+		}
+	}
+
 	if e.Flags.Verbose && len(failedDeregistrations) > 0 {
 		fmt.Println("Errored task definition deregistrations:")
 		for _, result := range failedDeregistrations {
@@ -361,10 +984,54 @@ func (e *ECSClient) DeregisterTaskDefinitions(taskDefinitionARNs []string) error
 	return nil
 }
 
+// CollectInUse gathers, without printing progress, the ARNs of the task definitions that
+// services and running tasks use right now.
+func (e *ECSClient) CollectInUse() (map[string]bool, error) {
+	defer e.startSpan("CollectInUse")()
+
+	quiet := e.Flags.Quiet
+	e.Flags.Quiet = true
+	defer func() { e.Flags.Quiet = quiet }()
+
+	clusterARNs, err := e.CollectClusters()
+	if err != nil {
+		return nil, err
+	}
+
+	serviceARNsByClusterARN, err := e.CollectServices(clusterARNs)
+	if err != nil {
+		return nil, err
+	}
+
+	services, err := e.DescribeServices(serviceARNsByClusterARN)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := e.CollectTasks(clusterARNs)
+	if err != nil {
+		return nil, err
+	}
+
+	inUse := make(map[string]bool)
+	for _, service := range services {
+		inUse[aws.StringValue(service.TaskDefinition)] = true
+	}
+
+	for _, task := range tasks {
+		inUse[aws.StringValue(task.TaskDefinitionArn)] = true
+	}
+
+	return inUse, nil
+}
+
 // DescribeServices compiles a list of `ecs.Service` objects given a map of cluster ARNs to
 // lists of service ARNs associated with each cluster. Most importantly, these `ecs.Service`
-// objects contain the ARNs of the task definitions currently in use by the services.
+// objects contain the ARNs of the task definitions currently in use by the services. A chunk
+// that can't be described, even after retries, stops with an error.
 func (e *ECSClient) DescribeServices(serviceARNsByClusterARN map[string][]string) ([]ecs.Service, error) {
+	defer e.startSpan("DescribeServices")()
+
 	if !e.Flags.Quiet {
 		fmt.Println("Describing services...")
 	}
@@ -392,125 +1059,156 @@ func (e *ECSClient) DescribeServices(serviceARNsByClusterARN map[string][]string
 			serviceARNsChunk = serviceARNs[iStart:iEnd]
 			serviceARNs = serviceARNs[0:iStart]
 
-			describedServices, err := e.describeServices(clusterARN, serviceARNsChunk)
-			if err != nil && !e.Flags.Quiet {
-				fmt.Println("Error describing services:", err)
+			var describedServices []ecs.Service
+			err := e.retry(func() error {
+				var err error
+				describedServices, err = e.describeServices(clusterARN, serviceARNsChunk)
+				return err
+			})
+			if err != nil {
+				return nil, fmt.Errorf("unable to describe services: %v", err)
 			}
 
-			for _, describedService := range describedServices {
-				ecsServices = append(ecsServices, describedService)
-			}
+			ecsServices = append(ecsServices, describedServices...)
 		}
 	}
 
 	return ecsServices, nil
 }
 
-// FilterTaskDefinitions takes a master list of task definition ARNs and returns a version of
-// that list from which has been removed:
-//   - All task definitions curently in use by a service.
-//   - All task definitions which are among the `n`-most-recently-used task definitions for each
-//     family. `n` is configured via the `--cutoff` flag.
-func (e *ECSClient) FilterTaskDefinitions(allTaskDefinitionARNs []string, ecsServices []ecs.Service) ([]string, error) {
-	taskDefinitionFilterMap := make(map[string]bool)
-	if !e.Flags.Quiet {
-		fmt.Printf("Filtering out in-use and %d most recent task definitions...\n", e.Flags.Cutoff)
+// Discover reads everything the cleaner needs to know about the configured account and
+// region: task definitions, clusters, services and running tasks, the task definitions that
+// scheduled rules run when `Events` is set, plus task definition tags when `CollectTags`,
+// `ProtectTags` or `GracePeriod` is set. Registration dates are collected for the latest
+// revision of each family that no service uses, or for all of their revisions when
+// `CollectRegistrations` or `OrphanKeepDays` is set. When the ECSClient is backed by an
+// `ecsfake.FromInventory` replay, Discover returns the replayed Inventory.
+func (e *ECSClient) Discover() (*inventory.Inventory, error) {
+	defer e.startSpan("Discover")()
+
+	var region string
+	if e.Session != nil {
+		region = aws.StringValue(e.Session.Config.Region)
 	}
 
-	if e.Flags.Verbose {
-		fmt.Println("Collecting task definitions actively attached to a service...")
+	inv := inventory.New(region)
+	inv.CapturedAt = e.Now()
+
+	var err error
+
+	if inv.TaskDefinitionARNs, err = e.CollectTaskDefinitions(); err != nil {
+		return nil, err
 	}
 
-	for _, service := range ecsServices {
-		if service.TaskDefinition != nil {
-			taskDefinitionFilterMap[*service.TaskDefinition] = true
-		}
+	if inv.ClusterARNs, err = e.CollectClusters(); err != nil {
+		return nil, err
 	}
 
-	if e.Flags.Verbose {
-		fmt.Printf("Found %d.\n", len(taskDefinitionFilterMap))
+	if inv.ServiceARNsByClusterARN, err = e.CollectServices(inv.ClusterARNs); err != nil {
+		return nil, err
+	}
+
+	if inv.Services, err = e.DescribeServices(inv.ServiceARNsByClusterARN); err != nil {
+		return nil, err
+	}
+
+	// DescribeServices walks a map; sort so that snapshots of the same account are identical.
+	sort.Slice(inv.Services, func(i, j int) bool {
+		return aws.StringValue(inv.Services[i].ServiceArn) < aws.StringValue(inv.Services[j].ServiceArn)
+	})
+
+	if inv.Tasks, err = e.CollectTasks(inv.ClusterARNs); err != nil {
+		return nil, err
 	}
 
-	if e.Flags.Cutoff > 0 {
-		if e.Flags.Verbose {
-			fmt.Printf("Collecting the %d most recent task definitions for each active family...\n", e.Flags.Cutoff)
+	if e.Events != nil {
+		if inv.References, err = e.CollectScheduleReferences(inv.TaskDefinitionARNs); err != nil {
+			return nil, err
 		}
+	}
 
-		inUseTaskDefinitionFamilies := make(map[string]bool)
-		var nextToken *string
+	allRevisions := e.Flags.CollectRegistrations || e.Flags.OrphanKeepDays > 0
+	if arns := orphanTaskDefinitionARNs(inv, allRevisions); len(arns) > 0 {
+		if inv.TaskDefinitionRegisteredAt, err = e.CollectRegistrationDates(arns); err != nil {
+			return nil, err
+		}
+	}
 
-		for arn := range taskDefinitionFilterMap {
-			r1 := regexp.MustCompile(`([A-Za-z0-9_-]+):([0-9]+)$`)
-			r2 := regexp.MustCompile(`^([A-Za-z0-9_-]+):`)
-			// turns "aws-blather:task-definition-family:revision-number" into "task-definition-family"
-			family := strings.TrimSuffix(r2.FindString(r1.FindString(arn)), ":")
-			inUseTaskDefinitionFamilies[family] = true
+	if e.Flags.CollectTags || len(e.Flags.ProtectTags) > 0 || e.Flags.GracePeriod > 0 {
+		if inv.TaskDefinitionTags, err = e.CollectTaskDefinitionTags(inv.TaskDefinitionARNs); err != nil {
+			return nil, err
 		}
+	}
 
-		runPaginatedLoop := func() {
-			var listedTaskDefinitionARNs []string
-			var err error
+	return inv, nil
+}
 
-			for family := range inUseTaskDefinitionFamilies {
-				listedTaskDefinitionARNs, nextToken, err = e.listTaskDefinitions(family, "DESC", nextToken)
-				if err != nil && !e.Flags.Quiet {
-					fmt.Println("Error listing task definitions, ", err)
-				}
+// FilterTaskDefinitions is PlanTaskDefinitions for callers that only need the ARNs to
+// deregister.
+func (e *ECSClient) FilterTaskDefinitions(inv *inventory.Inventory) ([]string, error) {
+	return e.PlanTaskDefinitions(inv).ARNs(planner.Deregister), nil
+}
 
-				var c, i int
+// PlanTaskDefinitions takes an inventory of the account and plans which task definitions to
+// deregister, leaving out:
+//   - All task definitions curently in use by a service or running task.
+//   - All task definitions which are among the `n`-most-recently-used task definitions for each
+//     family. `n` is configured via the `--cutoff` flag.
+//   - All task definitions among the `n` revisions immediately preceding each service's current
+//     revision, kept as rollback targets. `n` is configured via the `--keep-before-active` flag.
+//   - For families no service uses, the task definitions kept by the orphan policy, configured
+//     via the `--orphan-keep` and `--orphan-keep-days` flags.
+//
+// With a `--grace-period`, task definitions that haven't yet carried the pending tag for that
+// long are held back as well. The decisions themselves are made by the `planner` package; see
+// ECSClient.Policy.
+func (e *ECSClient) PlanTaskDefinitions(inv *inventory.Inventory) *planner.Plan {
+	if !e.Flags.Quiet {
+		fmt.Printf("Filtering out in-use and %d most recent task definitions...\n", e.Flags.Cutoff)
+	}
 
-				for c < e.Flags.Cutoff && c < len(listedTaskDefinitionARNs) && i < len(listedTaskDefinitionARNs) {
-					arn := listedTaskDefinitionARNs[i]
+	plan := planner.Build(inv, e.Policy())
+	taskDefinitionARNsToFilterOut := plan.ARNs(planner.Keep)
 
-					// In theory, the task definition actively in use by the service should be the most
-					// recent one, and the logic could probably be simplified to add e.Flags.Cutoff + 1
-					// task definitions to the filter map. However, in case there's ever a situation
-					// where the active task definition isn't among the first e.Flags.Cutoff + 1 task
-					// definitions, this will prevent the program from keeping around an extraneous
-					// most recent task definition.
-					if !taskDefinitionFilterMap[arn] {
-						taskDefinitionFilterMap[arn] = true
-						c++
-					}
+	if e.Flags.Verbose {
+		fmt.Println("The following task definitions will NOT be deregistered:")
+		for _, decision := range plan.Decisions {
+			if decision.Action != planner.Keep {
+				continue
+			}
 
-					i++
-				}
+			fmt.Println(decision.ARN)
 
-				if e.Flags.Verbose {
-					fmt.Printf("Found %d recent '%s' task definitions\n", c, family)
+			if e.Flags.Debug {
+				for _, reason := range decision.Reasons {
+					fmt.Printf("  %s: %s\n", reason.Code, reason.Detail)
 				}
 			}
 		}
-
-		runPaginatedLoop()
-		for nextToken != nil {
-			runPaginatedLoop()
-		}
 	}
 
-	var taskDefinitionARNsToFilterOut []string
-
-	for arn := range taskDefinitionFilterMap {
-		taskDefinitionARNsToFilterOut = append(taskDefinitionARNsToFilterOut, arn)
+	if !e.Flags.Quiet {
+		fmt.Printf("Filtered out %d task definitions.\n", len(taskDefinitionARNsToFilterOut))
 	}
 
-	sort.Strings(taskDefinitionARNsToFilterOut)
-
-	if e.Flags.Verbose {
-		fmt.Println("The following task definitions will NOT be deregistered:")
-		for _, arn := range taskDefinitionARNsToFilterOut {
-			fmt.Println(arn)
-		}
+	if pending := plan.ARNs(planner.Pending); len(pending) > 0 && !e.Flags.Quiet {
+		fmt.Printf("Holding back %d task definitions for their %v grace period.\n", len(pending), e.Flags.GracePeriod)
 	}
 
-	allTaskDefinitionARNs = removeAFromB(taskDefinitionARNsToFilterOut, allTaskDefinitionARNs)
-	sort.Strings(allTaskDefinitionARNs)
+	if !e.Flags.Quiet && len(plan.OrphanFamilies) > 0 {
+		fmt.Printf("%d families are not used by any service:\n", len(plan.OrphanFamilies))
+		for _, orphan := range plan.OrphanFamilies {
+			registered := "registration date unknown"
+			if orphan.LatestRevisionRegistered != nil {
+				registered = "registered " + orphan.LatestRevisionRegistered.Format("2006-01-02")
+			}
 
-	if !e.Flags.Quiet {
-		fmt.Printf("Filtered out %d task definitions.\n", len(taskDefinitionARNsToFilterOut))
+			fmt.Printf("  %s (latest revision %d, %s): keeping %d, deregistering %d\n",
+				orphan.Family, orphan.LatestRevision, registered, orphan.Kept, orphan.Deregistered)
+		}
 	}
 
-	return allTaskDefinitionARNs, nil
+	return plan
 }
 
 // listClusters is a helper method that handles interaction with AWS objects.
@@ -617,6 +1315,178 @@ func (e *ECSClient) describeServices(clusterARN string, serviceARNs []string) ([
 	return services, nil
 }
 
+// UpdatePendingTags marks the task definitions the Plan starts a grace period for with the
+// pending tag, stamped with `now`, and clears it from those the Plan keeps. Without
+// `--apply` it only reports what it would do.
+func (e *ECSClient) UpdatePendingTags(plan *planner.Plan, now time.Time) error {
+	defer e.startSpan("UpdatePendingTags")()
+
+	toMark := plan.PendingTagARNs(planner.AddPendingTag)
+	toClear := plan.PendingTagARNs(planner.RemovePendingTag)
+
+	if !e.Flags.Apply {
+		if !e.Flags.Quiet && len(toMark)+len(toClear) > 0 {
+			fmt.Printf("Would mark %d task definitions pending deregistration and clear %d.\n", len(toMark), len(toClear))
+		}
+
+		return nil
+	}
+
+	if err := e.Lock.Err(); err != nil {
+		return err
+	}
+
+	if !e.Flags.Quiet && len(toMark) > 0 {
+		fmt.Printf("Marking %d task definitions pending deregistration...\n", len(toMark))
+	}
+
+	for _, arn := range toMark {
+		err := e.retry(func() error {
+			return e.tagResource(arn, planner.PendingTag, now.Format(time.RFC3339))
+		})
+
+		if err := e.handleTaggingError(arn, err); err != nil {
+			return err
+		}
+	}
+
+	if !e.Flags.Quiet && len(toClear) > 0 {
+		fmt.Printf("Clearing the pending tag from %d task definitions back in use...\n", len(toClear))
+	}
+
+	for _, arn := range toClear {
+		err := e.retry(func() error {
+			return e.untagResource(arn, planner.PendingTag)
+		})
+
+		if err := e.handleTaggingError(arn, err); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// handleTaggingError reports a failure to tag or untag a task definition, and returns it
+// only if it should stop the run.
+func (e *ECSClient) handleTaggingError(arn string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if e.isStopworthyError(err) {
+		return err
+	}
+
+	if !e.Flags.Quiet {
+		fmt.Printf("Error tagging %s: %v\n", arn, err)
+	}
+
+	return nil
+}
+
+// describeTaskDefinition is a helper method that handles interaction with AWS objects.
+func (e *ECSClient) describeTaskDefinition(arn string, includeTags bool) (*ecs.DescribeTaskDefinitionOutput, error) {
+	describeTaskDefinitionInput := &ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: aws.String(arn),
+	}
+
+	if includeTags {
+		describeTaskDefinitionInput.Include = aws.StringSlice([]string{ecs.TaskDefinitionFieldTags})
+	}
+
+	return e.Svc.DescribeTaskDefinition(describeTaskDefinitionInput)
+}
+
+// listTagsForResource is a helper method that handles interaction with AWS objects.
+func (e *ECSClient) listTagsForResource(arn string) (map[string]string, error) {
+	listTagsForResourceInput := &ecs.ListTagsForResourceInput{
+		ResourceArn: aws.String(arn),
+	}
+
+	listTagsForResourceOutput, err := e.Svc.ListTagsForResource(listTagsForResourceInput)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string)
+	for _, tag := range listTagsForResourceOutput.Tags {
+		if tag != nil && tag.Key != nil {
+			tags[*tag.Key] = aws.StringValue(tag.Value)
+		}
+	}
+
+	return tags, nil
+}
+
+// tagResource is a helper method that handles interaction with AWS objects.
+func (e *ECSClient) tagResource(arn, key, value string) error {
+	tagResourceInput := &ecs.TagResourceInput{
+		ResourceArn: aws.String(arn),
+		Tags:        []*ecs.Tag{{Key: aws.String(key), Value: aws.String(value)}},
+	}
+
+	_, err := e.Svc.TagResource(tagResourceInput)
+	return err
+}
+
+// untagResource is a helper method that handles interaction with AWS objects.
+func (e *ECSClient) untagResource(arn, key string) error {
+	untagResourceInput := &ecs.UntagResourceInput{
+		ResourceArn: aws.String(arn),
+		TagKeys:     []*string{aws.String(key)},
+	}
+
+	_, err := e.Svc.UntagResource(untagResourceInput)
+	return err
+}
+
+// listTasks is a helper method that handles interaction with AWS objects.
+func (e *ECSClient) listTasks(clusterARN string, nextToken *string) ([]string, *string, error) {
+	listTasksInput := &ecs.ListTasksInput{
+		Cluster:   aws.String(clusterARN),
+		NextToken: nextToken,
+	}
+
+	listTasksOutput, err := e.Svc.ListTasks(listTasksInput)
+	if err != nil {
+		return []string{}, nil, err
+	}
+
+	var taskARNs []string
+	for _, arn := range listTasksOutput.TaskArns {
+		if arn != nil {
+			taskARNs = append(taskARNs, *arn)
+		}
+	}
+
+	nextToken = listTasksOutput.NextToken
+	return taskARNs, nextToken, nil
+}
+
+// describeTasks is a helper method that handles interaction with AWS objects. ListTasks
+// returns at most 100 ARNs per page, which is also DescribeTasks' limit.
+func (e *ECSClient) describeTasks(clusterARN string, taskARNs []string) ([]ecs.Task, error) {
+	describeTasksInput := &ecs.DescribeTasksInput{
+		Cluster: aws.String(clusterARN),
+		Tasks:   aws.StringSlice(taskARNs),
+	}
+
+	describeTasksOutput, err := e.Svc.DescribeTasks(describeTasksInput)
+	if err != nil {
+		return []ecs.Task{}, err
+	}
+
+	var tasks []ecs.Task
+	for _, task := range describeTasksOutput.Tasks {
+		if task != nil {
+			tasks = append(tasks, *task)
+		}
+	}
+
+	return tasks, nil
+}
+
 // Checks whether a given error is the result of the ECS Service's session token
 // having expired.
 func (e *ECSClient) isExpiredTokenError(err error) bool {
@@ -1,18 +1,43 @@
 package ecsclient
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs/cloudwatchlogsiface"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"github.com/aws/aws-sdk-go/service/ecr/ecriface"
 	"github.com/aws/aws-sdk-go/service/ecs"
 	"github.com/golang/mock/gomock"
 	"github.com/jpillora/backoff"
+	"github.com/quintilesims/go-ecs-cleaner/archive"
+	"github.com/quintilesims/go-ecs-cleaner/audit"
+	"github.com/quintilesims/go-ecs-cleaner/ecsfake"
+	"github.com/quintilesims/go-ecs-cleaner/inventory"
+	"github.com/quintilesims/go-ecs-cleaner/lock"
+	"github.com/quintilesims/go-ecs-cleaner/metrics"
 	"github.com/quintilesims/go-ecs-cleaner/mocks"
+	"github.com/quintilesims/go-ecs-cleaner/notify"
+	"github.com/quintilesims/go-ecs-cleaner/planner"
+	"github.com/quintilesims/go-ecs-cleaner/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 )
 
 func setup(t *testing.T) (*gomock.Controller, *ECSClient, *mocks.MockECSAPI) {
@@ -27,6 +52,1101 @@ func setup(t *testing.T) (*gomock.Controller, *ECSClient, *mocks.MockECSAPI) {
 	return ctrl, e, svc
 }
 
+// setupFake is like setup, but backs the ECSClient with an in-memory ECS account rather
+// than a mock.
+func setupFake() (*ECSClient, *ecsfake.ECS) {
+	e := NewECSClient()
+	e.Flags.Quiet = true
+	e.Backoff = &backoff.Backoff{Min: time.Millisecond, Max: 2 * time.Millisecond}
+
+	fake := ecsfake.New("000000000000", "us-east-1")
+	e.Svc = fake
+
+	return e, fake
+}
+
+func Test_CleanupTaskDefinitions(t *testing.T) {
+	e, fake := setupFake()
+	e.Flags.Apply = true
+	e.Flags.Cutoff = 2
+	e.Flags.RetireFamilies = true
+
+	// 25 services across two clusters exercise ListServices pagination and DescribeServices
+	// chunking; each runs the latest revision of its own family.
+	var expected []string
+	for i := 0; i < 25; i++ {
+		family := fmt.Sprintf("family%02d", i)
+		arns := fake.AddTaskDefinitions(family, 5)
+		fake.AddService(fmt.Sprintf("cluster%d", i%2), family, arns[4], 1)
+		expected = append(expected, arns[0], arns[1])
+	}
+
+	// a family with no service loses every revision
+	expected = append(expected, fake.AddTaskDefinitions("unused", 3)...)
+	sort.Strings(expected)
+
+	fake.Throttle("DeregisterTaskDefinition", 3)
+
+	if err := e.CleanupTaskDefinitions(); err != nil {
+		t.Fatal(err)
+	}
+
+	result := fake.TaskDefinitionARNs("INACTIVE")
+	if equal := reflect.DeepEqual(expected, result); !equal {
+		t.Errorf("Expected %v, got %v\n", expected, result)
+	}
+
+	if calls := fake.Calls("DeregisterTaskDefinition"); calls != len(expected)+3 {
+		t.Errorf("Expected %d deregistration calls, got %d\n", len(expected)+3, calls)
+	}
+}
+
+func Test_CleanupTaskDefinitions_DryRun(t *testing.T) {
+	e, fake := setupFake()
+	e.Flags.Cutoff = 1
+
+	fake.AddTaskDefinitions("family0", 3)
+	fake.AddService("cluster0", "service0", "family0:3", 1)
+
+	if err := e.CleanupTaskDefinitions(); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := fake.TaskDefinitionARNs("INACTIVE"); len(result) > 0 {
+		t.Errorf("Expected a dry run, got deregistrations %v\n", result)
+	}
+}
+
+func Test_CleanupTaskDefinitions_LimitTripped(t *testing.T) {
+	e, fake := setupFake()
+	e.Flags.Apply = true
+	e.Flags.MaxFraction = 0.5
+
+	// the previous run's counts mustn't outlive a run that stops at its limits
+	e.Metrics = metrics.New()
+	e.Metrics.SetTaskDefinitions(metrics.Deregistered, 3)
+
+	arns := fake.AddTaskDefinitions("family0", 4)
+	fake.AddService("cluster0", "service0", arns[3], 1)
+
+	err := e.CleanupTaskDefinitions()
+	if _, ok := err.(*planner.LimitError); !ok {
+		t.Errorf("Expected a *planner.LimitError, got %v\n", err)
+	}
+
+	if result := fake.Calls("DeregisterTaskDefinition"); result != 0 {
+		t.Errorf("Expected no deregistrations, got %d\n", result)
+	}
+
+	recorder := httptest.NewRecorder()
+	e.Metrics.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	if expected := `ecs_cleaner_task_definitions{outcome="deregistered"} 0`; !strings.Contains(recorder.Body.String(), expected) {
+		t.Errorf("Expected %q in:\n%s", expected, recorder.Body.String())
+	}
+}
+
+func Test_CleanupTaskDefinitions_NoServicesFound(t *testing.T) {
+	e, fake := setupFake()
+	e.Flags.Apply = true
+
+	// services discovery can't see make every family look like one no service uses
+	fake.AddTaskDefinitions("family0", 3)
+	fake.AddTaskDefinitions("family1", 2)
+	fake.AddCluster("cluster0")
+
+	err := e.CleanupTaskDefinitions()
+	if _, ok := err.(*planner.LimitError); !ok {
+		t.Errorf("Expected a *planner.LimitError, got %v\n", err)
+	}
+
+	if result := fake.Calls("DeregisterTaskDefinition"); result != 0 {
+		t.Errorf("Expected no deregistrations, got %d\n", result)
+	}
+}
+
+func Test_CleanupTaskDefinitions_Review(t *testing.T) {
+	e, fake := setupFake()
+	e.Flags.Apply = true
+	e.Flags.Cutoff = 1
+
+	var buf bytes.Buffer
+	e.Audit = audit.New(audit.WriterSink{Writer: &buf}, audit.Identity{Account: "000000000000"}, "us-east-1")
+
+	// the cutoff keeps revision 3 as well as the service's revision 4
+	arns := fake.AddTaskDefinitions("family0", 4)
+	fake.AddService("cluster0", "family0", arns[3], 1)
+
+	var reviewed []string
+	e.Review = func(taskDefinitionARNs []string) ([]string, error) {
+		reviewed = taskDefinitionARNs
+		return taskDefinitionARNs[:1], nil
+	}
+
+	if err := e.CleanupTaskDefinitions(); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := arns[:2]; !reflect.DeepEqual(expected, reviewed) {
+		t.Errorf("Expected %v reviewed, got %v\n", expected, reviewed)
+	}
+
+	if result := fake.TaskDefinitionARNs("INACTIVE"); !reflect.DeepEqual(arns[:1], result) {
+		t.Errorf("Expected %v deregistered, got %v\n", arns[:1], result)
+	}
+
+	expected := fmt.Sprintf(`"action":"skip","arn":%q,"decision":"deregister"`, arns[1])
+	if !strings.Contains(buf.String(), expected) {
+		t.Errorf("Expected the deselected task definition logged as skipped, got:\n%s", buf.String())
+	}
+}
+
+type recordingSink struct {
+	summaries []notify.Summary
+}
+
+func (s *recordingSink) Name() string {
+	return "recording"
+}
+
+func (s *recordingSink) Send(summary notify.Summary) error {
+	s.summaries = append(s.summaries, summary)
+	return nil
+}
+
+func Test_CleanupTaskDefinitions_Notify(t *testing.T) {
+	e, fake := setupFake()
+	e.Flags.Apply = true
+	e.Flags.Cutoff = 1
+	e.Flags.RetireFamilies = true
+
+	sink := &recordingSink{}
+	e.Notifier = &notify.Notifier{On: notify.Always, Sinks: []notify.Sink{sink}}
+
+	arns := fake.AddTaskDefinitions("family0", 4)
+	fake.AddService("cluster0", "family0", arns[3], 1)
+
+	// no service uses family1, so none of it is kept
+	fake.AddTaskDefinitions("family1", 2)
+
+	// the stack deregisters the last ARN first
+	fake.InjectFault("DeregisterTaskDefinition", awserr.New(ecs.ErrCodeClientException, "denied", nil), 1)
+
+	if err := e.CleanupTaskDefinitions(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sink.summaries) != 1 {
+		t.Fatalf("Expected one summary, got %d\n", len(sink.summaries))
+	}
+
+	summary := sink.summaries[0]
+
+	expectedCounts := notify.Counts{Discovered: 6, Kept: 2, Planned: 4, Deregistered: 3, Failed: 1}
+	if summary.Counts != expectedCounts {
+		t.Errorf("Expected %+v, got %+v\n", expectedCounts, summary.Counts)
+	}
+
+	expectedFamilies := []notify.Family{{Family: "family0", Count: 2}, {Family: "family1", Count: 2}}
+	if !reflect.DeepEqual(expectedFamilies, summary.Families) {
+		t.Errorf("Expected %v, got %v\n", expectedFamilies, summary.Families)
+	}
+
+	if summary.Result != notify.Succeeded || !summary.Apply || len(summary.Failures) != 1 {
+		t.Errorf("Expected a successful apply with one failure, got %+v\n", summary)
+	}
+
+	e.Flags.MaxDeregistrations = 1
+	fake.AddTaskDefinitions("family2", 3)
+
+	if err := e.CleanupTaskDefinitions(); err == nil {
+		t.Fatal("Expected the limit to trip")
+	}
+
+	if summary := sink.summaries[1]; summary.Result != notify.Failed || summary.LimitTripped == "" {
+		t.Errorf("Expected a failed run that tripped a limit, got %+v\n", summary)
+	}
+}
+
+func Test_DeregisterTaskDefinitions_Tracing(t *testing.T) {
+	e, fake := setupFake()
+
+	recorder := tracetest.NewSpanRecorder()
+	e.Tracer = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer(tracing.Name)
+
+	arns := fake.AddTaskDefinitions("family0", 1)
+	fake.Throttle("DeregisterTaskDefinition", 2)
+
+	if err := e.DeregisterTaskDefinitions(arns); err != nil {
+		t.Fatal(err)
+	}
+
+	var result []string
+	for _, span := range recorder.Ended() {
+		attrs := make(map[attribute.Key]string)
+		for _, kv := range span.Attributes() {
+			attrs[kv.Key] = kv.Value.Emit()
+		}
+
+		if span.Name() == "Backoff" {
+			result = append(result, fmt.Sprintf("%s attempt %s", attrs[tracing.Family], attrs[tracing.BackoffAttempt]))
+
+			if attrs[tracing.BackoffDuration] == "" {
+				t.Errorf("Expected the backoff's duration, got %v\n", attrs)
+			}
+		}
+	}
+
+	if expected := []string{"family0 attempt 1", "family0 attempt 2"}; !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, got %v\n", expected, result)
+	}
+}
+
+func Test_CleanupTaskDefinitions_Lock(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ecsclient-lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	backend := &lock.FileBackend{Path: filepath.Join(dir, "lock.json")}
+
+	e, fake := setupFake()
+	e.Flags.Apply = true
+	e.Flags.Cutoff = 1
+	e.Flags.RetireFamilies = true
+	e.Lock = lock.New(backend, "000000000000/us-east-1", "cron", time.Minute)
+
+	arns := fake.AddTaskDefinitions("family0", 2)
+
+	laptop := lock.New(backend, "000000000000/us-east-1", "laptop", time.Minute)
+	if err := laptop.Acquire(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.CleanupTaskDefinitions(); err == nil {
+		t.Fatal("Expected the run to be refused while the lock is held")
+	} else if _, ok := err.(*lock.HeldError); !ok {
+		t.Fatalf("Expected a HeldError, got %v\n", err)
+	}
+
+	if result := fake.TaskDefinitionARNs("INACTIVE"); len(result) != 0 {
+		t.Errorf("Expected nothing deregistered, got %v\n", result)
+	}
+
+	if err := laptop.Release(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := e.CleanupTaskDefinitions(); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := fake.TaskDefinitionARNs("INACTIVE"); !reflect.DeepEqual(arns, result) {
+		t.Errorf("Expected %v deregistered, got %v\n", arns, result)
+	}
+
+	// released when the run finished
+	if err := laptop.Acquire(); err != nil {
+		t.Error(err)
+	}
+
+	laptop.Release()
+}
+
+func Test_DeregisterTargets(t *testing.T) {
+	testCases := map[string]struct {
+		targets  []string
+		force    bool
+		inUse    bool
+		expected []string
+	}{
+		"revisions and families": {
+			targets:  []string{"family1", "family0:1"},
+			expected: []string{"family0:1", "family1:1", "family1:2"},
+		},
+		"in use": {
+			targets: []string{"family1", "family0"},
+			inUse:   true,
+		},
+		"forced": {
+			targets:  []string{"family0"},
+			force:    true,
+			expected: []string{"family0:1", "family0:2", "family0:3"},
+		},
+		"scheduled": {
+			targets: []string{"family2:1"},
+			inUse:   true,
+		},
+	}
+
+	for name, testCase := range testCases {
+		e, fake := setupFake()
+		e.Events = fake.Events()
+		e.Flags.Apply = true
+		e.Flags.Force = testCase.force
+		e.Flags.RevalidateEvery = 1
+
+		fake.AddTaskDefinitions("family0", 3)
+		fake.AddTaskDefinitions("family1", 2)
+		fake.AddTaskDefinitions("family2", 1)
+		fake.AddService("cluster0", "service0", "family0:3", 1)
+		fake.AddSchedule("cluster0", "nightly", "family2")
+
+		err := e.DeregisterTargets(testCase.targets)
+		if _, ok := err.(*planner.InUseError); ok != testCase.inUse {
+			t.Errorf("TestCase '%s': expected in-use error %t, got %v\n", name, testCase.inUse, err)
+		} else if err != nil && !testCase.inUse {
+			t.Fatalf("TestCase '%s': %v", name, err)
+		}
+
+		var expected []string
+		for _, familyRevision := range testCase.expected {
+			expected = append(expected, "arn:aws:ecs:us-east-1:000000000000:task-definition/"+familyRevision)
+		}
+
+		if result := fake.TaskDefinitionARNs("INACTIVE"); !reflect.DeepEqual(expected, result) {
+			t.Errorf("TestCase '%s': expected %v deregistered, got %v\n", name, expected, result)
+		}
+
+		if e.Flags.RevalidateEvery != 1 {
+			t.Errorf("TestCase '%s': expected RevalidateEvery restored, got %d\n", name, e.Flags.RevalidateEvery)
+		}
+	}
+}
+
+func Test_CleanupTaskDefinitions_GracePeriod(t *testing.T) {
+	e, fake := setupFake()
+	e.Flags.Apply = true
+	e.Flags.GracePeriod = 24 * time.Hour
+
+	arns := fake.AddTaskDefinitions("family0", 3)
+	fake.AddService("cluster0", "service0", arns[2], 1)
+
+	pendingTag := func(arn string) string {
+		output, err := fake.ListTagsForResource(&ecs.ListTagsForResourceInput{ResourceArn: aws.String(arn)})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tag := range output.Tags {
+			if *tag.Key == planner.PendingTag {
+				return *tag.Value
+			}
+		}
+
+		return ""
+	}
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	e.Now = func() time.Time { return start }
+
+	if err := e.CleanupTaskDefinitions(); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := fake.TaskDefinitionARNs("INACTIVE"); len(result) != 0 {
+		t.Errorf("Expected nothing deregistered during the first run, got %v\n", result)
+	}
+
+	for _, arn := range arns[:2] {
+		if result := pendingTag(arn); result != "2026-01-01T00:00:00Z" {
+			t.Errorf("Expected %s to be marked pending, got %q\n", arn, result)
+		}
+	}
+
+	// revision 2 goes back into use before the grace period ends
+	fake.AddService("cluster0", "service1", arns[1], 1)
+	e.Now = func() time.Time { return start.Add(48 * time.Hour) }
+
+	if err := e.CleanupTaskDefinitions(); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := fake.TaskDefinitionARNs("INACTIVE"); !reflect.DeepEqual(arns[:1], result) {
+		t.Errorf("Expected %v deregistered, got %v\n", arns[:1], result)
+	}
+
+	if result := pendingTag(arns[1]); result != "" {
+		t.Errorf("Expected the pending tag cleared from %s, got %q\n", arns[1], result)
+	}
+}
+
+// clusterARNs lists the fake's clusters.
+func clusterARNs(t *testing.T, fake *ecsfake.ECS) []string {
+	output, err := fake.ListClusters(&ecs.ListClustersInput{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return aws.StringValueSlice(output.ClusterArns)
+}
+
+func Test_CleanupClusters(t *testing.T) {
+	e, fake := setupFake()
+
+	empty := fake.AddCluster("empty")
+	arns := fake.AddTaskDefinitions("family0", 1)
+	fake.AddService("services", "service0", arns[0], 0)
+	fake.AddTask("tasks", arns[0], "")
+	fake.SetContainerInstances("instances", 2)
+	fake.SetCapacityProviders("capacity", "FARGATE")
+
+	expected := clusterARNs(t, fake)
+
+	if err := e.CleanupClusters(); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := clusterARNs(t, fake); !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected a dry run to delete nothing, got %v\n", result)
+	}
+
+	e.Flags.Apply = true
+	fake.Throttle("DeleteCluster", 2)
+
+	if err := e.CleanupClusters(); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := clusterARNs(t, fake); !reflect.DeepEqual(expected[1:], result) {
+		t.Errorf("Expected only %s deleted, got %v\n", empty, result)
+	}
+}
+
+func Test_CleanupClusters_EmptyDays(t *testing.T) {
+	e, fake := setupFake()
+	e.Flags.Apply = true
+	e.Flags.EmptyDays = 30
+
+	empty := fake.AddCluster("empty")
+	reused := fake.AddCluster("reused")
+
+	emptySince := func(arn string) string {
+		output, err := fake.ListTagsForResource(&ecs.ListTagsForResourceInput{ResourceArn: aws.String(arn)})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, tag := range output.Tags {
+			if *tag.Key == EmptySinceTag {
+				return *tag.Value
+			}
+		}
+
+		return ""
+	}
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	e.Now = func() time.Time { return start }
+
+	if err := e.CleanupClusters(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, arn := range []string{empty, reused} {
+		if result := emptySince(arn); result != "2026-01-01T00:00:00Z" {
+			t.Errorf("Expected %s to be marked empty, got %q\n", arn, result)
+		}
+	}
+
+	// reused gets a service before its 30 days are up
+	arns := fake.AddTaskDefinitions("family0", 1)
+	fake.AddService("reused", "service0", arns[0], 1)
+
+	e.Now = func() time.Time { return start.AddDate(0, 0, 29) }
+	if err := e.CleanupClusters(); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := clusterARNs(t, fake); len(result) != 2 {
+		t.Errorf("Expected nothing deleted before 30 days, got %v\n", result)
+	}
+
+	if result := emptySince(reused); result != "" {
+		t.Errorf("Expected the empty tag cleared from %s, got %q\n", reused, result)
+	}
+
+	e.Now = func() time.Time { return start.AddDate(0, 0, 30) }
+	if err := e.CleanupClusters(); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := clusterARNs(t, fake); !reflect.DeepEqual([]string{reused}, result) {
+		t.Errorf("Expected %s deleted, got %v\n", empty, result)
+	}
+}
+
+func Test_CleanupServices(t *testing.T) {
+	e, fake := setupFake()
+	e.Flags.IdleDays = 90
+	e.Flags.ExcludeServices = []string{"prod/*"}
+	e.Flags.ProtectTags = map[string]string{"keep": ""}
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	e.Now = func() time.Time { return start }
+	fake.Now = func() time.Time { return start.AddDate(0, 0, -200) }
+
+	arns := fake.AddTaskDefinitions("family0", 1)
+	idle := fake.AddService("staging", "idle", arns[0], 0)
+	fake.AddService("staging", "busy", arns[0], 1)
+	recent := fake.AddService("staging", "recent", arns[0], 0)
+	steady := fake.AddService("staging", "steady", arns[0], 0)
+	protected := fake.AddService("staging", "protected", arns[0], 0)
+	fake.AddService("prod", "excluded", arns[0], 0)
+
+	fake.AddServiceEvent(recent, "(service recent) was unable to place a task.", start.AddDate(0, 0, -10))
+	fake.AddServiceEvent(steady, "(service steady) has reached a steady state.", start.AddDate(0, 0, -1))
+	fake.TagResource(&ecs.TagResourceInput{
+		ResourceArn: aws.String(protected),
+		Tags:        []*ecs.Tag{{Key: aws.String("keep"), Value: aws.String("true")}},
+	})
+
+	services := func(cluster string) []string {
+		output, err := fake.ListServices(&ecs.ListServicesInput{Cluster: aws.String(cluster)})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return aws.StringValueSlice(output.ServiceArns)
+	}
+
+	if err := e.CleanupServices(); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := services("staging"); len(result) != 5 {
+		t.Errorf("Expected a dry run to delete nothing, got %v\n", result)
+	}
+
+	e.Flags.Apply = true
+	fake.Throttle("DeleteService", 2)
+
+	if err := e.CleanupServices(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, arn := range services("staging") {
+		if arn == idle || arn == steady {
+			t.Errorf("Expected %s to be deleted\n", arn)
+		}
+	}
+
+	if result := services("staging"); len(result) != 3 {
+		t.Errorf("Expected busy, recent and protected to remain, got %v\n", result)
+	}
+
+	if result := services("prod"); len(result) != 1 {
+		t.Errorf("Expected the excluded service to remain, got %v\n", result)
+	}
+}
+
+func Test_matchesServiceFilters(t *testing.T) {
+	cases := []struct {
+		include  []string
+		exclude  []string
+		name     string
+		expected bool
+	}{
+		{nil, nil, "prod/web", true},
+		{[]string{"staging-*/*"}, nil, "prod/web", false},
+		{[]string{"staging-*/*"}, nil, "staging-1/web", true},
+		{[]string{"staging-*/*"}, []string{"*/db"}, "staging-1/db", false},
+		{nil, []string{"prod/*"}, "prod/web", false},
+	}
+
+	for _, c := range cases {
+		e := NewECSClient()
+		e.Flags.IncludeServices, e.Flags.ExcludeServices = c.include, c.exclude
+
+		if result := e.matchesServiceFilters(c.name); result != c.expected {
+			t.Errorf("Expected %v for %s with include %v and exclude %v, got %v\n", c.expected, c.name, c.include, c.exclude, result)
+		}
+	}
+}
+
+// fakeECR is an in-memory ECR registry for a single account, holding images by repository
+// and multi-architecture manifests by digest.
+type fakeECR struct {
+	ecriface.ECRAPI
+
+	account   string
+	images    map[string][]*ecr.ImageDetail
+	manifests map[string]string
+}
+
+func newFakeECR(account string) *fakeECR {
+	return &fakeECR{account: account, images: make(map[string][]*ecr.ImageDetail), manifests: make(map[string]string)}
+}
+
+func (f *fakeECR) addImage(repository, digest, mediaType string, pushedAt time.Time, tags ...string) {
+	f.images[repository] = append(f.images[repository], &ecr.ImageDetail{
+		ImageDigest:            aws.String(digest),
+		ImageManifestMediaType: aws.String(mediaType),
+		ImagePushedAt:          aws.Time(pushedAt),
+		ImageTags:              aws.StringSlice(tags),
+		RegistryId:             aws.String(f.account),
+		RepositoryName:         aws.String(repository),
+	})
+}
+
+func (f *fakeECR) digests(repository string) []string {
+	var digests []string
+	for _, image := range f.images[repository] {
+		digests = append(digests, aws.StringValue(image.ImageDigest))
+	}
+
+	sort.Strings(digests)
+	return digests
+}
+
+func (f *fakeECR) DescribeRepositories(input *ecr.DescribeRepositoriesInput) (*ecr.DescribeRepositoriesOutput, error) {
+	output := &ecr.DescribeRepositoriesOutput{}
+	for repository := range f.images {
+		output.Repositories = append(output.Repositories, &ecr.Repository{
+			RegistryId:     aws.String(f.account),
+			RepositoryName: aws.String(repository),
+		})
+	}
+
+	return output, nil
+}
+
+func (f *fakeECR) DescribeImages(input *ecr.DescribeImagesInput) (*ecr.DescribeImagesOutput, error) {
+	return &ecr.DescribeImagesOutput{ImageDetails: f.images[aws.StringValue(input.RepositoryName)]}, nil
+}
+
+func (f *fakeECR) BatchGetImage(input *ecr.BatchGetImageInput) (*ecr.BatchGetImageOutput, error) {
+	output := &ecr.BatchGetImageOutput{}
+	for _, id := range input.ImageIds {
+		output.Images = append(output.Images, &ecr.Image{
+			ImageId:       id,
+			ImageManifest: aws.String(f.manifests[aws.StringValue(id.ImageDigest)]),
+		})
+	}
+
+	return output, nil
+}
+
+func (f *fakeECR) BatchDeleteImage(input *ecr.BatchDeleteImageInput) (*ecr.BatchDeleteImageOutput, error) {
+	repository := aws.StringValue(input.RepositoryName)
+	output := &ecr.BatchDeleteImageOutput{}
+
+	for _, id := range input.ImageIds {
+		images := f.images[repository][:0]
+		for _, image := range f.images[repository] {
+			if aws.StringValue(image.ImageDigest) != aws.StringValue(id.ImageDigest) {
+				images = append(images, image)
+			}
+		}
+
+		f.images[repository] = images
+		output.ImageIds = append(output.ImageIds, id)
+	}
+
+	return output, nil
+}
+
+func Test_ImageReferences(t *testing.T) {
+	registry := "000000000000.dkr.ecr.us-east-1.amazonaws.com"
+
+	testCases := map[string]struct {
+		image    string
+		digest   string
+		expected ImageReferences
+	}{
+		"tag":             {registry + "/app:v1", "", ImageReferences{"000000000000/app:v1": true}},
+		"implicit latest": {registry + "/team/app", "", ImageReferences{"000000000000/team/app:latest": true}},
+		"digest":          {registry + "/app@sha256:a", "", ImageReferences{"000000000000/app@sha256:a": true}},
+		"tag and digest":  {registry + "/app:v1@sha256:a", "", ImageReferences{"000000000000/app:v1": true, "000000000000/app@sha256:a": true}},
+		"running digest":  {registry + "/app:v1", "sha256:b", ImageReferences{"000000000000/app:v1": true, "000000000000/app@sha256:b": true}},
+		"docker hub":      {"nginx:latest", "", ImageReferences{}},
+		"other registry":  {"localhost:5000/app:v1", "", ImageReferences{}},
+	}
+
+	for name, testCase := range testCases {
+		result := make(ImageReferences)
+		result.Add(testCase.image, testCase.digest)
+
+		if equal := reflect.DeepEqual(testCase.expected, result); !equal {
+			t.Errorf("TestCase '%s': expected %v, got %v\n", name, testCase.expected, result)
+		}
+	}
+}
+
+func Test_CleanupImages(t *testing.T) {
+	e, fake := setupFake()
+	e.Flags.ImageAgeDays = 30
+	e.Flags.ImageRepositories = []string{"app*"}
+	e.Flags.KeepImages = 2
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	e.Now = func() time.Time { return start }
+	daysAgo := func(days int) time.Time { return start.AddDate(0, 0, -days) }
+
+	registry := newFakeECR("000000000000")
+	e.ECR = registry
+
+	const index = "application/vnd.oci.image.index.v1+json"
+	const manifest = "application/vnd.oci.image.manifest.v1+json"
+
+	registry.addImage("app", "sha256:active", manifest, daysAgo(100), "v1")
+	registry.addImage("app", "sha256:retired", manifest, daysAgo(90), "v2")
+	registry.addImage("app", "sha256:running", manifest, daysAgo(80))
+	registry.addImage("app", "sha256:multi", index, daysAgo(70), "multi")
+	registry.addImage("app", "sha256:platform", manifest, daysAgo(70))
+	registry.addImage("app", "sha256:untagged", manifest, daysAgo(60))
+	registry.addImage("app", "sha256:kept", manifest, daysAgo(40), "v8")
+	registry.addImage("app", "sha256:recent", manifest, daysAgo(10), "v9")
+	registry.addImage("other", "sha256:other", manifest, daysAgo(100))
+	registry.manifests["sha256:multi"] = `{"manifests":[{"digest":"sha256:platform"}]}`
+
+	register := func(image string) string {
+		output, err := fake.RegisterTaskDefinition(&ecs.RegisterTaskDefinitionInput{
+			Family:               aws.String("family0"),
+			ContainerDefinitions: []*ecs.ContainerDefinition{{Name: aws.String("app"), Image: aws.String(image)}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return aws.StringValue(output.TaskDefinition.TaskDefinitionArn)
+	}
+
+	host := "000000000000.dkr.ecr.us-east-1.amazonaws.com/app"
+	register(host + ":v1")
+	register(host + ":multi")
+	retired := register(host + ":v2")
+	running := register(host + "@sha256:running")
+	fake.AddTask("cluster0", running, "")
+
+	for _, arn := range []string{retired, running} {
+		if _, err := fake.DeregisterTaskDefinition(&ecs.DeregisterTaskDefinitionInput{TaskDefinition: aws.String(arn)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := e.CleanupImages(); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := registry.digests("app"); len(result) != 8 {
+		t.Errorf("Expected a dry run to delete nothing, got %v\n", result)
+	}
+
+	e.Flags.Apply = true
+
+	if err := e.CleanupImages(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"sha256:active", "sha256:kept", "sha256:multi", "sha256:platform", "sha256:recent", "sha256:running"}
+	if result := registry.digests("app"); !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v to remain, got %v\n", expected, result)
+	}
+
+	if result := registry.digests("other"); len(result) != 1 {
+		t.Errorf("Expected the unmatched repository to be left alone, got %v\n", result)
+	}
+}
+
+func Test_UnreferencedImages_KeepLast(t *testing.T) {
+	e, _ := setupFake()
+	e.Flags.KeepImages = 2
+
+	registry := newFakeECR("000000000000")
+	e.ECR = registry
+
+	const index = "application/vnd.oci.image.index.v1+json"
+	const manifest = "application/vnd.oci.image.manifest.v1+json"
+
+	// each multi-architecture image lists three untagged platform images, pushed just before it
+	start := e.Now().AddDate(0, 0, -1)
+	for i, tag := range []string{"v3", "v2"} {
+		pushedAt := start.AddDate(0, 0, -i)
+
+		var children []string
+		for _, platform := range []string{"amd64", "arm64", "arm"} {
+			digest := fmt.Sprintf("sha256:%s-%s", tag, platform)
+			registry.addImage("app", digest, manifest, pushedAt.Add(-time.Minute))
+			children = append(children, fmt.Sprintf(`{"digest":%q}`, digest))
+		}
+
+		registry.addImage("app", "sha256:"+tag, index, pushedAt, tag)
+		registry.manifests["sha256:"+tag] = fmt.Sprintf(`{"manifests":[%s]}`, strings.Join(children, ","))
+	}
+
+	registry.addImage("app", "sha256:v1", manifest, start.AddDate(0, 0, -2), "v1")
+
+	repository := &ecr.Repository{RegistryId: aws.String("000000000000"), RepositoryName: aws.String("app")}
+	unreferenced, err := e.UnreferencedImages(repository, ImageReferences{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result []string
+	for _, image := range unreferenced {
+		result = append(result, image.Digest)
+	}
+
+	if expected := []string{"sha256:v1"}; !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected only %v unreferenced, got %v\n", expected, result)
+	}
+}
+
+func Test_CleanupImages_CollectionError(t *testing.T) {
+	e, fake := setupFake()
+	e.Flags.Apply = true
+	e.Flags.ImageRepositories = []string{"app"}
+
+	registry := newFakeECR("000000000000")
+	e.ECR = registry
+	registry.addImage("app", "sha256:active", "application/vnd.oci.image.manifest.v1+json", e.Now().AddDate(-1, 0, 0), "v1")
+
+	if _, err := fake.RegisterTaskDefinition(&ecs.RegisterTaskDefinitionInput{
+		Family:               aws.String("family0"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{{Name: aws.String("app"), Image: aws.String("000000000000.dkr.ecr.us-east-1.amazonaws.com/app:v1")}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// without the task definitions, nothing would refer to the image
+	fake.InjectFault("ListTaskDefinitions", awserr.New(ecs.ErrCodeClientException, "denied", nil), 1)
+
+	if err := e.CleanupImages(); err == nil {
+		t.Error("Expected an error")
+	}
+
+	if result := registry.digests("app"); len(result) != 1 {
+		t.Errorf("Expected nothing deleted, got %v\n", result)
+	}
+}
+
+// fakeLogs is an in-memory CloudWatch Logs account, holding log groups by name and when each
+// last received an event.
+type fakeLogs struct {
+	cloudwatchlogsiface.CloudWatchLogsAPI
+
+	logGroups  map[string]*cloudwatchlogs.LogGroup
+	lastEvents map[string]time.Time
+}
+
+func newFakeLogs() *fakeLogs {
+	return &fakeLogs{logGroups: make(map[string]*cloudwatchlogs.LogGroup), lastEvents: make(map[string]time.Time)}
+}
+
+func (f *fakeLogs) addLogGroup(name string, createdAt time.Time) {
+	f.logGroups[name] = &cloudwatchlogs.LogGroup{
+		CreationTime: aws.Int64(aws.TimeUnixMilli(createdAt)),
+		LogGroupName: aws.String(name),
+	}
+}
+
+func (f *fakeLogs) retention() map[string]int64 {
+	retention := make(map[string]int64)
+	for name, logGroup := range f.logGroups {
+		retention[name] = aws.Int64Value(logGroup.RetentionInDays)
+	}
+
+	return retention
+}
+
+func (f *fakeLogs) DescribeLogGroups(input *cloudwatchlogs.DescribeLogGroupsInput) (*cloudwatchlogs.DescribeLogGroupsOutput, error) {
+	var names []string
+	for name := range f.logGroups {
+		if strings.HasPrefix(name, aws.StringValue(input.LogGroupNamePrefix)) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	output := &cloudwatchlogs.DescribeLogGroupsOutput{}
+	for _, name := range names {
+		logGroup := *f.logGroups[name]
+		output.LogGroups = append(output.LogGroups, &logGroup)
+	}
+
+	return output, nil
+}
+
+func (f *fakeLogs) DescribeLogStreams(input *cloudwatchlogs.DescribeLogStreamsInput) (*cloudwatchlogs.DescribeLogStreamsOutput, error) {
+	output := &cloudwatchlogs.DescribeLogStreamsOutput{}
+	if last, ok := f.lastEvents[aws.StringValue(input.LogGroupName)]; ok {
+		output.LogStreams = append(output.LogStreams, &cloudwatchlogs.LogStream{
+			LastEventTimestamp: aws.Int64(aws.TimeUnixMilli(last)),
+		})
+	}
+
+	return output, nil
+}
+
+func (f *fakeLogs) DeleteLogGroup(input *cloudwatchlogs.DeleteLogGroupInput) (*cloudwatchlogs.DeleteLogGroupOutput, error) {
+	delete(f.logGroups, aws.StringValue(input.LogGroupName))
+	return &cloudwatchlogs.DeleteLogGroupOutput{}, nil
+}
+
+func (f *fakeLogs) PutRetentionPolicy(input *cloudwatchlogs.PutRetentionPolicyInput) (*cloudwatchlogs.PutRetentionPolicyOutput, error) {
+	f.logGroups[aws.StringValue(input.LogGroupName)].RetentionInDays = input.RetentionInDays
+	return &cloudwatchlogs.PutRetentionPolicyOutput{}, nil
+}
+
+func Test_CleanupLogGroups(t *testing.T) {
+	e, fake := setupFake()
+	e.Flags.IdleDays = 30
+	e.Flags.LogGroupPrefix = "/ecs/"
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	e.Now = func() time.Time { return start }
+	daysAgo := func(days int) time.Time { return start.AddDate(0, 0, -days) }
+
+	logs := newFakeLogs()
+	e.Logs = logs
+
+	for _, name := range []string{"/ecs/web", "/ecs/retired", "/ecs/job", "/ecs/recent", "/ecs/unused", "/other/app"} {
+		logs.addLogGroup(name, daysAgo(200))
+	}
+
+	logs.lastEvents["/ecs/recent"] = daysAgo(5)
+	logs.lastEvents["/ecs/unused"] = daysAgo(60)
+
+	register := func(family, logGroup string) string {
+		output, err := fake.RegisterTaskDefinition(&ecs.RegisterTaskDefinitionInput{
+			Family: aws.String(family),
+			ContainerDefinitions: []*ecs.ContainerDefinition{{
+				Name: aws.String("app"),
+				LogConfiguration: &ecs.LogConfiguration{
+					LogDriver: aws.String(ecs.LogDriverAwslogs),
+					Options:   aws.StringMap(map[string]string{"awslogs-group": logGroup}),
+				},
+			}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return aws.StringValue(output.TaskDefinition.TaskDefinitionArn)
+	}
+
+	register("web", "/ecs/web")
+	retired := register("retired", "/ecs/retired")
+	job := register("job", "/ecs/job")
+	fake.AddTask("cluster0", job, "")
+
+	for _, arn := range []string{retired, job} {
+		if _, err := fake.DeregisterTaskDefinition(&ecs.DeregisterTaskDefinitionInput{TaskDefinition: aws.String(arn)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := e.CleanupLogGroups(); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := logs.retention(); len(result) != 6 {
+		t.Errorf("Expected a dry run to delete nothing, got %v\n", result)
+	}
+
+	e.Flags.Apply = true
+	e.Flags.LogRetentionDays = 7
+
+	if err := e.CleanupLogGroups(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]int64{"/ecs/web": 0, "/ecs/retired": 7, "/ecs/job": 0, "/ecs/recent": 0, "/ecs/unused": 7, "/other/app": 0}
+	if result := logs.retention(); !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, got %v\n", expected, result)
+	}
+
+	e.Flags.LogRetentionDays = 0
+
+	if err := e.CleanupLogGroups(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected = map[string]int64{"/ecs/web": 0, "/ecs/job": 0, "/ecs/recent": 0, "/other/app": 0}
+	if result := logs.retention(); !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, got %v\n", expected, result)
+	}
+}
+
+func Test_OrphanedLogGroups_Retention(t *testing.T) {
+	e, _ := setupFake()
+	e.Flags.LogRetentionDays = 30
+
+	logs := newFakeLogs()
+	e.Logs = logs
+
+	// 0 keeps events forever
+	for name, retention := range map[string]int64{"/ecs/forever": 0, "/ecs/week": 7, "/ecs/month": 30, "/ecs/quarter": 90} {
+		logs.addLogGroup(name, e.Now().AddDate(-1, 0, 0))
+		logs.logGroups[name].RetentionInDays = aws.Int64(retention)
+	}
+
+	logGroups, err := e.CollectLogGroups()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orphaned, err := e.OrphanedLogGroups(logGroups, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result []string
+	for _, logGroup := range orphaned {
+		result = append(result, logGroup.Name)
+	}
+
+	if expected := []string{"/ecs/forever", "/ecs/quarter"}; !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, got %v\n", expected, result)
+	}
+}
+
+func Test_CleanupLogGroups_CollectionError(t *testing.T) {
+	e, fake := setupFake()
+	e.Flags.Apply = true
+	e.Flags.LogGroupPrefix = "/ecs/"
+
+	logs := newFakeLogs()
+	e.Logs = logs
+	logs.addLogGroup("/ecs/job", e.Now().AddDate(-1, 0, 0))
+
+	// only a running task, of a deregistered task definition, logs to the group
+	output, err := fake.RegisterTaskDefinition(&ecs.RegisterTaskDefinitionInput{
+		Family: aws.String("job"),
+		ContainerDefinitions: []*ecs.ContainerDefinition{{
+			Name: aws.String("app"),
+			LogConfiguration: &ecs.LogConfiguration{
+				LogDriver: aws.String(ecs.LogDriverAwslogs),
+				Options:   aws.StringMap(map[string]string{"awslogs-group": "/ecs/job"}),
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := aws.StringValue(output.TaskDefinition.TaskDefinitionArn)
+	fake.AddTask("cluster0", job, "")
+
+	if _, err := fake.DeregisterTaskDefinition(&ecs.DeregisterTaskDefinitionInput{TaskDefinition: aws.String(job)}); err != nil {
+		t.Fatal(err)
+	}
+
+	fake.Throttle("ListTasks", 1)
+	fake.InjectFault("ListTasks", awserr.New(ecs.ErrCodeClientException, "denied", nil), 1)
+
+	if err := e.CleanupLogGroups(); err == nil {
+		t.Error("Expected an error")
+	}
+
+	if result := logs.retention(); len(result) != 1 {
+		t.Errorf("Expected nothing deleted, got %v\n", result)
+	}
+}
+
 func Test_CollectClusters(t *testing.T) {
 	ctrl, e, svc := setup(t)
 	defer ctrl.Finish()
@@ -146,6 +1266,464 @@ func Test_CollectTaskDefinitions(t *testing.T) {
 	}
 }
 
+func Test_Discover_RegistrationDates(t *testing.T) {
+	e, fake := setupFake()
+
+	used := fake.AddTaskDefinitions("family0", 2)
+	fake.AddService("cluster0", "service0", used[1], 1)
+	orphaned := fake.AddTaskDefinitions("family1", 3)
+
+	testCases := map[string]struct {
+		orphanKeepDays int
+		expected       []string
+	}{
+		"latest orphan revision only": {0, orphaned[2:]},
+		"every orphan revision":       {30, orphaned},
+	}
+
+	for name, testCase := range testCases {
+		e.Flags.OrphanKeepDays = testCase.orphanKeepDays
+
+		inv, err := e.Discover()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var result []string
+		for arn := range inv.TaskDefinitionRegisteredAt {
+			result = append(result, arn)
+		}
+
+		sort.Strings(result)
+
+		if equal := reflect.DeepEqual(testCase.expected, result); !equal {
+			t.Errorf("TestCase '%s': expected %v, got %v\n", name, testCase.expected, result)
+		}
+	}
+}
+
+func Test_CollectTaskDefinitionTags(t *testing.T) {
+	e, fake := setupFake()
+
+	untagged := fake.AddTaskDefinitions("family0", 1)[0]
+	output, err := fake.RegisterTaskDefinition(&ecs.RegisterTaskDefinitionInput{
+		Family: aws.String("family0"),
+		Tags:   []*ecs.Tag{{Key: aws.String("keep"), Value: aws.String("true")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tagged := *output.TaskDefinition.TaskDefinitionArn
+	fake.Throttle("ListTagsForResource", 2)
+
+	expected := map[string]map[string]string{
+		tagged: {"keep": "true"},
+	}
+
+	result, err := e.CollectTaskDefinitionTags([]string{untagged, tagged})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if equal := reflect.DeepEqual(expected, result); !equal {
+		t.Errorf("Expected %v, got %v\n", expected, result)
+	}
+}
+
+func Test_CollectTasks(t *testing.T) {
+	ctrl, e, svc := setup(t)
+	defer ctrl.Finish()
+
+	expected := []ecs.Task{
+		ecs.Task{TaskArn: aws.String("task0")},
+		ecs.Task{TaskArn: aws.String("task1")},
+	}
+
+	svc.EXPECT().
+		ListTasks(&ecs.ListTasksInput{
+			Cluster:   aws.String("cluster0"),
+			NextToken: nil,
+		}).
+		Return(&ecs.ListTasksOutput{
+			TaskArns:  []*string{aws.String("task0")},
+			NextToken: aws.String("a"),
+		}, nil)
+
+	svc.EXPECT().
+		DescribeTasks(&ecs.DescribeTasksInput{
+			Cluster: aws.String("cluster0"),
+			Tasks:   []*string{aws.String("task0")},
+		}).
+		Return(&ecs.DescribeTasksOutput{
+			Tasks: []*ecs.Task{&ecs.Task{TaskArn: aws.String("task0")}},
+		}, nil)
+
+	svc.EXPECT().
+		ListTasks(&ecs.ListTasksInput{
+			Cluster:   aws.String("cluster0"),
+			NextToken: aws.String("a"),
+		}).
+		Return(&ecs.ListTasksOutput{
+			TaskArns:  []*string{aws.String("task1")},
+			NextToken: nil,
+		}, nil)
+
+	svc.EXPECT().
+		DescribeTasks(&ecs.DescribeTasksInput{
+			Cluster: aws.String("cluster0"),
+			Tasks:   []*string{aws.String("task1")},
+		}).
+		Return(&ecs.DescribeTasksOutput{
+			Tasks: []*ecs.Task{&ecs.Task{TaskArn: aws.String("task1")}},
+		}, nil)
+
+	// a cluster with no tasks is never described
+	svc.EXPECT().
+		ListTasks(&ecs.ListTasksInput{
+			Cluster:   aws.String("cluster1"),
+			NextToken: nil,
+		}).
+		Return(&ecs.ListTasksOutput{}, nil)
+
+	result, err := e.CollectTasks([]string{"cluster0", "cluster1"})
+	if err != nil {
+		t.Error(err)
+	}
+
+	if equal := reflect.DeepEqual(expected, result); !equal {
+		t.Errorf("Expected %v, got %v\n", expected, result)
+	}
+}
+
+func Test_CollectScheduleReferences(t *testing.T) {
+	e, fake := setupFake()
+	e.Events = fake.Events()
+
+	arns := fake.AddTaskDefinitions("cron", 3)
+	fake.AddSchedule("cluster0", "nightly", "cron:1")
+	fake.AddSchedule("cluster0", "hourly", "cron")
+	fake.AddSchedule("cluster0", "retired", "gone")
+	fake.Throttle("ListRules", 1)
+	fake.Throttle("ListTargetsByRule", 1)
+
+	// a family on its own runs its latest ACTIVE revision; a family with none runs nothing
+	expected := []inventory.Reference{
+		{TaskDefinitionARN: arns[0], Source: "events-rule", ID: "nightly"},
+		{TaskDefinitionARN: arns[2], Source: "events-rule", ID: "hourly"},
+	}
+
+	result, err := e.CollectScheduleReferences(arns)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if equal := reflect.DeepEqual(expected, result); !equal {
+		t.Errorf("Expected %v, got %v\n", expected, result)
+	}
+
+	fake.InjectFault("ListTargetsByRule", awserr.New("AccessDeniedException", "denied", nil), 1)
+
+	if result, err := e.CollectScheduleReferences(arns); err == nil {
+		t.Errorf("Expected an error, got %v\n", result)
+	}
+}
+
+func Test_CollectTasks_Errors(t *testing.T) {
+	e, fake := setupFake()
+
+	cluster := fake.AddCluster("cluster0")
+	arn := fake.AddTaskDefinitions("family0", 1)[0]
+	fake.AddTask("cluster0", arn, "")
+
+	// a throttled page is retried, but one that can't be described stops the collection
+	fake.Throttle("ListTasks", 2)
+	fake.InjectFault("DescribeTasks", awserr.New(ecs.ErrCodeClientException, "denied", nil), 1)
+
+	result, err := e.CollectTasks([]string{cluster})
+	if err == nil {
+		t.Errorf("Expected an error, got %v\n", result)
+	}
+
+	if result != nil {
+		t.Errorf("Expected no tasks, got %v\n", result)
+	}
+}
+
+// deployingECS is a fake ECS account in which a deploy starts using `deployed` as soon as
+// the first task definition is deregistered.
+type deployingECS struct {
+	*ecsfake.ECS
+	deployed string
+}
+
+func (d *deployingECS) DeregisterTaskDefinition(input *ecs.DeregisterTaskDefinitionInput) (*ecs.DeregisterTaskDefinitionOutput, error) {
+	if d.deployed != "" {
+		d.AddService("cluster0", "deployed", d.deployed, 1)
+		d.deployed = ""
+	}
+
+	return d.ECS.DeregisterTaskDefinition(input)
+}
+
+func Test_DeregisterTaskDefinitions_Revalidate(t *testing.T) {
+	e, fake := setupFake()
+	e.Flags.RevalidateEvery = 1
+
+	arns := fake.AddTaskDefinitions("family0", 4)
+	e.Svc = &deployingECS{ECS: fake, deployed: arns[0]}
+
+	// the stack deregisters the last ARN first, after which arns[0] comes into use
+	if err := e.DeregisterTaskDefinitions(arns[:3]); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := fake.TaskDefinitionARNs("INACTIVE"); !reflect.DeepEqual(arns[1:3], result) {
+		t.Errorf("Expected %v deregistered, got %v\n", arns[1:3], result)
+	}
+}
+
+func Test_DeregisterTaskDefinitions_RevalidateFirst(t *testing.T) {
+	e, fake := setupFake()
+	e.Flags.RevalidateEvery = 100
+
+	// a deploy since planning uses arns[2], the first ARN the stack would deregister
+	arns := fake.AddTaskDefinitions("family0", 3)
+	fake.AddService("cluster0", "deployed", arns[2], 1)
+
+	if err := e.DeregisterTaskDefinitions(arns); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := fake.TaskDefinitionARNs("INACTIVE"); !reflect.DeepEqual(arns[:2], result) {
+		t.Errorf("Expected %v deregistered, got %v\n", arns[:2], result)
+	}
+}
+
+// cancelWriter cancels its context on the first write.
+type cancelWriter struct {
+	cancel context.CancelFunc
+}
+
+func (w cancelWriter) Write(p []byte) (int, error) {
+	w.cancel()
+	return len(p), nil
+}
+
+func Test_DeregisterTaskDefinitions_Context(t *testing.T) {
+	e, fake := setupFake()
+
+	// shutdown is asked for once the first deregistration is logged
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	e.Context = ctx
+	e.Audit = audit.New(audit.WriterSink{Writer: cancelWriter{cancel}}, audit.Identity{Account: "000000000000"}, "us-east-1")
+
+	arns := fake.AddTaskDefinitions("family0", 3)
+
+	if err := e.DeregisterTaskDefinitions(arns); err == nil {
+		t.Error("Expected an error")
+	}
+
+	if result := fake.TaskDefinitionARNs("INACTIVE"); !reflect.DeepEqual(arns[2:], result) {
+		t.Errorf("Expected %v deregistered, got %v\n", arns[2:], result)
+	}
+}
+
+func Test_retry_Context(t *testing.T) {
+	e, fake := setupFake()
+	e.Backoff = &backoff.Backoff{Min: time.Hour, Max: time.Hour}
+
+	// shutdown is asked for while waiting out throttling
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	e.Context = ctx
+	fake.Throttle("ListClusters", 1)
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	var calls int
+	listClusters := func() error {
+		calls++
+		_, err := e.Svc.ListClusters(&ecs.ListClustersInput{})
+		return err
+	}
+
+	result := make(chan error, 1)
+	go func() { result <- e.retry(listClusters) }()
+
+	select {
+	case err := <-result:
+		if err != context.Canceled {
+			t.Errorf("Expected %v, got %v\n", context.Canceled, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the backoff cut short")
+	}
+
+	if calls != 1 {
+		t.Errorf("Expected 1 call, got %d\n", calls)
+	}
+}
+
+func Test_DeregisterTaskDefinitions_RevalidateError(t *testing.T) {
+	e, fake := setupFake()
+	e.Flags.RevalidateEvery = 100
+
+	arns := fake.AddTaskDefinitions("family0", 2)
+	fake.AddCluster("cluster0")
+	fake.InjectFault("ListServices", awserr.New(ecs.ErrCodeClientException, "denied", nil), 1)
+
+	if err := e.DeregisterTaskDefinitions(arns); err == nil {
+		t.Error("Expected an error")
+	}
+
+	if result := fake.TaskDefinitionARNs("INACTIVE"); len(result) > 0 {
+		t.Errorf("Expected nothing deregistered, got %v\n", result)
+	}
+}
+
+func Test_DeregisterTaskDefinitions_Archive(t *testing.T) {
+	e, fake := setupFake()
+
+	dir, err := ioutil.TempDir("", "ecsclient-archive")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	e.Archive = archive.New(archive.DirStore(dir))
+	arns := fake.AddTaskDefinitions("family0", 2)
+
+	// the stack deregisters the last ARN first; the first can't be archived, so it's kept
+	fake.InjectFault("DescribeTaskDefinition", nil, 1)
+	fake.InjectFault("DescribeTaskDefinition", awserr.New(ecs.ErrCodeClientException, "denied", nil), 1)
+
+	if err := e.DeregisterTaskDefinitions(arns); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := fake.TaskDefinitionARNs("INACTIVE"); !reflect.DeepEqual(arns[1:], result) {
+		t.Errorf("Expected %v deregistered, got %v\n", arns[1:], result)
+	}
+
+	body, err := archive.DirStore(dir).Get("000000000000/us-east-1/family0/2.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := archive.Decode(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result := aws.StringValue(output.TaskDefinition.TaskDefinitionArn); result != arns[1] {
+		t.Errorf("Expected the archive to hold %s, got %s\n", arns[1], result)
+	}
+
+	if _, err := archive.DirStore(dir).Get("000000000000/us-east-1/family0/1.json"); err == nil {
+		t.Errorf("Expected nothing archived for %s\n", arns[0])
+	}
+}
+
+func Test_DeregisterTaskDefinitions_Audit(t *testing.T) {
+	e, fake := setupFake()
+
+	var buf bytes.Buffer
+	e.Audit = audit.New(audit.WriterSink{Writer: &buf}, audit.Identity{Account: "000000000000"}, "us-east-1")
+	arns := fake.AddTaskDefinitions("family0", 2)
+
+	// the stack deregisters the last ARN first
+	fake.InjectFault("DeregisterTaskDefinition", awserr.New(ecs.ErrCodeClientException, "denied", nil), 1)
+
+	if err := e.DeregisterTaskDefinitions(arns); err != nil {
+		t.Fatal(err)
+	}
+
+	var result []string
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var event audit.Event
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatal(err)
+		}
+
+		result = append(result, fmt.Sprintf("%s %s %s", event.Action, event.ARN, event.Detail))
+	}
+
+	expected := []string{
+		fmt.Sprintf("failure %s ClientException: denied", arns[1]),
+		fmt.Sprintf("deregister %s ", arns[0]),
+	}
+
+	if !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, got %v\n", expected, result)
+	}
+}
+
+func Test_RestoreTaskDefinitions(t *testing.T) {
+	e, fake := setupFake()
+
+	dir, err := ioutil.TempDir("", "ecsclient-restore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	e.Archive = archive.New(archive.DirStore(dir))
+	arns := fake.AddTaskDefinitions("family0", 2)
+
+	if err := e.DeregisterTaskDefinitions(arns); err != nil {
+		t.Fatal(err)
+	}
+
+	// newest first, to check the restore registers revisions in their original order
+	var outputs []*ecs.DescribeTaskDefinitionOutput
+	for i := len(arns) - 1; i >= 0; i-- {
+		output, err := archive.Load(archive.DirStore(dir), arns[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		outputs = append(outputs, output)
+	}
+
+	restorations, err := e.RestoreTaskDefinitions(outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result := fake.TaskDefinitionARNs("ACTIVE"); len(result) != 0 {
+		t.Errorf("Expected a dry run to register nothing, got %v\n", result)
+	}
+
+	expected := []Restoration{{OldARN: arns[0]}, {OldARN: arns[1]}}
+	if !reflect.DeepEqual(expected, restorations) {
+		t.Errorf("Expected %v, got %v\n", expected, restorations)
+	}
+
+	e.Flags.Apply = true
+
+	restorations, err = e.RestoreTaskDefinitions(outputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newARNs := []string{
+		"arn:aws:ecs:us-east-1:000000000000:task-definition/family0:3",
+		"arn:aws:ecs:us-east-1:000000000000:task-definition/family0:4",
+	}
+
+	expected = []Restoration{{OldARN: arns[0], NewARN: newARNs[0]}, {OldARN: arns[1], NewARN: newARNs[1]}}
+	if !reflect.DeepEqual(expected, restorations) {
+		t.Errorf("Expected %v, got %v\n", expected, restorations)
+	}
+
+	if result := fake.TaskDefinitionARNs("ACTIVE"); !reflect.DeepEqual(newARNs, result) {
+		t.Errorf("Expected %v registered, got %v\n", newARNs, result)
+	}
+}
+
 func Test_DeregisterTaskDefinitions_SunnyDay(t *testing.T) {
 	ctrl, e, svc := setup(t)
 	defer ctrl.Finish()
@@ -293,94 +1871,53 @@ func Test_DescribeServices(t *testing.T) {
 }
 
 func Test_FilterTaskDefinitions(t *testing.T) {
-	ctrl, e, svc := setup(t)
+	ctrl, e, _ := setup(t)
 	defer ctrl.Finish()
 
 	e.Flags.Cutoff = 2
 
-	allARNs := []string{
+	arn := func(familyRevision string) string {
+		return "arn:aws:ecs:us-west-2:000000000000:task-definition/" + familyRevision
+	}
+
+	// FilterTaskDefinitions makes no AWS calls, so the mock expects none.
+	inv := inventory.New("us-west-2")
+	inv.TaskDefinitionARNs = []string{
 		// service in use, cutoff=2;
-		// should filter out aws-blather:family0:3, aws-blather:family0:2, aws-blather:family0:1
-		"aws-blather:family0:0", "aws-blather:family0:1", "aws-blather:family0:2", "aws-blather:family0:3",
+		// should filter out family0:4, family0:3, family0:2
+		arn("family0:1"), arn("family0:2"), arn("family0:3"), arn("family0:4"),
 
 		// service in use, cutoff=2;
 		// should filter out all three of these
-		"aws-blather:family1:0", "aws-blather:family1:1", "aws-blather:family1:2",
+		arn("family1:1"), arn("family1:2"), arn("family1:3"),
 
 		// service in use, cutoff=2; should filter out both of these
-		"aws-blather:family2:0", "aws-blather:family2:1",
+		arn("family2:1"), arn("family2:2"),
 
 		// service in use, cutoff=2; should filter out this one
-		"aws-blather:family3:0",
+		arn("family3:1"),
 
 		// service not in use; should filter out neither of these
-		"aws-blather:family4:0", "aws-blather:family4:1",
-	}
+		arn("family4:1"), arn("family4:2"),
 
-	services := []ecs.Service{
-		ecs.Service{TaskDefinition: aws.String("aws-blather:family0:3")},
-		ecs.Service{TaskDefinition: aws.String("aws-blather:family1:2")},
-		ecs.Service{TaskDefinition: aws.String("aws-blather:family2:1")},
-		ecs.Service{TaskDefinition: aws.String("aws-blather:family3:0")},
+		// task in use, but no service; should filter out only the running revision
+		arn("family5:1"), arn("family5:2"),
 	}
 
-	expected := []string{"aws-blather:family0:0", "aws-blather:family4:0", "aws-blather:family4:1"}
-
-	svc.EXPECT().
-		ListTaskDefinitions(&ecs.ListTaskDefinitionsInput{
-			FamilyPrefix: aws.String("family0"),
-			Sort:         aws.String("DESC"),
-		}).
-		Return(&ecs.ListTaskDefinitionsOutput{
-			TaskDefinitionArns: []*string{
-				aws.String("aws-blather:family0:3"),
-				aws.String("aws-blather:family0:2"),
-				aws.String("aws-blather:family0:1"),
-				aws.String("aws-blather:family0:0"),
-			},
-			NextToken: nil,
-		}, nil)
-
-	svc.EXPECT().
-		ListTaskDefinitions(&ecs.ListTaskDefinitionsInput{
-			FamilyPrefix: aws.String("family1"),
-			Sort:         aws.String("DESC"),
-		}).
-		Return(&ecs.ListTaskDefinitionsOutput{
-			TaskDefinitionArns: []*string{
-				aws.String("aws-blather:family1:2"),
-				aws.String("aws-blather:family1:1"),
-				aws.String("aws-blather:family1:0"),
-			},
-			NextToken: nil,
-		}, nil)
+	inv.Services = []ecs.Service{
+		ecs.Service{TaskDefinition: aws.String(arn("family0:4"))},
+		ecs.Service{TaskDefinition: aws.String(arn("family1:3"))},
+		ecs.Service{TaskDefinition: aws.String(arn("family2:2"))},
+		ecs.Service{TaskDefinition: aws.String(arn("family3:1"))},
+	}
 
-	svc.EXPECT().
-		ListTaskDefinitions(&ecs.ListTaskDefinitionsInput{
-			FamilyPrefix: aws.String("family2"),
-			Sort:         aws.String("DESC"),
-		}).
-		Return(&ecs.ListTaskDefinitionsOutput{
-			TaskDefinitionArns: []*string{
-				aws.String("aws-blather:family2:1"),
-				aws.String("aws-blather:family2:0"),
-			},
-			NextToken: nil,
-		}, nil)
+	inv.Tasks = []ecs.Task{
+		ecs.Task{TaskDefinitionArn: aws.String(arn("family5:1"))},
+	}
 
-	svc.EXPECT().
-		ListTaskDefinitions(&ecs.ListTaskDefinitionsInput{
-			FamilyPrefix: aws.String("family3"),
-			Sort:         aws.String("DESC"),
-		}).
-		Return(&ecs.ListTaskDefinitionsOutput{
-			TaskDefinitionArns: []*string{
-				aws.String("aws-blather:family3:0"),
-			},
-			NextToken: nil,
-		}, nil)
+	expected := []string{arn("family0:1"), arn("family4:1"), arn("family4:2"), arn("family5:2")}
 
-	result, err := e.FilterTaskDefinitions(allARNs, services)
+	result, err := e.FilterTaskDefinitions(inv)
 	if err != nil {
 		t.Error(err)
 	}
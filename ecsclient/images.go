@@ -0,0 +1,461 @@
+package ecsclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecr"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// batchImagesChunkSize is the most images BatchGetImage and BatchDeleteImage take at once.
+const batchImagesChunkSize = 100
+
+// ecrRegistryHost matches the host of an ECR registry, capturing its account ID.
+var ecrRegistryHost = regexp.MustCompile(`^(\d{12})\.dkr\.ecr(-fips)?\.[a-z0-9-]+\.amazonaws\.com(\.cn)?$`)
+
+// indexMediaTypes are the media types of manifests that list other manifests, e.g. one per
+// platform of a multi-architecture image.
+var indexMediaTypes = map[string]bool{
+	"application/vnd.docker.distribution.manifest.list.v2+json": true,
+	"application/vnd.oci.image.index.v1+json":                   true,
+}
+
+// ImageReferences is the set of ECR images that task definitions and tasks refer to, keyed as
+// "registry/repository:tag" and "registry/repository@digest".
+type ImageReferences map[string]bool
+
+// Add records the image, as given in a container definition, and the digest a running
+// container resolved it to, if known. Images outside ECR are ignored; an image without a tag
+// or digest refers to "latest".
+func (r ImageReferences) Add(image, digest string) {
+	slash := strings.Index(image, "/")
+	if slash < 0 {
+		return
+	}
+
+	match := ecrRegistryHost.FindStringSubmatch(image[:slash])
+	if match == nil {
+		return
+	}
+
+	repository, imageDigest := image[slash+1:], ""
+	if at := strings.Index(repository, "@"); at >= 0 {
+		repository, imageDigest = repository[:at], repository[at+1:]
+	}
+
+	tag := "latest"
+	if colon := strings.LastIndex(repository, ":"); colon >= 0 {
+		repository, tag = repository[:colon], repository[colon+1:]
+	} else if imageDigest != "" {
+		tag = ""
+	}
+
+	key := match[1] + "/" + repository
+	if tag != "" {
+		r[key+":"+tag] = true
+	}
+
+	for _, d := range []string{imageDigest, digest} {
+		if d != "" {
+			r[key+"@"+d] = true
+		}
+	}
+}
+
+// Referenced reports whether the image is referred to by its digest or one of its tags.
+func (r ImageReferences) Referenced(registryID, repository string, image *ecr.ImageDetail) bool {
+	key := registryID + "/" + repository
+	if r[key+"@"+aws.StringValue(image.ImageDigest)] {
+		return true
+	}
+
+	for _, tag := range image.ImageTags {
+		if r[key+":"+aws.StringValue(tag)] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// UnreferencedImage is an ECR image that no ACTIVE task definition or running task refers to.
+type UnreferencedImage struct {
+	RegistryID string
+	Repository string
+	Digest     string
+	Tags       []string
+	PushedAt   time.Time
+}
+
+// CollectImageReferences gathers the images used by the containers of every ACTIVE task
+// definition and every running task.
+func (e *ECSClient) CollectImageReferences() (ImageReferences, error) {
+	defer e.startSpan("CollectImageReferences")()
+
+	taskDefinitionARNs, err := e.CollectTaskDefinitions()
+	if err != nil {
+		return nil, err
+	}
+
+	containers, err := e.CollectContainerDefinitions(taskDefinitionARNs)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make(ImageReferences)
+	for _, container := range containers {
+		refs.Add(aws.StringValue(container.Image), "")
+	}
+
+	clusterARNs, err := e.CollectClusters()
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := e.CollectTasks(clusterARNs)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, task := range tasks {
+		for _, container := range task.Containers {
+			refs.Add(aws.StringValue(container.Image), aws.StringValue(container.ImageDigest))
+		}
+	}
+
+	return refs, nil
+}
+
+// CollectRepositories gathers the ECR repositories whose names match one of the
+// `ImageRepositories` patterns.
+func (e *ECSClient) CollectRepositories() ([]*ecr.Repository, error) {
+	defer e.startSpan("CollectRepositories")()
+
+	var repositories []*ecr.Repository
+	var nextToken *string
+
+	for {
+		var output *ecr.DescribeRepositoriesOutput
+		err := e.retry(func() error {
+			var err error
+			output, err = e.ECR.DescribeRepositories(&ecr.DescribeRepositoriesInput{NextToken: nextToken})
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, repository := range output.Repositories {
+			name := aws.StringValue(repository.RepositoryName)
+			for _, pattern := range e.Flags.ImageRepositories {
+				if ok, _ := path.Match(pattern, name); ok {
+					repositories = append(repositories, repository)
+					break
+				}
+			}
+		}
+
+		if nextToken = output.NextToken; nextToken == nil {
+			return repositories, nil
+		}
+	}
+}
+
+// UnreferencedImages returns the repository's images that no task definition or task refers
+// to and were pushed more than `ImageAgeDays` ago, leaving out its `KeepImages` most recently
+// pushed tagged or multi-architecture images; the untagged platform images a
+// multi-architecture image lists don't count towards them. The platform images listed by a
+// multi-architecture image that's kept are kept too.
+func (e *ECSClient) UnreferencedImages(repository *ecr.Repository, refs ImageReferences) ([]UnreferencedImage, error) {
+	registryID := aws.StringValue(repository.RegistryId)
+	name := aws.StringValue(repository.RepositoryName)
+
+	images, err := e.describeImages(registryID, name)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(images, func(i, j int) bool {
+		return aws.TimeValue(images[i].ImagePushedAt).After(aws.TimeValue(images[j].ImagePushedAt))
+	})
+
+	cutoff := e.Now().AddDate(0, 0, -e.Flags.ImageAgeDays)
+	kept := make(map[string]bool)
+	var keptIndexes []string
+	var numTopLevel int
+
+	for _, image := range images {
+		digest := aws.StringValue(image.ImageDigest)
+		isIndex := indexMediaTypes[aws.StringValue(image.ImageManifestMediaType)]
+
+		var isLatest bool
+		if len(image.ImageTags) > 0 || isIndex {
+			isLatest = numTopLevel < e.Flags.KeepImages
+			numTopLevel++
+		}
+
+		if isLatest || aws.TimeValue(image.ImagePushedAt).After(cutoff) || refs.Referenced(registryID, name, image) {
+			kept[digest] = true
+
+			if isIndex {
+				keptIndexes = append(keptIndexes, digest)
+			}
+		}
+	}
+
+	children, err := e.indexedDigests(registryID, name, keptIndexes)
+	if err != nil {
+		return nil, err
+	}
+
+	var unreferenced []UnreferencedImage
+
+	for _, image := range images {
+		digest := aws.StringValue(image.ImageDigest)
+		if kept[digest] || children[digest] {
+			continue
+		}
+
+		unreferenced = append(unreferenced, UnreferencedImage{
+			RegistryID: registryID,
+			Repository: name,
+			Digest:     digest,
+			Tags:       aws.StringValueSlice(image.ImageTags),
+			PushedAt:   aws.TimeValue(image.ImagePushedAt),
+		})
+	}
+
+	return unreferenced, nil
+}
+
+// CleanupImages deletes ECR images, in repositories matching `ImageRepositories`, that no
+// ACTIVE task definition or running task refers to. A dry run only reports them.
+func (e *ECSClient) CleanupImages() (err error) {
+	endSpan := e.startSpan("CleanupImages", attribute.Bool("apply", e.Flags.Apply))
+	defer func() {
+		e.recordSpanError(err)
+		endSpan()
+	}()
+
+	refs, err := e.CollectImageReferences()
+	if err != nil {
+		return err
+	}
+
+	repositories, err := e.CollectRepositories()
+	if err != nil {
+		return err
+	}
+
+	var unreferenced []UnreferencedImage
+
+	for _, repository := range repositories {
+		images, err := e.UnreferencedImages(repository, refs)
+
+		switch {
+		case err == nil:
+			unreferenced = append(unreferenced, images...)
+
+		case e.isStopworthyError(err):
+			return err
+
+		default:
+			if !e.Flags.Quiet {
+				fmt.Printf("Error checking %s: %v\n", aws.StringValue(repository.RepositoryName), err)
+			}
+		}
+	}
+
+	if !e.Flags.Quiet {
+		fmt.Printf("Found %d unreferenced images pushed %d days ago or more in %d repositories.\n", len(unreferenced), e.Flags.ImageAgeDays, len(repositories))
+		for _, image := range unreferenced {
+			fmt.Printf("%s@%s %v (pushed %s)\n", image.Repository, image.Digest, image.Tags, image.PushedAt.Format(time.RFC3339))
+		}
+	}
+
+	if len(unreferenced) == 0 {
+		return nil
+	}
+
+	if !e.Flags.Apply {
+		if !e.Flags.Quiet {
+			fmt.Println("This is a dry run.")
+			fmt.Println("Use the `--apply` flag to delete these images.")
+		}
+
+		return nil
+	}
+
+	if err := e.DeleteImages(unreferenced); err != nil {
+		return err
+	}
+
+	if !e.Flags.Quiet {
+		fmt.Println("Process finished.")
+	}
+
+	return nil
+}
+
+// DeleteImages deletes the images by digest, with all their tags, backing off when throttled.
+// An image that can't be deleted is reported and left alone.
+func (e *ECSClient) DeleteImages(images []UnreferencedImage) error {
+	defer e.startSpan("DeleteImages", attribute.Int("count", len(images)))()
+
+	var deleted, failed int
+
+	for start := 0; start < len(images); {
+		end := start + 1
+		for end < len(images) && end-start < batchImagesChunkSize &&
+			images[end].RegistryID == images[start].RegistryID && images[end].Repository == images[start].Repository {
+			end++
+		}
+
+		var imageIDs []*ecr.ImageIdentifier
+		for _, image := range images[start:end] {
+			imageIDs = append(imageIDs, &ecr.ImageIdentifier{ImageDigest: aws.String(image.Digest)})
+		}
+
+		var output *ecr.BatchDeleteImageOutput
+		err := e.retry(func() error {
+			var err error
+			output, err = e.ECR.BatchDeleteImage(&ecr.BatchDeleteImageInput{
+				ImageIds:       imageIDs,
+				RegistryId:     aws.String(images[start].RegistryID),
+				RepositoryName: aws.String(images[start].Repository),
+			})
+
+			return err
+		})
+
+		switch {
+		case err == nil:
+			deleted += len(imageIDs) - len(output.Failures)
+			failed += len(output.Failures)
+
+			if !e.Flags.Quiet {
+				for _, failure := range output.Failures {
+					fmt.Printf("Error deleting %s@%s: %s\n", images[start].Repository, aws.StringValue(failure.ImageId.ImageDigest), aws.StringValue(failure.FailureReason))
+				}
+			}
+
+		case e.isStopworthyError(err):
+			return err
+
+		default:
+			failed += len(imageIDs)
+
+			if !e.Flags.Quiet {
+				fmt.Printf("Error deleting images from %s: %v\n", images[start].Repository, err)
+			}
+		}
+
+		start = end
+	}
+
+	if !e.Flags.Quiet {
+		fmt.Printf("%d deleted images, %d errored\n", deleted, failed)
+	}
+
+	return nil
+}
+
+// describeImages lists every image in the repository, backing off when throttled.
+func (e *ECSClient) describeImages(registryID, repository string) ([]*ecr.ImageDetail, error) {
+	var images []*ecr.ImageDetail
+	var nextToken *string
+
+	for {
+		var output *ecr.DescribeImagesOutput
+		err := e.retry(func() error {
+			var err error
+			output, err = e.ECR.DescribeImages(&ecr.DescribeImagesInput{
+				NextToken:      nextToken,
+				RegistryId:     aws.String(registryID),
+				RepositoryName: aws.String(repository),
+			})
+
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		images = append(images, output.ImageDetails...)
+
+		if nextToken = output.NextToken; nextToken == nil {
+			return images, nil
+		}
+	}
+}
+
+// indexedDigests returns the digests of the manifests that the given multi-architecture
+// images list.
+func (e *ECSClient) indexedDigests(registryID, repository string, digests []string) (map[string]bool, error) {
+	children := make(map[string]bool)
+
+	var mediaTypes []string
+	for mediaType := range indexMediaTypes {
+		mediaTypes = append(mediaTypes, mediaType)
+	}
+
+	sort.Strings(mediaTypes)
+
+	for start := 0; start < len(digests); start += batchImagesChunkSize {
+		end := start + batchImagesChunkSize
+		if end > len(digests) {
+			end = len(digests)
+		}
+
+		var imageIDs []*ecr.ImageIdentifier
+		for _, digest := range digests[start:end] {
+			imageIDs = append(imageIDs, &ecr.ImageIdentifier{ImageDigest: aws.String(digest)})
+		}
+
+		var output *ecr.BatchGetImageOutput
+		err := e.retry(func() error {
+			var err error
+			output, err = e.ECR.BatchGetImage(&ecr.BatchGetImageInput{
+				AcceptedMediaTypes: aws.StringSlice(mediaTypes),
+				ImageIds:           imageIDs,
+				RegistryId:         aws.String(registryID),
+				RepositoryName:     aws.String(repository),
+			})
+
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if len(output.Failures) > 0 {
+			failure := output.Failures[0]
+			return nil, fmt.Errorf("unable to get %s@%s: %s", repository, aws.StringValue(failure.ImageId.ImageDigest), aws.StringValue(failure.FailureReason))
+		}
+
+		for _, image := range output.Images {
+			var index struct {
+				Manifests []struct {
+					Digest string `json:"digest"`
+				} `json:"manifests"`
+			}
+
+			if err := json.Unmarshal([]byte(aws.StringValue(image.ImageManifest)), &index); err != nil {
+				return nil, fmt.Errorf("unable to parse the manifest of %s@%s: %v", repository, aws.StringValue(image.ImageId.ImageDigest), err)
+			}
+
+			for _, manifest := range index.Manifests {
+				children[manifest.Digest] = true
+			}
+		}
+	}
+
+	return children, nil
+}
@@ -5,9 +5,19 @@ import "github.com/aws/aws-sdk-go/service/ecs"
 // ECSSvc defines the methods that an object must have in order to be used as the `Svc`
 // object in the ECSClient. The AWS `ecs.ECS` object satisfies this interface.
 type ECSSvc interface {
+	DeleteCluster(*ecs.DeleteClusterInput) (*ecs.DeleteClusterOutput, error)
+	DeleteService(*ecs.DeleteServiceInput) (*ecs.DeleteServiceOutput, error)
+	DescribeClusters(*ecs.DescribeClustersInput) (*ecs.DescribeClustersOutput, error)
 	DescribeServices(*ecs.DescribeServicesInput) (*ecs.DescribeServicesOutput, error)
+	DescribeTaskDefinition(*ecs.DescribeTaskDefinitionInput) (*ecs.DescribeTaskDefinitionOutput, error)
+	DescribeTasks(*ecs.DescribeTasksInput) (*ecs.DescribeTasksOutput, error)
 	DeregisterTaskDefinition(*ecs.DeregisterTaskDefinitionInput) (*ecs.DeregisterTaskDefinitionOutput, error)
 	ListClusters(*ecs.ListClustersInput) (*ecs.ListClustersOutput, error)
 	ListServices(*ecs.ListServicesInput) (*ecs.ListServicesOutput, error)
+	ListTagsForResource(*ecs.ListTagsForResourceInput) (*ecs.ListTagsForResourceOutput, error)
 	ListTaskDefinitions(*ecs.ListTaskDefinitionsInput) (*ecs.ListTaskDefinitionsOutput, error)
+	ListTasks(*ecs.ListTasksInput) (*ecs.ListTasksOutput, error)
+	RegisterTaskDefinition(*ecs.RegisterTaskDefinitionInput) (*ecs.RegisterTaskDefinitionOutput, error)
+	TagResource(*ecs.TagResourceInput) (*ecs.TagResourceOutput, error)
+	UntagResource(*ecs.UntagResourceInput) (*ecs.UntagResourceOutput, error)
 }
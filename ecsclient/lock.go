@@ -0,0 +1,34 @@
+package ecsclient
+
+import (
+	"fmt"
+)
+
+// acquireLock takes the ECSClient's Lock, if it has one.
+func (e *ECSClient) acquireLock() error {
+	if e.Lock == nil {
+		return nil
+	}
+
+	if err := e.Lock.Acquire(); err != nil {
+		return err
+	}
+
+	if e.Flags.Verbose {
+		fmt.Printf("Acquired the lock on %s as %s.\n", e.Lock.Key, e.Lock.Holder)
+	}
+
+	return nil
+}
+
+// releaseLock gives up the ECSClient's Lock, if it has one. A failure to is only printed, as
+// the lease runs out on its own.
+func (e *ECSClient) releaseLock() {
+	if e.Lock == nil {
+		return
+	}
+
+	if err := e.Lock.Release(); err != nil {
+		fmt.Printf("Unable to release the lock on %s: %v\n", e.Lock.Key, err)
+	}
+}
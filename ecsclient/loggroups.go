@@ -0,0 +1,298 @@
+package ecsclient
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// awslogsGroupOption is the `awslogs` log driver option naming the log group a container
+// logs to.
+const awslogsGroupOption = "awslogs-group"
+
+// OrphanedLogGroup is a log group under `LogGroupPrefix` that no task definition logs to, and
+// that has had no events for `IdleDays`.
+type OrphanedLogGroup struct {
+	Name            string
+	LastActivity    time.Time
+	RetentionInDays int64
+}
+
+// CollectLogGroupReferences gathers the log groups that the containers of every ACTIVE task
+// definition, and of the task definitions of running tasks, send their logs to with the
+// `awslogs` driver.
+func (e *ECSClient) CollectLogGroupReferences() (map[string]bool, error) {
+	defer e.startSpan("CollectLogGroupReferences")()
+
+	taskDefinitionARNs, err := e.CollectTaskDefinitions()
+	if err != nil {
+		return nil, err
+	}
+
+	clusterARNs, err := e.CollectClusters()
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := e.CollectTasks(clusterARNs)
+	if err != nil {
+		return nil, err
+	}
+
+	// a running task's task definition may have been deregistered, but it still logs
+	seen := make(map[string]bool)
+	for _, arn := range taskDefinitionARNs {
+		seen[arn] = true
+	}
+
+	for _, task := range tasks {
+		if arn := aws.StringValue(task.TaskDefinitionArn); arn != "" && !seen[arn] {
+			seen[arn] = true
+			taskDefinitionARNs = append(taskDefinitionARNs, arn)
+		}
+	}
+
+	containers, err := e.CollectContainerDefinitions(taskDefinitionARNs)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make(map[string]bool)
+	for _, container := range containers {
+		config := container.LogConfiguration
+		if config == nil || aws.StringValue(config.LogDriver) != ecs.LogDriverAwslogs {
+			continue
+		}
+
+		if group := aws.StringValue(config.Options[awslogsGroupOption]); group != "" {
+			refs[group] = true
+		}
+	}
+
+	return refs, nil
+}
+
+// CollectLogGroups gathers the log groups whose names start with `LogGroupPrefix`.
+func (e *ECSClient) CollectLogGroups() ([]*cloudwatchlogs.LogGroup, error) {
+	defer e.startSpan("CollectLogGroups")()
+
+	var logGroups []*cloudwatchlogs.LogGroup
+	var nextToken *string
+
+	for {
+		var output *cloudwatchlogs.DescribeLogGroupsOutput
+		err := e.retry(func() error {
+			var err error
+			output, err = e.Logs.DescribeLogGroups(&cloudwatchlogs.DescribeLogGroupsInput{
+				LogGroupNamePrefix: aws.String(e.Flags.LogGroupPrefix),
+				NextToken:          nextToken,
+			})
+
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		logGroups = append(logGroups, output.LogGroups...)
+
+		if nextToken = output.NextToken; nextToken == nil {
+			return logGroups, nil
+		}
+	}
+}
+
+// OrphanedLogGroups returns the log groups that aren't referenced and have had no events, or
+// been created, in the last `IdleDays`. With `LogRetentionDays`, those already set to keep
+// events that long, or less, are left out, so their retention is never raised.
+func (e *ECSClient) OrphanedLogGroups(logGroups []*cloudwatchlogs.LogGroup, refs map[string]bool) ([]OrphanedLogGroup, error) {
+	cutoff := e.Now().AddDate(0, 0, -e.Flags.IdleDays)
+	var orphaned []OrphanedLogGroup
+
+	for _, logGroup := range logGroups {
+		name := aws.StringValue(logGroup.LogGroupName)
+		retention := aws.Int64Value(logGroup.RetentionInDays)
+
+		if refs[name] || (e.Flags.LogRetentionDays > 0 && retention > 0 && retention <= int64(e.Flags.LogRetentionDays)) {
+			continue
+		}
+
+		last, err := e.lastLogEvent(logGroup)
+		if err != nil {
+			return nil, err
+		}
+
+		if last.After(cutoff) {
+			if e.Flags.Verbose {
+				fmt.Printf("%s is unreferenced, but last logged at %s.\n", name, last.Format(time.RFC3339))
+			}
+
+			continue
+		}
+
+		orphaned = append(orphaned, OrphanedLogGroup{Name: name, LastActivity: last, RetentionInDays: retention})
+	}
+
+	sort.Slice(orphaned, func(i, j int) bool { return orphaned[i].Name < orphaned[j].Name })
+	return orphaned, nil
+}
+
+// CleanupLogGroups deletes the log groups under `LogGroupPrefix` that no task definition logs
+// to once they've been idle for `IdleDays`, or, with `LogRetentionDays`, sets them to keep
+// events for that long instead. A dry run only reports them.
+func (e *ECSClient) CleanupLogGroups() (err error) {
+	endSpan := e.startSpan("CleanupLogGroups", attribute.Bool("apply", e.Flags.Apply))
+	defer func() {
+		e.recordSpanError(err)
+		endSpan()
+	}()
+
+	refs, err := e.CollectLogGroupReferences()
+	if err != nil {
+		return err
+	}
+
+	logGroups, err := e.CollectLogGroups()
+	if err != nil {
+		return err
+	}
+
+	orphaned, err := e.OrphanedLogGroups(logGroups, refs)
+	if err != nil {
+		return err
+	}
+
+	if !e.Flags.Quiet {
+		fmt.Printf("Found %d unreferenced log groups under %q idle for %d days or more.\n", len(orphaned), e.Flags.LogGroupPrefix, e.Flags.IdleDays)
+		for _, logGroup := range orphaned {
+			fmt.Printf("%s (last active %s)\n", logGroup.Name, logGroup.LastActivity.Format(time.RFC3339))
+		}
+	}
+
+	if len(orphaned) == 0 {
+		return nil
+	}
+
+	if !e.Flags.Apply {
+		if !e.Flags.Quiet {
+			fmt.Println("This is a dry run.")
+
+			if e.Flags.LogRetentionDays > 0 {
+				fmt.Printf("Use the `--apply` flag to set these log groups to keep events for %d days.\n", e.Flags.LogRetentionDays)
+			} else {
+				fmt.Println("Use the `--apply` flag to delete these log groups.")
+			}
+		}
+
+		return nil
+	}
+
+	if e.Flags.LogRetentionDays > 0 {
+		err = e.SetLogGroupRetention(orphaned)
+	} else {
+		err = e.DeleteLogGroups(orphaned)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if !e.Flags.Quiet {
+		fmt.Println("Process finished.")
+	}
+
+	return nil
+}
+
+// DeleteLogGroups deletes the log groups, with all their events, backing off when throttled.
+// A log group that can't be deleted is reported and left alone.
+func (e *ECSClient) DeleteLogGroups(logGroups []OrphanedLogGroup) error {
+	defer e.startSpan("DeleteLogGroups", attribute.Int("count", len(logGroups)))()
+
+	return e.eachLogGroup(logGroups, "deleting", "deleted", func(name string) error {
+		_, err := e.Logs.DeleteLogGroup(&cloudwatchlogs.DeleteLogGroupInput{LogGroupName: aws.String(name)})
+		return err
+	})
+}
+
+// SetLogGroupRetention sets the log groups to expire events after `LogRetentionDays`,
+// backing off when throttled. A log group whose retention can't be set is reported and left
+// alone.
+func (e *ECSClient) SetLogGroupRetention(logGroups []OrphanedLogGroup) error {
+	defer e.startSpan("SetLogGroupRetention", attribute.Int("count", len(logGroups)))()
+
+	return e.eachLogGroup(logGroups, "setting the retention of", "updated", func(name string) error {
+		_, err := e.Logs.PutRetentionPolicy(&cloudwatchlogs.PutRetentionPolicyInput{
+			LogGroupName:    aws.String(name),
+			RetentionInDays: aws.Int64(int64(e.Flags.LogRetentionDays)),
+		})
+
+		return err
+	})
+}
+
+// eachLogGroup calls fn with each log group's name, with retries, counting the log groups it
+// succeeds and fails for; `doing` and `done` describe fn in what it prints.
+func (e *ECSClient) eachLogGroup(logGroups []OrphanedLogGroup, doing, done string, fn func(name string) error) error {
+	var succeeded, failed int
+
+	for _, logGroup := range logGroups {
+		err := e.retry(func() error { return fn(logGroup.Name) })
+
+		switch {
+		case err == nil:
+			succeeded++
+
+		case e.isStopworthyError(err):
+			return err
+
+		default:
+			failed++
+
+			if !e.Flags.Quiet {
+				fmt.Printf("Error %s %s: %v\n", doing, logGroup.Name, err)
+			}
+		}
+	}
+
+	if !e.Flags.Quiet {
+		fmt.Printf("%d %s log groups, %d errored\n", succeeded, done, failed)
+	}
+
+	return nil
+}
+
+// lastLogEvent returns when the log group last received an event, or when it was created if
+// it never has.
+func (e *ECSClient) lastLogEvent(logGroup *cloudwatchlogs.LogGroup) (time.Time, error) {
+	last := aws.Int64Value(logGroup.CreationTime)
+
+	var output *cloudwatchlogs.DescribeLogStreamsOutput
+	err := e.retry(func() error {
+		var err error
+		output, err = e.Logs.DescribeLogStreams(&cloudwatchlogs.DescribeLogStreamsInput{
+			Descending:   aws.Bool(true),
+			Limit:        aws.Int64(1),
+			LogGroupName: logGroup.LogGroupName,
+			OrderBy:      aws.String(cloudwatchlogs.OrderByLastEventTime),
+		})
+
+		return err
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, stream := range output.LogStreams {
+		if t := aws.Int64Value(stream.LastEventTimestamp); t > last {
+			last = t
+		}
+	}
+
+	return aws.MillisecondsTimeValue(&last).UTC(), nil
+}
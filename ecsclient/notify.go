@@ -0,0 +1,81 @@
+package ecsclient
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/quintilesims/go-ecs-cleaner/notify"
+	"github.com/quintilesims/go-ecs-cleaner/planner"
+)
+
+// startSummary begins the Summary of a run.
+func (e *ECSClient) startSummary(start time.Time) {
+	e.summary = &notify.Summary{Apply: e.Flags.Apply, StartedAt: start}
+	if e.Session != nil {
+		e.summary.Region = aws.StringValue(e.Session.Config.Region)
+	}
+}
+
+// summarizePlan records what the plan found, and the families of the task definitions it
+// would deregister, in the Summary, if there is one.
+func (e *ECSClient) summarizePlan(discovered int, plan *planner.Plan, taskDefinitionARNs []string) {
+	if e.summary == nil {
+		return
+	}
+
+	e.summary.Counts.Discovered = discovered
+	e.summary.Counts.Kept = len(plan.ARNs(planner.Keep))
+	e.summary.Counts.Pending = len(plan.ARNs(planner.Pending))
+	e.summary.Counts.Planned = len(taskDefinitionARNs)
+	e.summary.SetFamilies(taskDefinitionARNs)
+}
+
+// summarizeDeregistrations records what DeregisterTaskDefinitions did in the Summary, if
+// there is one.
+func (e *ECSClient) summarizeDeregistrations(deregistered int, failed []FailedDeregistration, skipped []string) {
+	if e.summary == nil {
+		return
+	}
+
+	e.summary.Counts.Deregistered = deregistered
+	e.summary.Counts.Failed = len(failed)
+	e.summary.Counts.Skipped = len(skipped)
+
+	failures := make([]notify.Failure, len(failed))
+	for i, f := range failed {
+		failures[i] = notify.Failure{ARN: f.Arn, Error: f.Err.Error()}
+	}
+
+	e.summary.SetFailures(failures)
+}
+
+// sendSummary finishes the Summary of a run that ended in err and sends it to the Notifier,
+// if there is one. Failing to notify doesn't fail the run; it's only printed.
+func (e *ECSClient) sendSummary(err error) {
+	s := e.summary
+	s.FinishedAt = time.Now().UTC()
+	s.Result = notify.Succeeded
+
+	if err != nil {
+		s.Result, s.Error = notify.Failed, err.Error()
+
+		if _, ok := err.(*planner.LimitError); ok {
+			s.LimitTripped = err.Error()
+		}
+	}
+
+	if e.Notifier == nil {
+		return
+	}
+
+	if err := e.Notifier.Notify(*s); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// Summary returns the Summary of the last CleanupTaskDefinitions run, or nil if there hasn't
+// been one.
+func (e *ECSClient) Summary() *notify.Summary {
+	return e.summary
+}
@@ -0,0 +1,121 @@
+package ecsclient
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/quintilesims/go-ecs-cleaner/inventory"
+	"github.com/quintilesims/go-ecs-cleaner/planner"
+)
+
+// scheduleSource is the inventory.Reference source of task definitions that an EventBridge
+// rule runs.
+const scheduleSource = "events-rule"
+
+// CollectScheduleReferences gathers the task definitions that the rules on the default event
+// bus run as ECS tasks. A target naming a family without a revision runs the family's latest
+// ACTIVE revision, which is looked up in `taskDefinitionARNs`. A page of rules or targets that
+// can't be listed, even after retries, stops the collection with an error.
+func (e *ECSClient) CollectScheduleReferences(taskDefinitionARNs []string) ([]inventory.Reference, error) {
+	defer e.startSpan("CollectScheduleReferences")()
+
+	if !e.Flags.Quiet {
+		fmt.Println("Collecting scheduled tasks...")
+	}
+
+	latest := make(map[string]planner.TaskDefinitionARN)
+	for _, arn := range taskDefinitionARNs {
+		parsed, err := planner.ParseTaskDefinitionARN(arn)
+		if err != nil {
+			continue
+		}
+
+		if parsed.Revision > latest[parsed.Family].Revision {
+			latest[parsed.Family] = parsed
+		}
+	}
+
+	var references []inventory.Reference
+	var nextToken *string
+
+	for {
+		var output *eventbridge.ListRulesOutput
+		err := e.retry(func() error {
+			var err error
+			output, err = e.Events.ListRules(&eventbridge.ListRulesInput{NextToken: nextToken})
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to list rules: %v", err)
+		}
+
+		for _, rule := range output.Rules {
+			targets, err := e.listTargetsByRule(aws.StringValue(rule.Name))
+			if err != nil {
+				return nil, fmt.Errorf("unable to list the targets of rule %s: %v", aws.StringValue(rule.Name), err)
+			}
+
+			for _, target := range targets {
+				if target.EcsParameters == nil {
+					continue
+				}
+
+				arn := aws.StringValue(target.EcsParameters.TaskDefinitionArn)
+				if family := arn[strings.LastIndex(arn, "/")+1:]; !strings.Contains(family, ":") {
+					parsed, ok := latest[family]
+					if !ok {
+						continue
+					}
+
+					arn = parsed.String()
+				}
+
+				references = append(references, inventory.Reference{
+					TaskDefinitionARN: arn,
+					Source:            scheduleSource,
+					ID:                aws.StringValue(rule.Name),
+				})
+			}
+		}
+
+		if nextToken = output.NextToken; nextToken == nil {
+			break
+		}
+	}
+
+	if !e.Flags.Quiet {
+		fmt.Printf("(found %d)\n", len(references))
+	}
+
+	return references, nil
+}
+
+// listTargetsByRule lists all the targets of a rule, backing off when throttled.
+func (e *ECSClient) listTargetsByRule(rule string) ([]*eventbridge.Target, error) {
+	var targets []*eventbridge.Target
+	var nextToken *string
+
+	for {
+		var output *eventbridge.ListTargetsByRuleOutput
+		err := e.retry(func() error {
+			var err error
+			output, err = e.Events.ListTargetsByRule(&eventbridge.ListTargetsByRuleInput{
+				NextToken: nextToken,
+				Rule:      aws.String(rule),
+			})
+
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		targets = append(targets, output.Targets...)
+
+		if nextToken = output.NextToken; nextToken == nil {
+			return targets, nil
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package ecsclient
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// IdleService is a service scaled to zero that has done nothing for `IdleDays`.
+type IdleService struct {
+	ARN          string
+	ClusterARN   string
+	LastActivity time.Time
+}
+
+// LastActivity returns when the service last did anything: the latest of its creation, its
+// deployments' creation and last update, and its events. Steady-state events don't count,
+// since ECS keeps reporting them for services that do nothing.
+func LastActivity(service ecs.Service) time.Time {
+	last := aws.TimeValue(service.CreatedAt)
+	latest := func(t *time.Time) {
+		if t != nil && t.After(last) {
+			last = *t
+		}
+	}
+
+	for _, deployment := range service.Deployments {
+		latest(deployment.CreatedAt)
+		latest(deployment.UpdatedAt)
+	}
+
+	for _, event := range service.Events {
+		if !strings.Contains(aws.StringValue(event.Message), "has reached a steady state") {
+			latest(event.CreatedAt)
+		}
+	}
+
+	return last
+}
+
+// matchesServiceFilters reports whether a service, as "cluster/service", matches one of the
+// `IncludeServices` patterns, if there are any, and none of the `ExcludeServices`.
+func (e *ECSClient) matchesServiceFilters(name string) bool {
+	matchesAny := func(patterns []string) bool {
+		for _, pattern := range patterns {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if len(e.Flags.IncludeServices) > 0 && !matchesAny(e.Flags.IncludeServices) {
+		return false
+	}
+
+	return !matchesAny(e.Flags.ExcludeServices)
+}
+
+// IdleServices returns the described services that are scaled to zero, with nothing running
+// or pending, and have been idle for `IdleDays`, that match the service filters and aren't
+// protected by one of the `ProtectTags`.
+func (e *ECSClient) IdleServices(services []ecs.Service) ([]IdleService, error) {
+	cutoff := e.Now().AddDate(0, 0, -e.Flags.IdleDays)
+	var idle []IdleService
+
+	for _, service := range services {
+		if aws.Int64Value(service.DesiredCount) > 0 || aws.Int64Value(service.RunningCount) > 0 || aws.Int64Value(service.PendingCount) > 0 {
+			continue
+		}
+
+		clusterARN := aws.StringValue(service.ClusterArn)
+		name := clusterARN[strings.LastIndex(clusterARN, "/")+1:] + "/" + aws.StringValue(service.ServiceName)
+		if !e.matchesServiceFilters(name) {
+			continue
+		}
+
+		last := LastActivity(service)
+		if last.After(cutoff) {
+			continue
+		}
+
+		arn := aws.StringValue(service.ServiceArn)
+		protected, err := e.isProtectedService(arn)
+		if err != nil {
+			return nil, err
+		}
+
+		if protected {
+			if e.Flags.Verbose {
+				fmt.Printf("%s is protected by its tags.\n", arn)
+			}
+
+			continue
+		}
+
+		idle = append(idle, IdleService{ARN: arn, ClusterARN: clusterARN, LastActivity: last})
+	}
+
+	sort.Slice(idle, func(i, j int) bool { return idle[i].ARN < idle[j].ARN })
+	return idle, nil
+}
+
+// isProtectedService reports whether the service carries one of the `ProtectTags`.
+func (e *ECSClient) isProtectedService(arn string) (bool, error) {
+	if len(e.Flags.ProtectTags) == 0 {
+		return false, nil
+	}
+
+	var tags map[string]string
+	err := e.retry(func() error {
+		var err error
+		tags, err = e.listTagsForResource(arn)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for key, protectValue := range e.Flags.ProtectTags {
+		if value, ok := tags[key]; ok && (protectValue == "" || protectValue == value) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// CleanupServices deletes services that are scaled to zero and have been idle for
+// `IdleDays`. A dry run only reports them.
+func (e *ECSClient) CleanupServices() (err error) {
+	endSpan := e.startSpan("CleanupServices", attribute.Bool("apply", e.Flags.Apply))
+	defer func() {
+		e.recordSpanError(err)
+		endSpan()
+	}()
+
+	clusterARNs, err := e.CollectClusters()
+	if err != nil {
+		return err
+	}
+
+	serviceARNsByClusterARN, err := e.CollectServices(clusterARNs)
+	if err != nil {
+		return err
+	}
+
+	services, err := e.DescribeServices(serviceARNsByClusterARN)
+	if err != nil {
+		return err
+	}
+
+	idle, err := e.IdleServices(services)
+	if err != nil {
+		return err
+	}
+
+	if !e.Flags.Quiet {
+		fmt.Printf("Found %d services idle for %d days or more.\n", len(idle), e.Flags.IdleDays)
+		for _, service := range idle {
+			fmt.Printf("%s (last active %s)\n", service.ARN, service.LastActivity.Format(time.RFC3339))
+		}
+	}
+
+	if len(idle) == 0 {
+		return nil
+	}
+
+	if !e.Flags.Apply {
+		if !e.Flags.Quiet {
+			fmt.Println("This is a dry run.")
+			fmt.Println("Use the `--apply` flag to delete these services.")
+		}
+
+		return nil
+	}
+
+	if err := e.DeleteServices(idle); err != nil {
+		return err
+	}
+
+	if !e.Flags.Quiet {
+		fmt.Println("Process finished.")
+	}
+
+	return nil
+}
+
+// DeleteServices force-deletes the services, backing off when throttled. A service that
+// can't be deleted is reported and left alone.
+func (e *ECSClient) DeleteServices(services []IdleService) error {
+	defer e.startSpan("DeleteServices", attribute.Int("count", len(services)))()
+
+	var deleted, failed int
+
+	for _, service := range services {
+		err := e.retry(func() error {
+			_, err := e.Svc.DeleteService(&ecs.DeleteServiceInput{
+				Cluster: aws.String(service.ClusterARN),
+				Service: aws.String(service.ARN),
+				Force:   aws.Bool(true),
+			})
+
+			return err
+		})
+
+		switch {
+		case err == nil:
+			deleted++
+
+		case e.isStopworthyError(err):
+			return err
+
+		default:
+			failed++
+
+			if !e.Flags.Quiet {
+				fmt.Printf("Error deleting %s: %v\n", service.ARN, err)
+			}
+		}
+	}
+
+	if !e.Flags.Quiet {
+		fmt.Printf("%d deleted services, %d errored\n", deleted, failed)
+	}
+
+	return nil
+}
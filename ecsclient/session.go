@@ -0,0 +1,123 @@
+package ecsclient
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// newSession builds an AWS session from the ECSClient's Flags. With no session flags set,
+// this is equivalent to `session.NewSession(&aws.Config{})`.
+func (e *ECSClient) newSession() (*session.Session, error) {
+	config := aws.Config{}
+
+	if e.Flags.EndpointURL != "" || len(e.Flags.ServiceEndpoints) > 0 {
+		resolver, err := e.endpointResolver()
+		if err != nil {
+			return nil, err
+		}
+
+		config.EndpointResolver = resolver
+	}
+
+	// the SDK loads AWS_CA_BUNDLE into the session's HTTP client too, which would otherwise be
+	// http.DefaultClient, shared by every session, including those being created concurrently
+	if e.Flags.NoVerifySSL || e.Flags.CABundle != "" || os.Getenv("AWS_CA_BUNDLE") != "" {
+		config.HTTPClient = &http.Client{Transport: newTransport(e.Flags.NoVerifySSL)}
+	}
+
+	opts := session.Options{Config: config}
+
+	if e.Flags.CABundle != "" {
+		f, err := os.Open(e.Flags.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open CA bundle: %v", err)
+		}
+		defer f.Close()
+
+		opts.CustomCABundle = f
+	}
+
+	return session.NewSessionWithOptions(opts)
+}
+
+// endpointResolver returns a resolver that sends requests for a service to its entry in
+// `ServiceEndpoints` (keyed by endpoint ID, e.g. "ecs"), then to `EndpointURL`, and
+// otherwise to the service's default AWS endpoint.
+func (e *ECSClient) endpointResolver() (endpoints.Resolver, error) {
+	overrides := make(map[string]string)
+
+	for service, endpoint := range e.Flags.ServiceEndpoints {
+		if err := validateEndpointURL(endpoint); err != nil {
+			return nil, fmt.Errorf("invalid endpoint for %s: %v", service, err)
+		}
+
+		overrides[service] = endpoint
+	}
+
+	if e.Flags.EndpointURL != "" {
+		if err := validateEndpointURL(e.Flags.EndpointURL); err != nil {
+			return nil, fmt.Errorf("invalid endpoint URL: %v", err)
+		}
+	}
+
+	resolve := func(service, region string, opts ...func(*endpoints.Options)) (endpoints.ResolvedEndpoint, error) {
+		endpoint, ok := overrides[service]
+		if !ok {
+			endpoint = e.Flags.EndpointURL
+		}
+
+		if endpoint == "" {
+			return endpoints.DefaultResolver().EndpointFor(service, region, opts...)
+		}
+
+		return endpoints.ResolvedEndpoint{
+			URL:           endpoint,
+			SigningRegion: region,
+		}, nil
+	}
+
+	return endpoints.ResolverFunc(resolve), nil
+}
+
+// newTransport mirrors the settings of `http.DefaultTransport`. It's used in place of the
+// default so that a CA bundle or `--no-verify-ssl` can be applied without modifying the
+// transport shared by the rest of the process.
+func newTransport(insecureSkipVerify bool) *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: insecureSkipVerify,
+		},
+	}
+}
+
+// validateEndpointURL checks that an endpoint override is an absolute URL.
+func validateEndpointURL(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("%q must include a scheme and host, e.g. http://localhost:4566", endpoint)
+	}
+
+	return nil
+}
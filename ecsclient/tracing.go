@@ -0,0 +1,76 @@
+package ecsclient
+
+import (
+	"context"
+	"time"
+
+	"github.com/quintilesims/go-ecs-cleaner/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// startSpan starts a span, if the ECSClient has a Tracer, as a child of the current one, and
+// makes it current until the function returned ends it.
+func (e *ECSClient) startSpan(name string, attrs ...attribute.KeyValue) func() {
+	if e.Tracer == nil {
+		return func() {}
+	}
+
+	parent := e.spanContext()
+	ctx, span := e.Tracer.Start(parent, name, trace.WithAttributes(attrs...))
+	e.ctx = ctx
+
+	return func() {
+		span.End()
+		e.ctx = parent
+	}
+}
+
+// spanContext returns a context holding the current span; ConfigureSession makes API calls
+// its children.
+func (e *ECSClient) spanContext() context.Context {
+	if e.ctx == nil {
+		return context.Background()
+	}
+
+	return e.ctx
+}
+
+// recordSpanError marks the current span as failed, if err is set.
+func (e *ECSClient) recordSpanError(err error) {
+	if err == nil || e.ctx == nil {
+		return
+	}
+
+	span := trace.SpanFromContext(e.ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// sleepBackoff waits out throttling for t, recording the wait in the Metrics, and as a span
+// with the attrs. It stops waiting early once the ECSClient's Context is done.
+func (e *ECSClient) sleepBackoff(t time.Duration, attrs ...attribute.KeyValue) {
+	e.Metrics.ObserveBackoff(t)
+
+	attrs = append(attrs,
+		tracing.BackoffAttempt.Int(int(e.Backoff.Attempt())),
+		tracing.BackoffDuration.Int64(int64(t/time.Millisecond)),
+	)
+
+	end := e.startSpan("Backoff", attrs...)
+	defer end()
+
+	if e.Context == nil {
+		time.Sleep(t)
+		return
+	}
+
+	timer := time.NewTimer(t)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-e.Context.Done():
+	}
+}
@@ -1,5 +1,14 @@
 package ecsclient
 
+import (
+	"fmt"
+	"sort"
+
+	"github.com/quintilesims/go-ecs-cleaner/audit"
+	"github.com/quintilesims/go-ecs-cleaner/inventory"
+	"github.com/quintilesims/go-ecs-cleaner/planner"
+)
+
 // FailedDeregistration is a struct that couples a task definition deregistration
 // error to the ARN of the task definition.
 type FailedDeregistration struct {
@@ -7,28 +16,106 @@ type FailedDeregistration struct {
 	Err error
 }
 
-// Given two lists of strings, ensures that list B contains none of the items in
-// list A. If an A-item is in list B, it is removed from list B. If an A-item is
-// not in list B, it is not added to list B. The remaining list B items are returned.
-func removeAFromB(a, b []string) []string {
-	var diff []string
-	m := make(map[string]int)
+// retry calls `fn` until it succeeds or fails with something other than a throttling or
+// expired token error. Throttling errors are waited out according to the ECSClient's Backoff;
+// expired tokens get a new session. Once the ECSClient's Context is done, it stops with the
+// Context's error instead of calling `fn` again.
+func (e *ECSClient) retry(fn func() error) error {
+	for {
+		if e.Context != nil && e.Context.Err() != nil {
+			return e.Context.Err()
+		}
+
+		err := fn()
+
+		switch {
+		case err == nil:
+			e.Backoff.Reset()
+			return nil
+
+		case e.isThrottlingError(err):
+			t := e.Backoff.Duration()
+
+			if e.Flags.Verbose {
+				fmt.Printf("Backoff triggered, waiting for %v\n", t)
+
+				if e.Flags.Debug {
+					fmt.Printf("Triggering error: %v\n", err)
+				}
+			}
+
+			e.sleepBackoff(t)
+
+		case e.isExpiredTokenError(err):
+			if e.Flags.Verbose {
+				fmt.Println("Token expired, creating new session.")
+			}
+
+			if err := e.ConfigureSession(); err != nil {
+				return err
+			}
+
+		default:
+			return err
+		}
+	}
+}
 
-	for _, item := range b {
-		m[item] = 1
+// orphanTaskDefinitionARNs returns the ARNs of the task definitions in families that no
+// service uses: every revision if `allRevisions` is set, otherwise only each family's latest.
+func orphanTaskDefinitionARNs(inv *inventory.Inventory, allRevisions bool) []string {
+	orphans := make(map[string]bool)
+	for _, family := range planner.OrphanFamilies(inv) {
+		orphans[family] = true
 	}
 
-	for _, item := range a {
-		if m[item] != 0 {
-			m[item]++
+	latest := make(map[string]planner.TaskDefinitionARN)
+	var arns []string
+
+	for _, arn := range inv.TaskDefinitionARNs {
+		parsed, err := planner.ParseTaskDefinitionARN(arn)
+		if err != nil || !orphans[parsed.Family] {
+			continue
+		}
+
+		if allRevisions {
+			arns = append(arns, parsed.String())
+		} else if parsed.Revision > latest[parsed.Family].Revision {
+			latest[parsed.Family] = parsed
 		}
 	}
 
-	for k, v := range m {
-		if v == 1 {
-			diff = append(diff, k)
+	for _, parsed := range latest {
+		arns = append(arns, parsed.String())
+	}
+
+	sort.Strings(arns)
+	return arns
+}
+
+// reviewTaskDefinitions passes the ARNs to the ECSClient's Review, and returns the ones it
+// selects. Those it leaves out are logged as skipped.
+func (e *ECSClient) reviewTaskDefinitions(taskDefinitionARNs []string) ([]string, error) {
+	selected, err := e.Review(taskDefinitionARNs)
+	if err != nil {
+		return nil, err
+	}
+
+	keep := make(map[string]bool)
+	for _, arn := range selected {
+		keep[arn] = true
+	}
+
+	for _, arn := range taskDefinitionARNs {
+		if keep[arn] {
+			continue
+		}
+
+		e.requestID = ""
+		if err := e.auditLog(audit.Skip, arn, "deselected in review"); err != nil {
+			return nil, err
 		}
 	}
 
-	return diff
+	return selected, nil
 }
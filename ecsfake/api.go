@@ -0,0 +1,613 @@
+package ecsfake
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// DeregisterTaskDefinition marks a task definition INACTIVE.
+func (f *ECS) DeregisterTaskDefinition(input *ecs.DeregisterTaskDefinitionInput) (*ecs.DeregisterTaskDefinitionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.call("DeregisterTaskDefinition"); err != nil {
+		return nil, err
+	}
+
+	td := f.findTaskDefinition(aws.StringValue(input.TaskDefinition))
+	if td == nil || !strings.Contains(aws.StringValue(input.TaskDefinition), ":") {
+		return nil, clientException("Unable to describe task definition.")
+	}
+
+	if *td.definition.Status == ecs.TaskDefinitionStatusInactive {
+		return nil, clientException("The specified task definition is already inactive.")
+	}
+
+	td.definition.Status = aws.String(ecs.TaskDefinitionStatusInactive)
+	return &ecs.DeregisterTaskDefinitionOutput{TaskDefinition: copyTaskDefinition(td.definition)}, nil
+}
+
+// DeleteCluster deletes a cluster, unless it still has services, tasks or container
+// instances.
+func (f *ECS) DeleteCluster(input *ecs.DeleteClusterInput) (*ecs.DeleteClusterOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.call("DeleteCluster"); err != nil {
+		return nil, err
+	}
+
+	c := f.findCluster(aws.StringValue(input.Cluster))
+	switch {
+	case c == nil:
+		return nil, clusterNotFound()
+
+	case len(c.services) > 0:
+		return nil, awserr.New(ecs.ErrCodeClusterContainsServicesException, "The Cluster cannot be deleted while Services are active.", nil)
+
+	case len(c.tasks) > 0:
+		return nil, awserr.New(ecs.ErrCodeClusterContainsTasksException, "The Cluster cannot be deleted while Tasks are active.", nil)
+
+	case c.containerInstances > 0:
+		return nil, awserr.New(ecs.ErrCodeClusterContainsContainerInstancesException, "The Cluster cannot be deleted while Container Instances are active or draining.", nil)
+	}
+
+	described := f.describeCluster(c, false)
+	described.Status = aws.String("INACTIVE")
+
+	for i := range f.clusters {
+		if f.clusters[i] == c {
+			f.clusters = append(f.clusters[:i], f.clusters[i+1:]...)
+			break
+		}
+	}
+
+	return &ecs.DeleteClusterOutput{Cluster: described}, nil
+}
+
+// DescribeClusters describes up to 100 clusters, given by name or ARN, with their tags if
+// `include` has TAGS. Clusters that don't exist are reported as failures.
+func (f *ECS) DescribeClusters(input *ecs.DescribeClustersInput) (*ecs.DescribeClustersOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.call("DescribeClusters"); err != nil {
+		return nil, err
+	}
+
+	if len(input.Clusters) > 100 {
+		return nil, invalidParameter("clusters can have at most 100 items.")
+	}
+
+	includeTags := false
+	for _, field := range aws.StringValueSlice(input.Include) {
+		includeTags = includeTags || field == ecs.ClusterFieldTags
+	}
+
+	output := &ecs.DescribeClustersOutput{}
+	for _, ref := range aws.StringValueSlice(input.Clusters) {
+		c := f.findCluster(ref)
+		if c == nil {
+			output.Failures = append(output.Failures, &ecs.Failure{Arn: aws.String(ref), Reason: aws.String("MISSING")})
+			continue
+		}
+
+		output.Clusters = append(output.Clusters, f.describeCluster(c, includeTags))
+	}
+
+	return output, nil
+}
+
+// describeCluster reports a cluster as DescribeClusters does, counting its services and its
+// tasks by status.
+func (f *ECS) describeCluster(c *cluster, includeTags bool) *ecs.Cluster {
+	var running, pending int64
+	for _, task := range c.tasks {
+		switch aws.StringValue(task.LastStatus) {
+		case "RUNNING":
+			running++
+		case "PENDING", "PROVISIONING", "ACTIVATING":
+			pending++
+		}
+	}
+
+	described := &ecs.Cluster{
+		ActiveServicesCount:               aws.Int64(int64(len(c.services))),
+		CapacityProviders:                 aws.StringSlice(c.capacityProviders),
+		ClusterArn:                        aws.String(c.arn),
+		ClusterName:                       aws.String(c.name),
+		PendingTasksCount:                 aws.Int64(pending),
+		RegisteredContainerInstancesCount: aws.Int64(c.containerInstances),
+		RunningTasksCount:                 aws.Int64(running),
+		Status:                            aws.String("ACTIVE"),
+	}
+
+	if includeTags {
+		described.Tags = copyTags(c.tags)
+	}
+
+	return described
+}
+
+// DeleteService deletes a service, which must be scaled to zero unless `force` is set.
+func (f *ECS) DeleteService(input *ecs.DeleteServiceInput) (*ecs.DeleteServiceOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.call("DeleteService"); err != nil {
+		return nil, err
+	}
+
+	c := f.findCluster(clusterName(input.Cluster))
+	if c == nil {
+		return nil, clusterNotFound()
+	}
+
+	ref := aws.StringValue(input.Service)
+	for i, service := range c.services {
+		if *service.ServiceName != ref && *service.ServiceArn != ref {
+			continue
+		}
+
+		if aws.Int64Value(service.DesiredCount) > 0 && !aws.BoolValue(input.Force) {
+			return nil, invalidParameter("The service cannot be stopped while it is scaled above 0.")
+		}
+
+		c.services = append(c.services[:i], c.services[i+1:]...)
+
+		deleted := *service
+		deleted.Status = aws.String("DRAINING")
+		return &ecs.DeleteServiceOutput{Service: &deleted}, nil
+	}
+
+	return nil, awserr.New(ecs.ErrCodeServiceNotFoundException, "Service not found.", nil)
+}
+
+// DescribeServices describes up to 10 services, given by name or ARN, in one cluster, with
+// their tags if `include` has TAGS.
+func (f *ECS) DescribeServices(input *ecs.DescribeServicesInput) (*ecs.DescribeServicesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.call("DescribeServices"); err != nil {
+		return nil, err
+	}
+
+	if len(input.Services) == 0 || len(input.Services) > 10 {
+		return nil, invalidParameter("Services must contain between 1 and 10 items.")
+	}
+
+	c := f.findCluster(clusterName(input.Cluster))
+	if c == nil {
+		return nil, clusterNotFound()
+	}
+
+	includeTags := false
+	for _, field := range aws.StringValueSlice(input.Include) {
+		includeTags = includeTags || field == ecs.ServiceFieldTags
+	}
+
+	output := &ecs.DescribeServicesOutput{}
+
+	for _, ref := range aws.StringValueSlice(input.Services) {
+		var found *ecs.Service
+		for _, service := range c.services {
+			if *service.ServiceName == ref || *service.ServiceArn == ref {
+				found = service
+			}
+		}
+
+		if found == nil {
+			output.Failures = append(output.Failures, &ecs.Failure{
+				Arn:    aws.String(ref),
+				Reason: aws.String("MISSING"),
+			})
+
+			continue
+		}
+
+		service := *found
+		service.Tags = nil
+		if includeTags {
+			service.Tags = copyTags(found.Tags)
+		}
+
+		output.Services = append(output.Services, &service)
+	}
+
+	return output, nil
+}
+
+// DescribeTaskDefinition describes a task definition given by ARN, "family:revision" or
+// family name, optionally including its tags.
+func (f *ECS) DescribeTaskDefinition(input *ecs.DescribeTaskDefinitionInput) (*ecs.DescribeTaskDefinitionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.call("DescribeTaskDefinition"); err != nil {
+		return nil, err
+	}
+
+	td := f.findTaskDefinition(aws.StringValue(input.TaskDefinition))
+	if td == nil {
+		return nil, clientException("Unable to describe task definition.")
+	}
+
+	output := &ecs.DescribeTaskDefinitionOutput{TaskDefinition: copyTaskDefinition(td.definition)}
+
+	for _, include := range aws.StringValueSlice(input.Include) {
+		if include == ecs.TaskDefinitionFieldTags {
+			output.Tags = copyTags(td.tags)
+		}
+	}
+
+	return output, nil
+}
+
+// DescribeTasks describes up to 100 tasks, given by ARN or ID, in one cluster.
+func (f *ECS) DescribeTasks(input *ecs.DescribeTasksInput) (*ecs.DescribeTasksOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.call("DescribeTasks"); err != nil {
+		return nil, err
+	}
+
+	if len(input.Tasks) == 0 || len(input.Tasks) > 100 {
+		return nil, invalidParameter("Tasks must contain between 1 and 100 items.")
+	}
+
+	c := f.findCluster(clusterName(input.Cluster))
+	if c == nil {
+		return nil, clusterNotFound()
+	}
+
+	output := &ecs.DescribeTasksOutput{}
+
+	for _, ref := range aws.StringValueSlice(input.Tasks) {
+		var found *ecs.Task
+		for _, task := range c.tasks {
+			if *task.TaskArn == ref || strings.HasSuffix(*task.TaskArn, "/"+ref) {
+				found = task
+			}
+		}
+
+		if found == nil {
+			output.Failures = append(output.Failures, &ecs.Failure{
+				Arn:    aws.String(ref),
+				Reason: aws.String("MISSING"),
+			})
+
+			continue
+		}
+
+		task := *found
+		output.Tasks = append(output.Tasks, &task)
+	}
+
+	return output, nil
+}
+
+// ListClusters lists cluster ARNs in the order the clusters were created.
+func (f *ECS) ListClusters(input *ecs.ListClustersInput) (*ecs.ListClustersOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.call("ListClusters"); err != nil {
+		return nil, err
+	}
+
+	var arns []string
+	for _, c := range f.clusters {
+		arns = append(arns, c.arn)
+	}
+
+	page, nextToken, err := paginate(arns, input.NextToken, input.MaxResults, DefaultClustersPageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ecs.ListClustersOutput{ClusterArns: aws.StringSlice(page), NextToken: nextToken}, nil
+}
+
+// ListServices lists the ARNs of the services in one cluster.
+func (f *ECS) ListServices(input *ecs.ListServicesInput) (*ecs.ListServicesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.call("ListServices"); err != nil {
+		return nil, err
+	}
+
+	c := f.findCluster(clusterName(input.Cluster))
+	if c == nil {
+		return nil, clusterNotFound()
+	}
+
+	var arns []string
+	for _, service := range c.services {
+		if input.LaunchType == nil || aws.StringValue(service.LaunchType) == *input.LaunchType {
+			arns = append(arns, *service.ServiceArn)
+		}
+	}
+
+	page, nextToken, err := paginate(arns, input.NextToken, input.MaxResults, DefaultServicesPageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ecs.ListServicesOutput{ServiceArns: aws.StringSlice(page), NextToken: nextToken}, nil
+}
+
+// ListTagsForResource lists the tags of a task definition, cluster or service.
+func (f *ECS) ListTagsForResource(input *ecs.ListTagsForResourceInput) (*ecs.ListTagsForResourceOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.call("ListTagsForResource"); err != nil {
+		return nil, err
+	}
+
+	tags, err := f.taggedResource(input.ResourceArn)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ecs.ListTagsForResourceOutput{Tags: copyTags(*tags)}, nil
+}
+
+// ListTaskDefinitions lists task definition ARNs sorted by family and revision. As in ECS,
+// `familyPrefix` must match a family name exactly and `status` defaults to ACTIVE.
+func (f *ECS) ListTaskDefinitions(input *ecs.ListTaskDefinitionsInput) (*ecs.ListTaskDefinitionsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.call("ListTaskDefinitions"); err != nil {
+		return nil, err
+	}
+
+	status := ecs.TaskDefinitionStatusActive
+	if input.Status != nil {
+		status = *input.Status
+	}
+
+	var arns []string
+	for _, td := range f.sortedTaskDefinitions() {
+		if *td.definition.Status != status {
+			continue
+		}
+
+		if input.FamilyPrefix != nil && *td.definition.Family != *input.FamilyPrefix {
+			continue
+		}
+
+		arns = append(arns, *td.definition.TaskDefinitionArn)
+	}
+
+	if aws.StringValue(input.Sort) == ecs.SortOrderDesc {
+		for i, j := 0, len(arns)-1; i < j; i, j = i+1, j-1 {
+			arns[i], arns[j] = arns[j], arns[i]
+		}
+	}
+
+	page, nextToken, err := paginate(arns, input.NextToken, input.MaxResults, DefaultTaskDefinitionsPageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ecs.ListTaskDefinitionsOutput{TaskDefinitionArns: aws.StringSlice(page), NextToken: nextToken}, nil
+}
+
+// ListTasks lists the ARNs of the tasks in one cluster, optionally filtered by family,
+// service (via the task's `startedBy`), `startedBy` and desired status.
+func (f *ECS) ListTasks(input *ecs.ListTasksInput) (*ecs.ListTasksOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.call("ListTasks"); err != nil {
+		return nil, err
+	}
+
+	c := f.findCluster(clusterName(input.Cluster))
+	if c == nil {
+		return nil, clusterNotFound()
+	}
+
+	desiredStatus := ecs.DesiredStatusRunning
+	if input.DesiredStatus != nil {
+		desiredStatus = *input.DesiredStatus
+	}
+
+	var arns []string
+	for _, task := range c.tasks {
+		if *task.DesiredStatus != desiredStatus {
+			continue
+		}
+
+		if input.Family != nil && !strings.Contains(*task.TaskDefinitionArn, "/"+*input.Family+":") {
+			continue
+		}
+
+		if input.StartedBy != nil && aws.StringValue(task.StartedBy) != *input.StartedBy {
+			continue
+		}
+
+		if input.ServiceName != nil && aws.StringValue(task.Group) != "service:"+*input.ServiceName {
+			continue
+		}
+
+		arns = append(arns, *task.TaskArn)
+	}
+
+	page, nextToken, err := paginate(arns, input.NextToken, input.MaxResults, DefaultTasksPageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ecs.ListTasksOutput{TaskArns: aws.StringSlice(page), NextToken: nextToken}, nil
+}
+
+// RegisterTaskDefinition registers the next revision of a family.
+func (f *ECS) RegisterTaskDefinition(input *ecs.RegisterTaskDefinitionInput) (*ecs.RegisterTaskDefinitionOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.call("RegisterTaskDefinition"); err != nil {
+		return nil, err
+	}
+
+	return f.registerTaskDefinition(input)
+}
+
+func (f *ECS) registerTaskDefinition(input *ecs.RegisterTaskDefinitionInput) (*ecs.RegisterTaskDefinitionOutput, error) {
+	family := aws.StringValue(input.Family)
+	if family == "" {
+		return nil, clientException("Family must be specified.")
+	}
+
+	var revision int64
+	for _, td := range f.taskDefinitions {
+		if *td.definition.Family == family && *td.definition.Revision > revision {
+			revision = *td.definition.Revision
+		}
+	}
+
+	revision++
+
+	definition := &ecs.TaskDefinition{
+		ContainerDefinitions:    input.ContainerDefinitions,
+		Cpu:                     input.Cpu,
+		EphemeralStorage:        input.EphemeralStorage,
+		ExecutionRoleArn:        input.ExecutionRoleArn,
+		Family:                  aws.String(family),
+		InferenceAccelerators:   input.InferenceAccelerators,
+		IpcMode:                 input.IpcMode,
+		Memory:                  input.Memory,
+		NetworkMode:             input.NetworkMode,
+		PidMode:                 input.PidMode,
+		PlacementConstraints:    input.PlacementConstraints,
+		ProxyConfiguration:      input.ProxyConfiguration,
+		RegisteredAt:            aws.Time(f.Now()),
+		RequiresCompatibilities: input.RequiresCompatibilities,
+		Revision:                aws.Int64(revision),
+		RuntimePlatform:         input.RuntimePlatform,
+		Status:                  aws.String(ecs.TaskDefinitionStatusActive),
+		TaskDefinitionArn:       aws.String(f.arn("task-definition", fmt.Sprintf("%s:%d", family, revision))),
+		TaskRoleArn:             input.TaskRoleArn,
+		Volumes:                 input.Volumes,
+	}
+
+	td := &taskDefinition{definition: definition, tags: copyTags(input.Tags)}
+	f.taskDefinitions = append(f.taskDefinitions, td)
+
+	return &ecs.RegisterTaskDefinitionOutput{
+		TaskDefinition: copyTaskDefinition(definition),
+		Tags:           copyTags(td.tags),
+	}, nil
+}
+
+// TagResource adds tags to a task definition, cluster or service, replacing the values of tags it already has.
+func (f *ECS) TagResource(input *ecs.TagResourceInput) (*ecs.TagResourceOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.call("TagResource"); err != nil {
+		return nil, err
+	}
+
+	tags, err := f.taggedResource(input.ResourceArn)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, tag := range input.Tags {
+		*tags = removeTag(*tags, aws.StringValue(tag.Key))
+		*tags = append(*tags, &ecs.Tag{Key: tag.Key, Value: tag.Value})
+	}
+
+	return &ecs.TagResourceOutput{}, nil
+}
+
+// UntagResource removes tags from a task definition, cluster or service. Keys it doesn't have are ignored.
+func (f *ECS) UntagResource(input *ecs.UntagResourceInput) (*ecs.UntagResourceOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := f.call("UntagResource"); err != nil {
+		return nil, err
+	}
+
+	tags, err := f.taggedResource(input.ResourceArn)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range aws.StringValueSlice(input.TagKeys) {
+		*tags = removeTag(*tags, key)
+	}
+
+	return &ecs.UntagResourceOutput{}, nil
+}
+
+// taggedResource looks up the tags of the task definition, cluster or service a tagging
+// request's `resourceArn` names.
+func (f *ECS) taggedResource(resourceARN *string) (*[]*ecs.Tag, error) {
+	arn := aws.StringValue(resourceARN)
+	for _, td := range f.taskDefinitions {
+		if *td.definition.TaskDefinitionArn == arn {
+			return &td.tags, nil
+		}
+	}
+
+	for _, c := range f.clusters {
+		if c.arn == arn {
+			return &c.tags, nil
+		}
+	}
+
+	if service := f.findService(arn); service != nil {
+		return &service.Tags, nil
+	}
+
+	return nil, invalidParameter("The specified resource could not be found.")
+}
+
+// clusterName resolves the `cluster` parameter of an ECS request, which defaults to the
+// cluster named "default".
+func clusterName(cluster *string) string {
+	if cluster == nil {
+		return "default"
+	}
+
+	return *cluster
+}
+
+func copyTaskDefinition(definition *ecs.TaskDefinition) *ecs.TaskDefinition {
+	c := *definition
+	return &c
+}
+
+func copyTags(tags []*ecs.Tag) []*ecs.Tag {
+	var c []*ecs.Tag
+	for _, tag := range tags {
+		t := *tag
+		c = append(c, &t)
+	}
+
+	return c
+}
+
+func removeTag(tags []*ecs.Tag, key string) []*ecs.Tag {
+	var kept []*ecs.Tag
+	for _, tag := range tags {
+		if aws.StringValue(tag.Key) != key {
+			kept = append(kept, tag)
+		}
+	}
+
+	return kept
+}
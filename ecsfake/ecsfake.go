@@ -0,0 +1,423 @@
+// Package ecsfake provides an in-memory ECS backend for tests. An `ECS` satisfies both the
+// `ecsclient.ECSSvc` interface and `ecsiface.ECSAPI`, keeps real cluster, service, task and
+// task definition state, paginates its list operations the way ECS does, and can be told to
+// fail or throttle specific operations.
+//
+// Operations of `ecsiface.ECSAPI` that the fake does not implement panic when called.
+package ecsfake
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+)
+
+// Default page sizes, matching the ECS API's defaults when `maxResults` is omitted.
+const (
+	DefaultClustersPageSize        = 100
+	DefaultServicesPageSize        = 10
+	DefaultTaskDefinitionsPageSize = 100
+	DefaultTasksPageSize           = 100
+)
+
+// ECS is an in-memory ECS account for a single region.
+type ECS struct {
+	ecsiface.ECSAPI
+
+	Account string
+	Region  string
+
+	// Now returns the time recorded on newly created resources. Defaults to the current UTC
+	// time.
+	Now func() time.Time
+
+	mu              sync.Mutex
+	calls           map[string]int
+	clusters        []*cluster
+	faults          []*fault
+	requestSequence int
+	schedules       []*schedule
+	taskDefinitions []*taskDefinition
+	taskSequence    int
+}
+
+type cluster struct {
+	arn                string
+	name               string
+	services           []*ecs.Service
+	tasks              []*ecs.Task
+	tags               []*ecs.Tag
+	containerInstances int64
+	capacityProviders  []string
+}
+
+type taskDefinition struct {
+	definition *ecs.TaskDefinition
+	tags       []*ecs.Tag
+}
+
+type fault struct {
+	operation string
+	err       error
+	remaining int
+}
+
+// New creates an empty fake ECS account.
+func New(account, region string) *ECS {
+	return &ECS{
+		Account: account,
+		Region:  region,
+		Now:     func() time.Time { return time.Now().UTC().Round(0) },
+		calls:   make(map[string]int),
+	}
+}
+
+// InjectFault makes the next `times` calls to `operation` (e.g. "DeregisterTaskDefinition")
+// fail with `err`. An empty operation matches every operation; `times` <= 0 fails every
+// matching call until `ClearFaults` is called. Faults are consumed in the order they were
+// injected.
+func (f *ECS) InjectFault(operation string, err error, times int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.faults = append(f.faults, &fault{operation: operation, err: err, remaining: times})
+}
+
+// Throttle makes the next `times` calls to `operation` fail with the throttling error ECS
+// returns when its request rate is exceeded.
+func (f *ECS) Throttle(operation string, times int) {
+	f.InjectFault(operation, awserr.New("ThrottlingException", "Rate exceeded", nil), times)
+}
+
+// ClearFaults removes every injected fault.
+func (f *ECS) ClearFaults() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.faults = nil
+}
+
+// Calls returns how many times `operation` has been called, including failed calls.
+func (f *ECS) Calls(operation string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.calls[operation]
+}
+
+// AddCluster creates a cluster, if it doesn't already exist, and returns its ARN.
+func (f *ECS) AddCluster(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.addCluster(name).arn
+}
+
+// SetContainerInstances sets how many container instances are registered to the named cluster,
+// creating the cluster if necessary.
+func (f *ECS) SetContainerInstances(clusterName string, n int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.addCluster(clusterName).containerInstances = n
+}
+
+// SetCapacityProviders associates capacity providers with the named cluster, creating the
+// cluster if necessary.
+func (f *ECS) SetCapacityProviders(clusterName string, providers ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.addCluster(clusterName).capacityProviders = providers
+}
+
+// AddService creates a service running `taskDefinition` (an ARN or "family:revision") in
+// the named cluster, creating the cluster if necessary, and returns the service's ARN.
+func (f *ECS) AddService(clusterName, name, taskDefinition string, desiredCount int64) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c := f.addCluster(clusterName)
+	now := f.Now()
+	service := &ecs.Service{
+		ClusterArn:     aws.String(c.arn),
+		CreatedAt:      aws.Time(now),
+		DesiredCount:   aws.Int64(desiredCount),
+		RunningCount:   aws.Int64(desiredCount),
+		ServiceArn:     aws.String(f.arn("service", c.name+"/"+name)),
+		ServiceName:    aws.String(name),
+		Status:         aws.String("ACTIVE"),
+		TaskDefinition: aws.String(f.taskDefinitionARN(taskDefinition)),
+	}
+
+	service.Deployments = []*ecs.Deployment{{
+		CreatedAt:      aws.Time(now),
+		DesiredCount:   aws.Int64(desiredCount),
+		Id:             aws.String("ecs-svc/" + name),
+		RunningCount:   aws.Int64(desiredCount),
+		Status:         aws.String("PRIMARY"),
+		TaskDefinition: service.TaskDefinition,
+		UpdatedAt:      aws.Time(now),
+	}}
+
+	c.services = append(c.services, service)
+	return *service.ServiceArn
+}
+
+// AddServiceEvent records an event, such as "(service web) has reached a steady state.", in
+// the event log of the service with the ARN, which must exist.
+func (f *ECS) AddServiceEvent(serviceARN, message string, at time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	service := f.findService(serviceARN)
+	event := &ecs.ServiceEvent{
+		CreatedAt: aws.Time(at),
+		Id:        aws.String(strconv.Itoa(len(service.Events))),
+		Message:   aws.String(message),
+	}
+
+	// as in ECS, the newest event comes first
+	service.Events = append([]*ecs.ServiceEvent{event}, service.Events...)
+}
+
+// AddTask starts a task running `taskDefinition` (an ARN or "family:revision") in the named
+// cluster, creating the cluster if necessary, and returns the task's ARN. The task has a
+// running container for each of the task definition's container definitions. As in ECS, a
+// task started by a service's deployment ("ecs-svc/" plus the service's name) is in the
+// service's group, so ListTasks can filter on the service.
+func (f *ECS) AddTask(clusterName, taskDefinition, startedBy string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c := f.addCluster(clusterName)
+	f.taskSequence++
+
+	task := &ecs.Task{
+		ClusterArn:        aws.String(c.arn),
+		DesiredStatus:     aws.String("RUNNING"),
+		LastStatus:        aws.String("RUNNING"),
+		StartedAt:         aws.Time(f.Now()),
+		TaskArn:           aws.String(f.arn("task", fmt.Sprintf("%s/%032d", c.name, f.taskSequence))),
+		TaskDefinitionArn: aws.String(f.taskDefinitionARN(taskDefinition)),
+	}
+
+	if startedBy != "" {
+		task.StartedBy = aws.String(startedBy)
+
+		for _, service := range c.services {
+			for _, deployment := range service.Deployments {
+				if aws.StringValue(deployment.Id) == startedBy {
+					task.Group = aws.String("service:" + aws.StringValue(service.ServiceName))
+				}
+			}
+		}
+	}
+
+	if td := f.findTaskDefinition(taskDefinition); td != nil {
+		for _, container := range td.definition.ContainerDefinitions {
+			task.Containers = append(task.Containers, &ecs.Container{
+				Image:      container.Image,
+				LastStatus: aws.String("RUNNING"),
+				Name:       container.Name,
+				TaskArn:    task.TaskArn,
+			})
+		}
+	}
+
+	c.tasks = append(c.tasks, task)
+	return *task.TaskArn
+}
+
+// AddTaskDefinitions registers `n` new revisions of a family and returns their ARNs.
+func (f *ECS) AddTaskDefinitions(family string, n int) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var arns []string
+
+	for i := 0; i < n; i++ {
+		output, _ := f.registerTaskDefinition(&ecs.RegisterTaskDefinitionInput{
+			Family: aws.String(family),
+		})
+
+		arns = append(arns, *output.TaskDefinition.TaskDefinitionArn)
+	}
+
+	return arns
+}
+
+// TaskDefinitionARNs returns the ARNs of every task definition with the given status
+// ("ACTIVE" or "INACTIVE"), sorted by family and then revision.
+func (f *ECS) TaskDefinitionARNs(status string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var arns []string
+	for _, td := range f.sortedTaskDefinitions() {
+		if *td.definition.Status == status {
+			arns = append(arns, *td.definition.TaskDefinitionArn)
+		}
+	}
+
+	return arns
+}
+
+// call records a call to `operation` and returns the error of the first fault matching it.
+func (f *ECS) call(operation string) error {
+	f.calls[operation]++
+
+	for i, flt := range f.faults {
+		if flt.operation != "" && flt.operation != operation {
+			continue
+		}
+
+		if flt.remaining > 0 {
+			flt.remaining--
+			if flt.remaining == 0 {
+				f.faults = append(f.faults[:i], f.faults[i+1:]...)
+			}
+		}
+
+		return flt.err
+	}
+
+	return nil
+}
+
+func (f *ECS) addCluster(name string) *cluster {
+	if c := f.findCluster(name); c != nil {
+		return c
+	}
+
+	c := &cluster{arn: f.arn("cluster", name), name: name}
+	f.clusters = append(f.clusters, c)
+	return c
+}
+
+// findCluster looks up a cluster by name or ARN.
+func (f *ECS) findCluster(nameOrARN string) *cluster {
+	for _, c := range f.clusters {
+		if c.name == nameOrARN || c.arn == nameOrARN {
+			return c
+		}
+	}
+
+	return nil
+}
+
+// findService looks up a service by ARN.
+func (f *ECS) findService(arn string) *ecs.Service {
+	for _, c := range f.clusters {
+		for _, service := range c.services {
+			if *service.ServiceArn == arn {
+				return service
+			}
+		}
+	}
+
+	return nil
+}
+
+// findTaskDefinition looks up a task definition by ARN or "family:revision". A bare family
+// name resolves to the family's latest ACTIVE revision.
+func (f *ECS) findTaskDefinition(ref string) *taskDefinition {
+	if !strings.Contains(ref, ":") {
+		var latest *taskDefinition
+		for _, td := range f.taskDefinitions {
+			if *td.definition.Family == ref && *td.definition.Status == "ACTIVE" {
+				if latest == nil || *td.definition.Revision > *latest.definition.Revision {
+					latest = td
+				}
+			}
+		}
+
+		return latest
+	}
+
+	arn := f.taskDefinitionARN(ref)
+	for _, td := range f.taskDefinitions {
+		if *td.definition.TaskDefinitionArn == arn {
+			return td
+		}
+	}
+
+	return nil
+}
+
+func (f *ECS) sortedTaskDefinitions() []*taskDefinition {
+	sorted := make([]*taskDefinition, len(f.taskDefinitions))
+	copy(sorted, f.taskDefinitions)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i].definition, sorted[j].definition
+		if *a.Family != *b.Family {
+			return *a.Family < *b.Family
+		}
+
+		return *a.Revision < *b.Revision
+	})
+
+	return sorted
+}
+
+func (f *ECS) arn(resource, id string) string {
+	return fmt.Sprintf("arn:aws:ecs:%s:%s:%s/%s", f.Region, f.Account, resource, id)
+}
+
+// taskDefinitionARN expands "family:revision" into a full task definition ARN.
+func (f *ECS) taskDefinitionARN(ref string) string {
+	if strings.HasPrefix(ref, "arn:") {
+		return ref
+	}
+
+	return f.arn("task-definition", ref)
+}
+
+// paginate returns the page of `items` selected by `nextToken` and `maxResults`, along with
+// the token for the following page.
+func paginate(items []string, nextToken *string, maxResults *int64, defaultPageSize int) ([]string, *string, error) {
+	start := 0
+	if nextToken != nil {
+		var err error
+		if start, err = strconv.Atoi(strings.TrimPrefix(*nextToken, "ecsfake-")); err != nil || start < 0 || start > len(items) {
+			return nil, nil, invalidParameter("Invalid nextToken")
+		}
+	}
+
+	pageSize := defaultPageSize
+	if maxResults != nil {
+		if *maxResults < 1 {
+			return nil, nil, invalidParameter("maxResults must be positive")
+		}
+
+		pageSize = int(*maxResults)
+	}
+
+	end := start + pageSize
+	if end >= len(items) {
+		return items[start:], nil, nil
+	}
+
+	return items[start:end], aws.String(fmt.Sprintf("ecsfake-%d", end)), nil
+}
+
+func clientException(message string) error {
+	return awserr.New(ecs.ErrCodeClientException, message, nil)
+}
+
+func invalidParameter(message string) error {
+	return awserr.New(ecs.ErrCodeInvalidParameterException, message, nil)
+}
+
+func clusterNotFound() error {
+	return awserr.New(ecs.ErrCodeClusterNotFoundException, "Cluster not found.", nil)
+}
@@ -0,0 +1,332 @@
+package ecsfake_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/aws/aws-sdk-go/service/ecs/ecsiface"
+	"github.com/quintilesims/go-ecs-cleaner/ecsclient"
+	"github.com/quintilesims/go-ecs-cleaner/ecsfake"
+	"github.com/quintilesims/go-ecs-cleaner/inventory"
+)
+
+var _ ecsclient.ECSSvc = (*ecsfake.ECS)(nil)
+var _ ecsiface.ECSAPI = (*ecsfake.ECS)(nil)
+
+func Test_LoadFile(t *testing.T) {
+	f, err := ecsfake.LoadFile("testdata/seed.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedActive := []string{
+		"arn:aws:ecs:us-west-2:123456789012:task-definition/cron:2",
+		"arn:aws:ecs:us-west-2:123456789012:task-definition/web:1",
+		"arn:aws:ecs:us-west-2:123456789012:task-definition/web:2",
+		"arn:aws:ecs:us-west-2:123456789012:task-definition/web:3",
+		"arn:aws:ecs:us-west-2:123456789012:task-definition/web:4",
+		"arn:aws:ecs:us-west-2:123456789012:task-definition/web:5",
+	}
+
+	if result := f.TaskDefinitionARNs("ACTIVE"); !reflect.DeepEqual(expectedActive, result) {
+		t.Errorf("Expected %v, got %v\n", expectedActive, result)
+	}
+
+	expectedInactive := []string{"arn:aws:ecs:us-west-2:123456789012:task-definition/cron:1"}
+	if result := f.TaskDefinitionARNs("INACTIVE"); !reflect.DeepEqual(expectedInactive, result) {
+		t.Errorf("Expected %v, got %v\n", expectedInactive, result)
+	}
+
+	described, err := f.DescribeTaskDefinition(&ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: aws.String("cron"),
+		Include:        aws.StringSlice([]string{"TAGS"}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if revision := aws.Int64Value(described.TaskDefinition.Revision); revision != 2 {
+		t.Errorf("Expected latest revision 2, got %d\n", revision)
+	}
+
+	if len(described.Tags) != 1 || *described.Tags[0].Key != "team" {
+		t.Errorf("Expected the seeded tag, got %v\n", described.Tags)
+	}
+
+	services, err := f.DescribeServices(&ecs.DescribeServicesInput{
+		Cluster:  aws.String("staging"),
+		Services: aws.StringSlice([]string{"web", "missing"}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(services.Services) != 1 || *services.Services[0].TaskDefinition != "arn:aws:ecs:us-west-2:123456789012:task-definition/web:4" {
+		t.Errorf("Expected staging's web service, got %v\n", services.Services)
+	}
+
+	if len(services.Failures) != 1 || *services.Failures[0].Reason != "MISSING" {
+		t.Errorf("Expected one MISSING failure, got %v\n", services.Failures)
+	}
+
+	tasks, err := f.ListTasks(&ecs.ListTasksInput{
+		Cluster:   aws.String("arn:aws:ecs:us-west-2:123456789012:cluster/prod"),
+		StartedBy: aws.String("events-rule/nightly"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tasks.TaskArns) != 1 {
+		t.Errorf("Expected one task, got %v\n", tasks.TaskArns)
+	}
+}
+
+func Test_Load_RainyDay(t *testing.T) {
+	testCases := map[string]string{
+		"unknown field":    `{"clusterz": []}`,
+		"no revisions":     `{"taskDefinitions": [{"family": "web"}]}`,
+		"unknown inactive": `{"taskDefinitions": [{"family": "web", "revisions": 1, "inactive": [2]}]}`,
+		"unnamed cluster":  `{"clusters": [{}]}`,
+		"malformed":        `{`,
+	}
+
+	for name, seed := range testCases {
+		if _, err := ecsfake.Load(strings.NewReader(seed)); err == nil {
+			t.Errorf("TestCase '%s': expected an error\n", name)
+		}
+	}
+}
+
+func Test_ListTaskDefinitions_Pagination(t *testing.T) {
+	f := ecsfake.New("000000000000", "us-east-1")
+	f.AddTaskDefinitions("family0", 150)
+	f.AddTaskDefinitions("family1", 100)
+
+	var pages []int
+	var nextToken *string
+
+	for {
+		output, err := f.ListTaskDefinitions(&ecs.ListTaskDefinitionsInput{NextToken: nextToken})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pages = append(pages, len(output.TaskDefinitionArns))
+
+		if nextToken = output.NextToken; nextToken == nil {
+			break
+		}
+	}
+
+	if expected := []int{100, 100, 50}; !reflect.DeepEqual(expected, pages) {
+		t.Errorf("Expected pages %v, got %v\n", expected, pages)
+	}
+
+	output, err := f.ListTaskDefinitions(&ecs.ListTaskDefinitionsInput{
+		FamilyPrefix: aws.String("family0"),
+		Sort:         aws.String("DESC"),
+		MaxResults:   aws.Int64(2),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"arn:aws:ecs:us-east-1:000000000000:task-definition/family0:150",
+		"arn:aws:ecs:us-east-1:000000000000:task-definition/family0:149",
+	}
+
+	if result := aws.StringValueSlice(output.TaskDefinitionArns); !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, got %v\n", expected, result)
+	}
+
+	if _, err := f.ListTaskDefinitions(&ecs.ListTaskDefinitionsInput{NextToken: aws.String("bogus")}); err == nil {
+		t.Error("Expected an error for an invalid next token")
+	}
+}
+
+// Run with -race: the fake may be driven through its server while a test seeds it.
+func Test_AddTaskDefinitions_Concurrent(t *testing.T) {
+	f := ecsfake.New("000000000000", "us-east-1")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			f.AddTaskDefinitions("family0", 5)
+		}()
+
+		go func() {
+			defer wg.Done()
+			f.ListTaskDefinitions(&ecs.ListTaskDefinitionsInput{})
+		}()
+	}
+
+	wg.Wait()
+
+	if result := f.TaskDefinitionARNs("ACTIVE"); len(result) != 20 {
+		t.Errorf("Expected 20 task definitions, got %d\n", len(result))
+	}
+}
+
+func Test_ListTasks_ServiceName(t *testing.T) {
+	f := ecsfake.New("000000000000", "us-east-1")
+	arns := f.AddTaskDefinitions("web", 1)
+
+	f.AddService("cluster0", "web", arns[0], 1)
+	expected := []string{f.AddTask("cluster0", arns[0], "ecs-svc/web")}
+	f.AddTask("cluster0", arns[0], "")
+
+	output, err := f.ListTasks(&ecs.ListTasksInput{
+		Cluster:     aws.String("cluster0"),
+		ServiceName: aws.String("web"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result := aws.StringValueSlice(output.TaskArns); !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, got %v\n", expected, result)
+	}
+}
+
+func Test_Faults(t *testing.T) {
+	f := ecsfake.New("000000000000", "us-east-1")
+	arns := f.AddTaskDefinitions("family0", 2)
+
+	f.Throttle("DeregisterTaskDefinition", 2)
+	f.InjectFault("ListClusters", errors.New("IntentionalException"), 0)
+
+	input := &ecs.DeregisterTaskDefinitionInput{TaskDefinition: aws.String(arns[0])}
+
+	for i := 0; i < 2; i++ {
+		_, err := f.DeregisterTaskDefinition(input)
+		if awsErr, ok := err.(awserr.Error); !ok || awsErr.Code() != "ThrottlingException" {
+			t.Errorf("Call %d: expected a throttling error, got %v\n", i, err)
+		}
+	}
+
+	if _, err := f.DeregisterTaskDefinition(input); err != nil {
+		t.Errorf("Expected the throttling to have been used up, got %v\n", err)
+	}
+
+	if _, err := f.DeregisterTaskDefinition(input); err == nil {
+		t.Error("Expected an error deregistering an INACTIVE task definition")
+	}
+
+	if calls := f.Calls("DeregisterTaskDefinition"); calls != 4 {
+		t.Errorf("Expected 4 calls, got %d\n", calls)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := f.ListClusters(&ecs.ListClustersInput{}); err == nil {
+			t.Errorf("Call %d: expected the persistent fault to fire\n", i)
+		}
+	}
+
+	f.ClearFaults()
+
+	if _, err := f.ListClusters(&ecs.ListClustersInput{}); err != nil {
+		t.Errorf("Expected no error after clearing faults, got %v\n", err)
+	}
+}
+
+func Test_Handler(t *testing.T) {
+	f, err := ecsfake.LoadFile("testdata/seed.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(f.Handler())
+	defer server.Close()
+
+	sess := session.Must(session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials("AKIDECSFAKE", "ecsfake", ""),
+		Endpoint:    aws.String(server.URL),
+		MaxRetries:  aws.Int(0),
+		Region:      aws.String("us-west-2"),
+	}))
+	svc := ecs.New(sess)
+
+	output, err := svc.ListServices(&ecs.ListServicesInput{Cluster: aws.String("prod")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"arn:aws:ecs:us-west-2:123456789012:service/prod/web"}
+	if result := aws.StringValueSlice(output.ServiceArns); !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, got %v\n", expected, result)
+	}
+
+	_, err = svc.ListServices(&ecs.ListServicesInput{Cluster: aws.String("missing")})
+	if awsErr, ok := err.(awserr.Error); !ok || awsErr.Code() != "ClusterNotFoundException" {
+		t.Errorf("Expected ClusterNotFoundException, got %v\n", err)
+	}
+
+	_, err = svc.StopTask(&ecs.StopTaskInput{Task: aws.String("task")})
+	if awsErr, ok := err.(awserr.Error); !ok || awsErr.Code() != "UnknownOperationException" {
+		t.Errorf("Expected UnknownOperationException, got %v\n", err)
+	}
+}
+
+func Test_FromInventory(t *testing.T) {
+	f, err := ecsfake.LoadFile("testdata/seed.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := ecsclient.NewECSClient()
+	e.Flags.Quiet = true
+	e.Svc = f
+	e.Events = f.Events()
+
+	captured, err := e.Discover()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := captured.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := inventory.Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replay, err := ecsfake.FromInventory(loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e.Svc = replay
+	e.Events = replay.Events()
+
+	replayed, err := e.Discover()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replayed.CapturedAt = captured.CapturedAt
+	if equal := reflect.DeepEqual(captured, replayed); !equal {
+		t.Errorf("Expected %+v, got %+v\n", captured, replayed)
+	}
+
+	if len(replayed.Tasks) != 1 || len(replayed.Services) != 2 || len(replayed.References) != 1 {
+		t.Errorf("Expected the seeded task, services and schedule, got %v, %v and %v\n", replayed.Tasks, replayed.Services, replayed.References)
+	}
+}
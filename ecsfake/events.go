@@ -0,0 +1,122 @@
+package ecsfake
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
+)
+
+// DefaultRulesPageSize is the page size the fake uses for ListRules when `limit` is omitted.
+const DefaultRulesPageSize = 100
+
+// schedule is an ECS target of an EventBridge rule on the default event bus. A rule may have
+// several.
+type schedule struct {
+	rule           string
+	clusterARN     string
+	taskDefinition string
+}
+
+// AddSchedule gives the EventBridge rule named `rule` on the default event bus, creating it if
+// necessary, a target that runs `taskDefinition` (an ARN, "family:revision", or a family on
+// its own for the latest ACTIVE revision) in the named cluster, creating the cluster if
+// necessary. Schedules are read through Events.
+func (f *ECS) AddSchedule(clusterName, rule, taskDefinition string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	c := f.addCluster(clusterName)
+	f.schedules = append(f.schedules, &schedule{
+		rule:           rule,
+		clusterARN:     c.arn,
+		taskDefinition: f.taskDefinitionARN(taskDefinition),
+	})
+}
+
+// Events returns the account's EventBridge API. It implements ListRules and ListTargetsByRule
+// for the rules AddSchedule created, and shares the fake's faults and call counts; other
+// operations panic when called.
+func (f *ECS) Events() eventbridgeiface.EventBridgeAPI {
+	return &events{f: f}
+}
+
+type events struct {
+	eventbridgeiface.EventBridgeAPI
+
+	f *ECS
+}
+
+func (e *events) ListRules(input *eventbridge.ListRulesInput) (*eventbridge.ListRulesOutput, error) {
+	e.f.mu.Lock()
+	defer e.f.mu.Unlock()
+
+	if err := e.f.call("ListRules"); err != nil {
+		return nil, err
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+
+	for _, s := range e.f.schedules {
+		if !seen[s.rule] {
+			seen[s.rule] = true
+			names = append(names, s.rule)
+		}
+	}
+
+	page, nextToken, err := paginate(names, input.NextToken, input.Limit, DefaultRulesPageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &eventbridge.ListRulesOutput{NextToken: nextToken}
+	for _, name := range page {
+		output.Rules = append(output.Rules, &eventbridge.Rule{
+			Arn:                aws.String(e.f.eventsARN("rule", name)),
+			EventBusName:       aws.String("default"),
+			Name:               aws.String(name),
+			ScheduleExpression: aws.String("rate(1 day)"),
+			State:              aws.String(eventbridge.RuleStateEnabled),
+		})
+	}
+
+	return output, nil
+}
+
+func (e *events) ListTargetsByRule(input *eventbridge.ListTargetsByRuleInput) (*eventbridge.ListTargetsByRuleOutput, error) {
+	e.f.mu.Lock()
+	defer e.f.mu.Unlock()
+
+	if err := e.f.call("ListTargetsByRule"); err != nil {
+		return nil, err
+	}
+
+	var targets []*eventbridge.Target
+	for _, s := range e.f.schedules {
+		if s.rule != aws.StringValue(input.Rule) {
+			continue
+		}
+
+		targets = append(targets, &eventbridge.Target{
+			Arn: aws.String(s.clusterARN),
+			EcsParameters: &eventbridge.EcsParameters{
+				TaskCount:         aws.Int64(1),
+				TaskDefinitionArn: aws.String(s.taskDefinition),
+			},
+			Id: aws.String(strconv.Itoa(len(targets))),
+		})
+	}
+
+	if len(targets) == 0 {
+		return nil, &eventbridge.ResourceNotFoundException{Message_: aws.String(fmt.Sprintf("Rule %s does not exist.", aws.StringValue(input.Rule)))}
+	}
+
+	return &eventbridge.ListTargetsByRuleOutput{Targets: targets}, nil
+}
+
+func (f *ECS) eventsARN(resource, id string) string {
+	return fmt.Sprintf("arn:aws:events:%s:%s:%s/%s", f.Region, f.Account, resource, id)
+}
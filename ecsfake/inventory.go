@@ -0,0 +1,105 @@
+package ecsfake
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/quintilesims/go-ecs-cleaner/inventory"
+)
+
+// FromInventory creates a fake ECS account holding exactly what an Inventory recorded, with
+// the original ARNs, and its scheduled rules' references as schedules. Discovery against the
+// fake, with its Events, reads back the same Inventory, which lets a snapshot be replayed
+// through the cleaner without any AWS calls.
+func FromInventory(inv *inventory.Inventory) (*ECS, error) {
+	account := "000000000000"
+	if len(inv.ClusterARNs) > 0 {
+		if fields := strings.Split(inv.ClusterARNs[0], ":"); len(fields) == 6 {
+			account = fields[4]
+		}
+	}
+
+	f := New(account, inv.Region)
+
+	for _, arn := range inv.TaskDefinitionARNs {
+		i := strings.LastIndex(arn, "/")
+		j := strings.LastIndex(arn, ":")
+		if i < 0 || j < i {
+			return nil, fmt.Errorf("unable to parse task definition ARN %q", arn)
+		}
+
+		revision, err := strconv.ParseInt(arn[j+1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse task definition ARN %q", arn)
+		}
+
+		td := &taskDefinition{
+			definition: &ecs.TaskDefinition{
+				Family:            aws.String(arn[i+1 : j]),
+				Revision:          aws.Int64(revision),
+				Status:            aws.String(ecs.TaskDefinitionStatusActive),
+				TaskDefinitionArn: aws.String(arn),
+			},
+		}
+
+		if registeredAt, ok := inv.TaskDefinitionRegisteredAt[arn]; ok {
+			td.definition.RegisteredAt = aws.Time(registeredAt)
+		}
+
+		var keys []string
+		for key := range inv.TaskDefinitionTags[arn] {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			td.tags = append(td.tags, &ecs.Tag{Key: aws.String(key), Value: aws.String(inv.TaskDefinitionTags[arn][key])})
+		}
+
+		f.taskDefinitions = append(f.taskDefinitions, td)
+	}
+
+	for _, arn := range inv.ClusterARNs {
+		f.clusters = append(f.clusters, &cluster{arn: arn, name: arn[strings.LastIndex(arn, "/")+1:]})
+	}
+
+	for _, service := range inv.Services {
+		c := f.findCluster(aws.StringValue(service.ClusterArn))
+		if c == nil {
+			return nil, fmt.Errorf("service %s belongs to unknown cluster %s", aws.StringValue(service.ServiceArn), aws.StringValue(service.ClusterArn))
+		}
+
+		s := service
+		c.services = append(c.services, &s)
+	}
+
+	for _, task := range inv.Tasks {
+		c := f.findCluster(aws.StringValue(task.ClusterArn))
+		if c == nil {
+			return nil, fmt.Errorf("task %s belongs to unknown cluster %s", aws.StringValue(task.TaskArn), aws.StringValue(task.ClusterArn))
+		}
+
+		t := task
+		if t.DesiredStatus == nil {
+			t.DesiredStatus = aws.String(ecs.DesiredStatusRunning)
+		}
+
+		c.tasks = append(c.tasks, &t)
+	}
+
+	// a reference doesn't record the cluster the rule's target runs in
+	for _, reference := range inv.References {
+		if reference.Source != "events-rule" {
+			return nil, fmt.Errorf("unable to replay a reference from %q", reference.Source)
+		}
+
+		f.schedules = append(f.schedules, &schedule{rule: reference.ID, taskDefinition: reference.TaskDefinitionARN})
+	}
+
+	return f, nil
+}
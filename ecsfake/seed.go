@@ -0,0 +1,177 @@
+package ecsfake
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// Seed describes the initial state of a fake ECS account. It's usually loaded from JSON:
+//
+//	{
+//	  "account": "123456789012",
+//	  "region": "us-west-2",
+//	  "taskDefinitions": [
+//	    {"family": "web", "revisions": 5},
+//	    {"family": "cron", "revisions": 2, "inactive": [1], "tags": {"team": "data"}}
+//	  ],
+//	  "clusters": [
+//	    {
+//	      "name": "prod",
+//	      "services": [{"name": "web", "taskDefinition": "web:5", "desiredCount": 2}],
+//	      "tasks": [{"taskDefinition": "cron:2", "startedBy": "events-rule/nightly"}],
+//	      "schedules": [{"rule": "nightly", "taskDefinition": "cron"}]
+//	    }
+//	  ]
+//	}
+//
+// Task definition references may be full ARNs or "family:revision".
+type Seed struct {
+	Account         string               `json:"account"`
+	Region          string               `json:"region"`
+	Clusters        []SeedCluster        `json:"clusters"`
+	TaskDefinitions []SeedTaskDefinition `json:"taskDefinitions"`
+}
+
+// SeedCluster describes a cluster and what runs, or is scheduled to run, in it.
+type SeedCluster struct {
+	Name      string         `json:"name"`
+	Services  []SeedService  `json:"services"`
+	Tasks     []SeedTask     `json:"tasks"`
+	Schedules []SeedSchedule `json:"schedules"`
+}
+
+// SeedService describes a service. `CreatedAt` defaults to the time the seed is loaded.
+type SeedService struct {
+	Name           string     `json:"name"`
+	TaskDefinition string     `json:"taskDefinition"`
+	DesiredCount   int64      `json:"desiredCount"`
+	CreatedAt      *time.Time `json:"createdAt,omitempty"`
+}
+
+// SeedTask describes a running task.
+type SeedTask struct {
+	TaskDefinition string `json:"taskDefinition"`
+	StartedBy      string `json:"startedBy,omitempty"`
+}
+
+// SeedSchedule describes an EventBridge rule's target that runs a task in the cluster.
+type SeedSchedule struct {
+	Rule           string `json:"rule"`
+	TaskDefinition string `json:"taskDefinition"`
+}
+
+// SeedTaskDefinition describes the revisions 1 through `Revisions` of a family. Revisions
+// listed in `Inactive` are registered and then deregistered.
+type SeedTaskDefinition struct {
+	Family    string            `json:"family"`
+	Revisions int               `json:"revisions"`
+	Inactive  []int64           `json:"inactive,omitempty"`
+	Tags      map[string]string `json:"tags,omitempty"`
+}
+
+// Load creates a fake ECS account from a JSON seed.
+func Load(r io.Reader) (*ECS, error) {
+	var seed Seed
+
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&seed); err != nil {
+		return nil, fmt.Errorf("unable to decode seed: %v", err)
+	}
+
+	return NewFromSeed(seed)
+}
+
+// LoadFile creates a fake ECS account from a JSON seed file.
+func LoadFile(path string) (*ECS, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Load(f)
+}
+
+// NewFromSeed creates a fake ECS account in the state described by `seed`. The account and
+// region default to "000000000000" and "us-east-1".
+func NewFromSeed(seed Seed) (*ECS, error) {
+	if seed.Account == "" {
+		seed.Account = "000000000000"
+	}
+
+	if seed.Region == "" {
+		seed.Region = "us-east-1"
+	}
+
+	f := New(seed.Account, seed.Region)
+
+	for _, std := range seed.TaskDefinitions {
+		if std.Family == "" || std.Revisions < 1 {
+			return nil, fmt.Errorf("task definition seeds need a family and at least one revision")
+		}
+
+		var tags []string
+		for key := range std.Tags {
+			tags = append(tags, key)
+		}
+
+		sort.Strings(tags)
+
+		for i := 0; i < std.Revisions; i++ {
+			input := &ecs.RegisterTaskDefinitionInput{Family: aws.String(std.Family)}
+			for _, key := range tags {
+				input.Tags = append(input.Tags, &ecs.Tag{Key: aws.String(key), Value: aws.String(std.Tags[key])})
+			}
+
+			f.registerTaskDefinition(input)
+		}
+
+		for _, revision := range std.Inactive {
+			td := f.findTaskDefinition(fmt.Sprintf("%s:%d", std.Family, revision))
+			if td == nil {
+				return nil, fmt.Errorf("inactive revision %s:%d was never registered", std.Family, revision)
+			}
+
+			td.definition.Status = aws.String(ecs.TaskDefinitionStatusInactive)
+		}
+	}
+
+	for _, sc := range seed.Clusters {
+		if sc.Name == "" {
+			return nil, fmt.Errorf("cluster seeds need a name")
+		}
+
+		f.AddCluster(sc.Name)
+
+		for _, ss := range sc.Services {
+			arn := f.AddService(sc.Name, ss.Name, ss.TaskDefinition, ss.DesiredCount)
+
+			if ss.CreatedAt != nil {
+				for _, service := range f.findCluster(sc.Name).services {
+					if *service.ServiceArn == arn {
+						service.CreatedAt = aws.Time(*ss.CreatedAt)
+					}
+				}
+			}
+		}
+
+		for _, st := range sc.Tasks {
+			f.AddTask(sc.Name, st.TaskDefinition, st.StartedBy)
+		}
+
+		for _, ss := range sc.Schedules {
+			f.AddSchedule(sc.Name, ss.Rule, ss.TaskDefinition)
+		}
+	}
+
+	return f, nil
+}
@@ -0,0 +1,97 @@
+package ecsfake
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/private/protocol/json/jsonutil"
+)
+
+// Handler returns an `http.Handler` that serves the fake over the ECS JSON protocol, and its
+// Events over EventBridge's, so it can stand in for real endpoints (e.g. via
+// `--endpoint-url`). Request signatures are not checked. Each response carries a request ID, "ecsfake-1", "ecsfake-2" and so on.
+func (f *ECS) Handler() http.Handler {
+	return http.HandlerFunc(f.serveHTTP)
+}
+
+func (f *ECS) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	target := r.Header.Get("X-Amz-Target")
+	operation := target[strings.LastIndex(target, ".")+1:]
+
+	f.mu.Lock()
+	f.requestSequence++
+	w.Header().Set("X-Amzn-Requestid", fmt.Sprintf("ecsfake-%d", f.requestSequence))
+	f.mu.Unlock()
+
+	var receiver interface{} = f
+	if strings.HasPrefix(target, "AWSEvents.") {
+		receiver = f.Events()
+	}
+
+	output, err := f.dispatch(receiver, operation, r)
+	if err != nil {
+		code, message := "ServerException", err.Error()
+		if awsErr, ok := err.(awserr.Error); ok {
+			code, message = awsErr.Code(), awsErr.Message()
+		}
+
+		status := http.StatusBadRequest
+		if code == "ServerException" {
+			status = http.StatusInternalServerError
+		}
+
+		w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"__type": code, "message": message})
+		return
+	}
+
+	body, err := jsonutil.BuildJSON(output)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-amz-json-1.1")
+	w.Write(body)
+}
+
+// dispatch decodes the request into the input type of the named operation and calls it on
+// the receiver.
+func (f *ECS) dispatch(receiver interface{}, operation string, r *http.Request) (output interface{}, err error) {
+	method := reflect.ValueOf(receiver).MethodByName(operation)
+	if operation == "" || !method.IsValid() || method.Type().NumIn() != 1 || method.Type().NumOut() != 2 {
+		return nil, awserr.New("UnknownOperationException", fmt.Sprintf("unknown operation %q", operation), nil)
+	}
+
+	// Operations the fake doesn't implement fall through to the nil embedded interface.
+	defer func() {
+		if recover() != nil {
+			output, err = nil, awserr.New("UnknownOperationException", fmt.Sprintf("ecsfake does not implement %s", operation), nil)
+		}
+	}()
+
+	var body bytes.Buffer
+	if _, err := body.ReadFrom(r.Body); err != nil {
+		return nil, err
+	}
+
+	input := reflect.New(method.Type().In(0).Elem())
+	if body.Len() > 0 {
+		if err := jsonutil.UnmarshalJSON(input.Interface(), &body); err != nil {
+			return nil, awserr.New("SerializationException", err.Error(), nil)
+		}
+	}
+
+	results := method.Call([]reflect.Value{input})
+	if e, ok := results[1].Interface().(error); ok && e != nil {
+		return nil, e
+	}
+
+	return results[0].Interface(), nil
+}
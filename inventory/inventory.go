@@ -0,0 +1,112 @@
+// Package inventory defines the account state that the cleaner's discovery phase reads,
+// and its on-disk snapshot format.
+package inventory
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+// Version is the snapshot format version written by this package.
+const Version = 1
+
+// Inventory is everything discovery reads from an account and region. Written to disk, it's
+// a snapshot that the filtering pipeline can be replayed against offline.
+type Inventory struct {
+	Version    int       `json:"version"`
+	Region     string    `json:"region"`
+	CapturedAt time.Time `json:"capturedAt"`
+
+	ClusterARNs             []string            `json:"clusterArns"`
+	ServiceARNsByClusterARN map[string][]string `json:"serviceArnsByClusterArn"`
+	Services                []ecs.Service       `json:"services"`
+	TaskDefinitionARNs      []string            `json:"taskDefinitionArns"`
+	Tasks                   []ecs.Task          `json:"tasks"`
+
+	// TaskDefinitionTags maps task definition ARNs to their tags. It's only populated when
+	// discovery was asked to collect tags.
+	TaskDefinitionTags map[string]map[string]string `json:"taskDefinitionTags,omitempty"`
+
+	// TaskDefinitionRegisteredAt maps task definition ARNs to when they were registered.
+	// Discovery only records it for revisions of families that no service uses.
+	TaskDefinitionRegisteredAt map[string]time.Time `json:"taskDefinitionRegisteredAt,omitempty"`
+
+	// References are task definitions referenced from somewhere other than an ECS service or
+	// task, such as a schedule.
+	References []Reference `json:"references,omitempty"`
+}
+
+// Reference records that a task definition is referenced by something outside ECS.
+type Reference struct {
+	TaskDefinitionARN string `json:"taskDefinitionArn"`
+	Source            string `json:"source"`
+	ID                string `json:"id"`
+}
+
+// New creates an empty Inventory for a region, stamped with the current time.
+func New(region string) *Inventory {
+	return &Inventory{
+		Version:                 Version,
+		Region:                  region,
+		CapturedAt:              time.Now().UTC(),
+		ServiceARNsByClusterARN: make(map[string][]string),
+	}
+}
+
+// Read decodes a snapshot.
+func Read(r io.Reader) (*Inventory, error) {
+	var inv Inventory
+
+	if err := json.NewDecoder(r).Decode(&inv); err != nil {
+		return nil, fmt.Errorf("unable to decode snapshot: %v", err)
+	}
+
+	if inv.Version != Version {
+		return nil, fmt.Errorf("unsupported snapshot version %d (expected %d)", inv.Version, Version)
+	}
+
+	if inv.ServiceARNsByClusterARN == nil {
+		inv.ServiceARNsByClusterARN = make(map[string][]string)
+	}
+
+	return &inv, nil
+}
+
+// Load reads a snapshot from a file.
+func Load(path string) (*Inventory, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Read(f)
+}
+
+// Write encodes the Inventory as an indented JSON snapshot.
+func (i *Inventory) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(i)
+}
+
+// Save writes the Inventory to a snapshot file, replacing any existing file.
+func (i *Inventory) Save(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := i.Write(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
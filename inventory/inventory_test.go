@@ -0,0 +1,55 @@
+package inventory
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+)
+
+func Test_WriteRead(t *testing.T) {
+	expected := New("us-west-2")
+	expected.ClusterARNs = []string{"cluster0"}
+	expected.ServiceARNsByClusterARN["cluster0"] = []string{"service0"}
+	expected.Services = []ecs.Service{
+		ecs.Service{ServiceArn: aws.String("service0"), TaskDefinition: aws.String("family0:1")},
+	}
+	expected.TaskDefinitionARNs = []string{"family0:1", "family0:2"}
+	expected.Tasks = []ecs.Task{
+		ecs.Task{TaskArn: aws.String("task0"), TaskDefinitionArn: aws.String("family0:2")},
+	}
+	expected.References = []Reference{
+		Reference{TaskDefinitionARN: "family0:2", Source: "schedule", ID: "rule0"},
+	}
+
+	var buf bytes.Buffer
+	if err := expected.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if equal := reflect.DeepEqual(expected, result); !equal {
+		t.Errorf("Expected %v, got %v\n", expected, result)
+	}
+}
+
+func Test_Read_RainyDay(t *testing.T) {
+	testCases := map[string]string{
+		"malformed":           `{`,
+		"unsupported version": `{"version": 2}`,
+		"missing version":     `{"region": "us-west-2"}`,
+	}
+
+	for name, snapshot := range testCases {
+		if _, err := Read(strings.NewReader(snapshot)); err == nil {
+			t.Errorf("TestCase '%s': expected an error\n", name)
+		}
+	}
+}
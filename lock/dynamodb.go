@@ -0,0 +1,193 @@
+package lock
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// The attributes of a lease's item in a DynamoDB table. The table's partition key must be a
+// string named LockKey; ExpiresAt, in Unix seconds, may be used as the table's TTL attribute.
+const (
+	keyAttribute        = "LockKey"
+	holderAttribute     = "Holder"
+	acquiredAtAttribute = "AcquiredAt"
+	expiresAtAttribute  = "ExpiresAt"
+)
+
+// DynamoDBBackend keeps leases as items in a DynamoDB table, taking and changing them with
+// conditional writes, so cleaners anywhere can share it.
+type DynamoDBBackend struct {
+	Svc   dynamodbiface.DynamoDBAPI
+	Table string
+}
+
+// Acquire stores the lease, unless another holder's unexpired lease on its key is stored.
+func (b *DynamoDBBackend) Acquire(lease Lease, now time.Time) error {
+	_, err := b.Svc.PutItem(&dynamodb.PutItemInput{
+		TableName: b.tableName(),
+		Item: map[string]*dynamodb.AttributeValue{
+			keyAttribute:        {S: aws.String(lease.Key)},
+			holderAttribute:     {S: aws.String(lease.Holder)},
+			acquiredAtAttribute: unixValue(lease.AcquiredAt),
+			expiresAtAttribute:  expiryValue(lease.ExpiresAt),
+		},
+		ConditionExpression:      aws.String("attribute_not_exists(#key) OR #holder = :holder OR #expiresAt <= :now"),
+		ExpressionAttributeNames: b.names(),
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":holder": {S: aws.String(lease.Holder)},
+			":now":    unixValue(now),
+		},
+	})
+
+	if !isConditionFailed(err) {
+		return err
+	}
+
+	current, err := b.get(lease.Key)
+	if err != nil {
+		return err
+	}
+
+	if current == nil {
+		// released since the write was refused
+		return b.Acquire(lease, now)
+	}
+
+	return &HeldError{Lease: *current}
+}
+
+// Renew extends the holder's lease on key.
+func (b *DynamoDBBackend) Renew(key, holder string, expiresAt time.Time) error {
+	_, err := b.Svc.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:                b.tableName(),
+		Key:                      itemKey(key),
+		UpdateExpression:         aws.String("SET #expiresAt = :expiresAt"),
+		ConditionExpression:      aws.String("#holder = :holder"),
+		ExpressionAttributeNames: map[string]*string{"#holder": aws.String(holderAttribute), "#expiresAt": aws.String(expiresAtAttribute)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":holder":    {S: aws.String(holder)},
+			":expiresAt": expiryValue(expiresAt),
+		},
+	})
+
+	if isConditionFailed(err) {
+		return ErrNotHeld
+	}
+
+	return err
+}
+
+// Release removes the holder's lease on key.
+func (b *DynamoDBBackend) Release(key, holder string) error {
+	_, err := b.Svc.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName:                 b.tableName(),
+		Key:                       itemKey(key),
+		ConditionExpression:       aws.String("#holder = :holder"),
+		ExpressionAttributeNames:  map[string]*string{"#holder": aws.String(holderAttribute)},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{":holder": {S: aws.String(holder)}},
+	})
+
+	if isConditionFailed(err) {
+		return ErrNotHeld
+	}
+
+	return err
+}
+
+// ForceRelease removes any lease on key.
+func (b *DynamoDBBackend) ForceRelease(key string) (*Lease, error) {
+	output, err := b.Svc.DeleteItem(&dynamodb.DeleteItemInput{
+		TableName:    b.tableName(),
+		Key:          itemKey(key),
+		ReturnValues: aws.String(dynamodb.ReturnValueAllOld),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return leaseFromItem(output.Attributes), nil
+}
+
+// get returns the lease on key, or nil if there is none.
+func (b *DynamoDBBackend) get(key string) (*Lease, error) {
+	output, err := b.Svc.GetItem(&dynamodb.GetItemInput{
+		TableName:      b.tableName(),
+		Key:            itemKey(key),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return leaseFromItem(output.Item), nil
+}
+
+func (b *DynamoDBBackend) tableName() *string {
+	return aws.String(b.Table)
+}
+
+func (b *DynamoDBBackend) names() map[string]*string {
+	return map[string]*string{
+		"#key":       aws.String(keyAttribute),
+		"#holder":    aws.String(holderAttribute),
+		"#expiresAt": aws.String(expiresAtAttribute),
+	}
+}
+
+func itemKey(key string) map[string]*dynamodb.AttributeValue {
+	return map[string]*dynamodb.AttributeValue{keyAttribute: {S: aws.String(key)}}
+}
+
+func unixValue(t time.Time) *dynamodb.AttributeValue {
+	return &dynamodb.AttributeValue{N: aws.String(strconv.FormatInt(t.Unix(), 10))}
+}
+
+// expiryValue is like unixValue, but rounds up, so that a lease never expires early.
+func expiryValue(t time.Time) *dynamodb.AttributeValue {
+	if t.Nanosecond() > 0 {
+		t = t.Truncate(time.Second).Add(time.Second)
+	}
+
+	return unixValue(t)
+}
+
+func unixTime(value *dynamodb.AttributeValue) time.Time {
+	if value == nil {
+		return time.Time{}
+	}
+
+	seconds, _ := strconv.ParseInt(aws.StringValue(value.N), 10, 64)
+	return time.Unix(seconds, 0).UTC()
+}
+
+// leaseFromItem reads a lease from its item, or returns nil if there is no item.
+func leaseFromItem(item map[string]*dynamodb.AttributeValue) *Lease {
+	if len(item) == 0 {
+		return nil
+	}
+
+	lease := &Lease{
+		AcquiredAt: unixTime(item[acquiredAtAttribute]),
+		ExpiresAt:  unixTime(item[expiresAtAttribute]),
+	}
+
+	if value := item[keyAttribute]; value != nil {
+		lease.Key = aws.StringValue(value.S)
+	}
+
+	if value := item[holderAttribute]; value != nil {
+		lease.Holder = aws.StringValue(value.S)
+	}
+
+	return lease
+}
+
+func isConditionFailed(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	return ok && awsErr.Code() == dynamodb.ErrCodeConditionalCheckFailedException
+}
@@ -0,0 +1,152 @@
+package lock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// guardTimeout is how long FileBackend waits for another process's update to the file, and
+// how old a guard has to be before it's taken to have been left behind by a crashed one.
+const guardTimeout = 10 * time.Second
+
+// FileBackend keeps leases, by key, in a JSON file, for cleaners that share a machine or a
+// filesystem. Updates are serialized by a guard file created alongside it.
+type FileBackend struct {
+	Path string
+}
+
+// Acquire stores the lease, unless another holder's unexpired lease on its key is stored.
+func (b *FileBackend) Acquire(lease Lease, now time.Time) error {
+	return b.update(func(leases map[string]Lease) error {
+		if current, ok := leases[lease.Key]; ok && current.Holder != lease.Holder && now.Before(current.ExpiresAt) {
+			return &HeldError{Lease: current}
+		}
+
+		leases[lease.Key] = lease
+		return nil
+	})
+}
+
+// Renew extends the holder's lease on key.
+func (b *FileBackend) Renew(key, holder string, expiresAt time.Time) error {
+	return b.update(func(leases map[string]Lease) error {
+		current, ok := leases[key]
+		if !ok || current.Holder != holder {
+			return ErrNotHeld
+		}
+
+		current.ExpiresAt = expiresAt
+		leases[key] = current
+		return nil
+	})
+}
+
+// Release removes the holder's lease on key.
+func (b *FileBackend) Release(key, holder string) error {
+	return b.update(func(leases map[string]Lease) error {
+		if current, ok := leases[key]; !ok || current.Holder != holder {
+			return ErrNotHeld
+		}
+
+		delete(leases, key)
+		return nil
+	})
+}
+
+// ForceRelease removes any lease on key.
+func (b *FileBackend) ForceRelease(key string) (*Lease, error) {
+	var released *Lease
+	err := b.update(func(leases map[string]Lease) error {
+		if current, ok := leases[key]; ok {
+			released = &current
+			delete(leases, key)
+		}
+
+		return nil
+	})
+
+	return released, err
+}
+
+// update reads the leases, lets fn change them, and writes them back if it succeeds, all
+// while holding the guard.
+func (b *FileBackend) update(fn func(leases map[string]Lease) error) error {
+	guard := b.Path + ".guard"
+	if err := acquireGuard(guard); err != nil {
+		return err
+	}
+	defer os.Remove(guard)
+
+	leases := make(map[string]Lease)
+
+	body, err := ioutil.ReadFile(b.Path)
+	switch {
+	case os.IsNotExist(err):
+
+	case err != nil:
+		return err
+
+	default:
+		if err := json.Unmarshal(body, &leases); err != nil {
+			return fmt.Errorf("%s: %v", b.Path, err)
+		}
+	}
+
+	if err := fn(leases); err != nil {
+		return err
+	}
+
+	if body, err = json.MarshalIndent(leases, "", "  "); err != nil {
+		return err
+	}
+
+	// write a temporary file and rename it, so that a crash never leaves the file half-written
+	tmp, err := ioutil.TempFile(filepath.Dir(b.Path), filepath.Base(b.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), b.Path)
+}
+
+// acquireGuard creates the guard file, waiting for any other process's to be removed.
+func acquireGuard(guard string) error {
+	deadline := time.Now().Add(guardTimeout)
+
+	for {
+		f, err := os.OpenFile(guard, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			return f.Close()
+		}
+
+		if !os.IsExist(err) {
+			return err
+		}
+
+		if info, err := os.Stat(guard); err == nil && time.Since(info.ModTime()) > guardTimeout {
+			os.Remove(guard)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for %s to be removed", guard)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
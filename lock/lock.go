@@ -0,0 +1,181 @@
+// Package lock keeps cleaners from running against the same account and region at once. A
+// Lock is a lease on a key, held in a Backend such as a DynamoDB table or a local file, that
+// its holder renews while it runs and that anyone may take over once it expires.
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+)
+
+// ErrNotHeld is returned when renewing or releasing a lock the holder no longer holds, e.g.
+// because it was forcibly unlocked, or its lease expired and someone else took it.
+var ErrNotHeld = errors.New("lock not held")
+
+// Lease records who holds a lock, and until when.
+type Lease struct {
+	Key        string    `json:"key"`
+	Holder     string    `json:"holder"`
+	AcquiredAt time.Time `json:"acquiredAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+// HeldError is returned when acquiring a lock that someone else holds.
+type HeldError struct {
+	Lease Lease
+}
+
+func (e *HeldError) Error() string {
+	return fmt.Sprintf("%s is locked by %s since %s, until %s unless renewed; use --force-unlock if it's stale",
+		e.Lease.Key, e.Lease.Holder, e.Lease.AcquiredAt.Format(time.RFC3339), e.Lease.ExpiresAt.Format(time.RFC3339))
+}
+
+// Backend stores leases.
+type Backend interface {
+	// Acquire stores the lease, unless another holder's unexpired lease on its key is
+	// stored, in which case it returns a *HeldError.
+	Acquire(lease Lease, now time.Time) error
+
+	// Renew extends the holder's lease on key, or returns ErrNotHeld.
+	Renew(key, holder string, expiresAt time.Time) error
+
+	// Release removes the holder's lease on key, or returns ErrNotHeld.
+	Release(key, holder string) error
+
+	// ForceRelease removes any lease on key, and returns it, or nil if there was none.
+	ForceRelease(key string) (*Lease, error)
+}
+
+// Holder identifies this process to whoever finds the lock held, as user@host (pid N).
+func Holder() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s@%s (pid %d)", name, host, os.Getpid())
+}
+
+// Lock is a lease on Key, held by Holder for TTL at a time, and renewed in the background
+// while it's held.
+type Lock struct {
+	Backend Backend
+	Key     string
+	Holder  string
+	TTL     time.Duration
+
+	// Now is the clock leases are stamped with; it defaults to the current UTC time.
+	Now func() time.Time
+
+	mu        sync.Mutex
+	err       error
+	expiresAt time.Time
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// New creates a Lock on key for holder, with leases lasting ttl.
+func New(backend Backend, key, holder string, ttl time.Duration) *Lock {
+	return &Lock{
+		Backend: backend,
+		Key:     key,
+		Holder:  holder,
+		TTL:     ttl,
+		Now:     func() time.Time { return time.Now().UTC() },
+	}
+}
+
+// Acquire takes the lock, or returns a *HeldError saying who holds it. Once taken, its lease
+// is renewed every third of its TTL until Release.
+func (l *Lock) Acquire() error {
+	now := l.Now()
+	lease := Lease{Key: l.Key, Holder: l.Holder, AcquiredAt: now, ExpiresAt: now.Add(l.TTL)}
+
+	if err := l.Backend.Acquire(lease, now); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.err, l.expiresAt = nil, lease.ExpiresAt
+	l.mu.Unlock()
+
+	l.stop, l.done = make(chan struct{}), make(chan struct{})
+	go l.renew()
+
+	return nil
+}
+
+// renew extends the lease until Release. A renewal that fails is retried at the next tick,
+// until the lease is found to be lost or runs out.
+func (l *Lock) renew() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(l.TTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-l.stop:
+			return
+
+		case <-ticker.C:
+			now := l.Now()
+			expiresAt := now.Add(l.TTL)
+			err := l.Backend.Renew(l.Key, l.Holder, expiresAt)
+
+			l.mu.Lock()
+			switch {
+			case err == nil:
+				l.expiresAt = expiresAt
+
+			case err == ErrNotHeld:
+				l.err = fmt.Errorf("lost the lock on %s: it was released or taken over", l.Key)
+
+			case !now.Before(l.expiresAt):
+				l.err = fmt.Errorf("lost the lock on %s: unable to renew it: %v", l.Key, err)
+			}
+			lost := l.err != nil
+			l.mu.Unlock()
+
+			if lost {
+				return
+			}
+		}
+	}
+}
+
+// Err returns why the lock was lost while held, if it was. A nil *Lock is never lost.
+func (l *Lock) Err() error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.err
+}
+
+// Release stops renewing the lease and gives the lock up. A lock already lost is left to its
+// new holder.
+func (l *Lock) Release() error {
+	close(l.stop)
+	<-l.done
+
+	if l.Err() != nil {
+		return nil
+	}
+
+	return l.Backend.Release(l.Key, l.Holder)
+}
+
+// ForceUnlock removes any lease on the lock, whoever holds it, and returns it, or nil if
+// there was none.
+func (l *Lock) ForceUnlock() (*Lease, error) {
+	return l.Backend.ForceRelease(l.Key)
+}
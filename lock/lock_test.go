@@ -0,0 +1,215 @@
+package lock
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbiface"
+)
+
+// fakeDynamoDB keeps items by LockKey, and evaluates the conditions DynamoDBBackend writes
+// with.
+type fakeDynamoDB struct {
+	dynamodbiface.DynamoDBAPI
+
+	mu    sync.Mutex
+	items map[string]map[string]*dynamodb.AttributeValue
+}
+
+func newFakeDynamoDB() *fakeDynamoDB {
+	return &fakeDynamoDB{items: make(map[string]map[string]*dynamodb.AttributeValue)}
+}
+
+var conditionFailed = awserr.New(dynamodb.ErrCodeConditionalCheckFailedException, "The conditional request failed", nil)
+
+// heldBy reports whether the item's holder is the :holder value.
+func heldBy(item map[string]*dynamodb.AttributeValue, values map[string]*dynamodb.AttributeValue) bool {
+	return item != nil && aws.StringValue(item[holderAttribute].S) == aws.StringValue(values[":holder"].S)
+}
+
+func number(value *dynamodb.AttributeValue) int64 {
+	n, _ := strconv.ParseInt(aws.StringValue(value.N), 10, 64)
+	return n
+}
+
+func (f *fakeDynamoDB) PutItem(input *dynamodb.PutItemInput) (*dynamodb.PutItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := aws.StringValue(input.Item[keyAttribute].S)
+	current := f.items[key]
+	values := input.ExpressionAttributeValues
+
+	if current != nil && !heldBy(current, values) && number(current[expiresAtAttribute]) > number(values[":now"]) {
+		return nil, conditionFailed
+	}
+
+	f.items[key] = input.Item
+	return &dynamodb.PutItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) UpdateItem(input *dynamodb.UpdateItemInput) (*dynamodb.UpdateItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := aws.StringValue(input.Key[keyAttribute].S)
+	if !heldBy(f.items[key], input.ExpressionAttributeValues) {
+		return nil, conditionFailed
+	}
+
+	f.items[key][expiresAtAttribute] = input.ExpressionAttributeValues[":expiresAt"]
+	return &dynamodb.UpdateItemOutput{}, nil
+}
+
+func (f *fakeDynamoDB) DeleteItem(input *dynamodb.DeleteItemInput) (*dynamodb.DeleteItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := aws.StringValue(input.Key[keyAttribute].S)
+	current := f.items[key]
+
+	if input.ConditionExpression != nil && !heldBy(current, input.ExpressionAttributeValues) {
+		return nil, conditionFailed
+	}
+
+	delete(f.items, key)
+	return &dynamodb.DeleteItemOutput{Attributes: current}, nil
+}
+
+func (f *fakeDynamoDB) GetItem(input *dynamodb.GetItemInput) (*dynamodb.GetItemOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return &dynamodb.GetItemOutput{Item: f.items[aws.StringValue(input.Key[keyAttribute].S)]}, nil
+}
+
+// backends runs fn against a fresh Backend of each kind.
+func backends(t *testing.T, fn func(t *testing.T, backend Backend)) {
+	dir, err := ioutil.TempDir("", "lock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Run("file", func(t *testing.T) {
+		fn(t, &FileBackend{Path: filepath.Join(dir, "lock.json")})
+	})
+
+	t.Run("dynamodb", func(t *testing.T) {
+		fn(t, &DynamoDBBackend{Svc: newFakeDynamoDB(), Table: "locks"})
+	})
+}
+
+func Test_Backend(t *testing.T) {
+	backends(t, func(t *testing.T, backend Backend) {
+		now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		key := "000000000000/us-west-2"
+
+		lease := Lease{Key: key, Holder: "cron", AcquiredAt: now, ExpiresAt: now.Add(time.Minute)}
+		if err := backend.Acquire(lease, now); err != nil {
+			t.Fatal(err)
+		}
+
+		laptop := Lease{Key: key, Holder: "laptop", AcquiredAt: now, ExpiresAt: now.Add(time.Minute)}
+		err := backend.Acquire(laptop, now.Add(30*time.Second))
+		if held, ok := err.(*HeldError); !ok || held.Lease.Holder != "cron" {
+			t.Fatalf("Expected the lock to be held by cron, got %v\n", err)
+		}
+
+		if err := backend.Acquire(Lease{Key: "000000000000/us-east-1", Holder: "laptop"}, now); err != nil {
+			t.Errorf("Expected another region's lock to be free, got %v\n", err)
+		}
+
+		if err := backend.Renew(key, "laptop", now.Add(time.Hour)); err != ErrNotHeld {
+			t.Errorf("Expected laptop not to be able to renew, got %v\n", err)
+		}
+
+		if err := backend.Renew(key, "cron", now.Add(2*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+
+		// renewed, so still held after the first lease would have run out
+		if err := backend.Acquire(laptop, now.Add(90*time.Second)); err == nil {
+			t.Error("Expected the renewed lock to still be held")
+		}
+
+		// expired, so anyone may take it over
+		if err := backend.Acquire(laptop, now.Add(3*time.Minute)); err != nil {
+			t.Fatalf("Expected to take over the expired lock, got %v\n", err)
+		}
+
+		if err := backend.Release(key, "cron"); err != ErrNotHeld {
+			t.Errorf("Expected cron not to be able to release laptop's lock, got %v\n", err)
+		}
+
+		released, err := backend.ForceRelease(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if released == nil || released.Holder != "laptop" {
+			t.Errorf("Expected to release laptop's lease, got %v\n", released)
+		}
+
+		if err := backend.Acquire(lease, now.Add(3*time.Minute)); err != nil {
+			t.Errorf("Expected the lock to be free after forcibly releasing it, got %v\n", err)
+		}
+
+		if err := backend.Release(key, "cron"); err != nil {
+			t.Error(err)
+		}
+	})
+}
+
+func Test_Lock(t *testing.T) {
+	backends(t, func(t *testing.T, backend Backend) {
+		cron := New(backend, "000000000000/us-west-2", "cron", 1200*time.Millisecond)
+		laptop := New(backend, "000000000000/us-west-2", "laptop", 1200*time.Millisecond)
+
+		if err := cron.Acquire(); err != nil {
+			t.Fatal(err)
+		}
+
+		// outlasts the first lease, so only renewal keeps cron holding the lock
+		time.Sleep(1500 * time.Millisecond)
+
+		if err := laptop.Acquire(); err == nil {
+			t.Fatal("Expected the renewed lock to still be held")
+		}
+
+		if err := cron.Err(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := cron.Release(); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := laptop.Acquire(); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := cron.ForceUnlock(); err != nil {
+			t.Fatal(err)
+		}
+
+		// laptop finds out at its next renewal
+		time.Sleep(600 * time.Millisecond)
+
+		if err := laptop.Err(); err == nil {
+			t.Error("Expected laptop to have lost the lock")
+		}
+
+		if err := laptop.Release(); err != nil {
+			t.Errorf("Expected releasing a lost lock to do nothing, got %v\n", err)
+		}
+	})
+}
@@ -0,0 +1,195 @@
+// Package metrics instruments cleaner runs for Prometheus: the AWS API calls they make, the
+// throttling they run into, what they find and do to task definitions, and how long they
+// take. A long-running cleaner serves them over HTTP; a one-shot run pushes them to a
+// Pushgateway when it's done.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// The outcomes counted by the task definitions gauge.
+const (
+	// Discovered: ACTIVE when the run started.
+	Discovered = "discovered"
+	// Kept: kept by the plan.
+	Kept = "kept"
+	// Pending: held back by the plan until its grace period ends.
+	Pending = "pending"
+	// Planned: to be deregistered, according to the plan.
+	Planned = "planned"
+	// Deregistered: deregistered.
+	Deregistered = "deregistered"
+	// Failed: deregistering it, or archiving it first, failed.
+	Failed = "failed"
+	// Skipped: planned, then left alone, e.g. because it became active.
+	Skipped = "skipped"
+)
+
+// Metrics holds a cleaner's collectors, in a Registry of their own. Its methods do nothing on
+// a nil *Metrics, so an uninstrumented ECSClient can call them freely.
+type Metrics struct {
+	Registry *prometheus.Registry
+
+	apiCalls        *prometheus.CounterVec
+	apiCallDuration *prometheus.HistogramVec
+	throttles       *prometheus.CounterVec
+	backoff         prometheus.Histogram
+	taskDefinitions *prometheus.GaugeVec
+	deregistrations prometheus.Counter
+	runDuration     *prometheus.HistogramVec
+	lastSuccess     prometheus.Gauge
+}
+
+// New creates a Metrics with every collector registered.
+func New() *Metrics {
+	m := &Metrics{
+		Registry: prometheus.NewRegistry(),
+		apiCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ecs_cleaner_api_calls_total",
+			Help: "AWS API call attempts, by service, operation and error code (empty on success).",
+		}, []string{"service", "operation", "error_code"}),
+		apiCallDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ecs_cleaner_api_call_duration_seconds",
+			Help:    "Latency of AWS API call attempts, by service and operation.",
+			Buckets: prometheus.ExponentialBuckets(0.01, 2, 12),
+		}, []string{"service", "operation"}),
+		throttles: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ecs_cleaner_api_throttles_total",
+			Help: "AWS API call attempts that were throttled, by service and operation.",
+		}, []string{"service", "operation"}),
+		backoff: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "ecs_cleaner_backoff_seconds",
+			Help:    "Time spent backing off after throttling, per wait.",
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 12),
+		}),
+		taskDefinitions: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "ecs_cleaner_task_definitions",
+			Help: "Task definitions in the last run, by outcome.",
+		}, []string{"outcome"}),
+		deregistrations: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "ecs_cleaner_deregistrations_total",
+			Help: "Task definitions deregistered.",
+		}),
+		runDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ecs_cleaner_run_duration_seconds",
+			Help:    "Duration of cleanup runs, by result.",
+			Buckets: prometheus.ExponentialBuckets(1, 2, 14),
+		}, []string{"result"}),
+		lastSuccess: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "ecs_cleaner_last_success_timestamp_seconds",
+			Help: "When the last successful run finished, in Unix time.",
+		}),
+	}
+
+	m.Registry.MustRegister(
+		m.apiCalls,
+		m.apiCallDuration,
+		m.throttles,
+		m.backoff,
+		m.taskDefinitions,
+		m.deregistrations,
+		m.runDuration,
+		m.lastSuccess,
+	)
+
+	return m
+}
+
+// Handler serves the Metrics in the Prometheus exposition format, e.g. at /metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.Registry, promhttp.HandlerOpts{})
+}
+
+// Push replaces the Metrics a Pushgateway holds for the job with the current ones.
+func (m *Metrics) Push(url, job string) error {
+	return push.New(url, job).Gatherer(m.Registry).Push()
+}
+
+// Instrument records every API call attempt made by AWS clients with these handlers, e.g. a
+// session's, counting each retry the SDK makes on its own.
+func (m *Metrics) Instrument(handlers *request.Handlers) {
+	if m == nil {
+		return
+	}
+
+	handlers.CompleteAttempt.PushBack(func(r *request.Request) {
+		service, operation := r.ClientInfo.ServiceName, r.Operation.Name
+
+		var code string
+		if awsErr, ok := r.Error.(awserr.Error); ok {
+			code = awsErr.Code()
+		} else if r.Error != nil {
+			code = "Unknown"
+		}
+
+		m.apiCalls.WithLabelValues(service, operation, code).Inc()
+		m.apiCallDuration.WithLabelValues(service, operation).Observe(time.Since(r.AttemptTime).Seconds())
+
+		if request.IsErrorThrottle(r.Error) {
+			m.throttles.WithLabelValues(service, operation).Inc()
+		}
+	})
+}
+
+// ObserveBackoff records a wait after throttling.
+func (m *Metrics) ObserveBackoff(d time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.backoff.Observe(d.Seconds())
+}
+
+// SetTaskDefinitions records how many task definitions had an outcome in this run.
+func (m *Metrics) SetTaskDefinitions(outcome string, n int) {
+	if m == nil {
+		return
+	}
+
+	m.taskDefinitions.WithLabelValues(outcome).Set(float64(n))
+}
+
+// ResetTaskDefinitions zeroes every outcome, so that a run which stops early doesn't leave the
+// last run's counts behind.
+func (m *Metrics) ResetTaskDefinitions() {
+	if m == nil {
+		return
+	}
+
+	for _, outcome := range []string{Discovered, Kept, Pending, Planned, Deregistered, Failed, Skipped} {
+		m.taskDefinitions.WithLabelValues(outcome).Set(0)
+	}
+}
+
+// AddDeregistrations counts task definitions deregistered.
+func (m *Metrics) AddDeregistrations(n int) {
+	if m == nil {
+		return
+	}
+
+	m.deregistrations.Add(float64(n))
+}
+
+// ObserveRun records a run that started at start and ended in err.
+func (m *Metrics) ObserveRun(start time.Time, err error) {
+	if m == nil {
+		return
+	}
+
+	result := "success"
+	if err != nil {
+		result = "failure"
+	} else {
+		m.lastSuccess.SetToCurrentTime()
+	}
+
+	m.runDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+}
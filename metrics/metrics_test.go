@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/client/metadata"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func Test_Instrument(t *testing.T) {
+	m := New()
+
+	var handlers request.Handlers
+	m.Instrument(&handlers)
+
+	for _, err := range []error{
+		nil,
+		awserr.New("ThrottlingException", "Rate exceeded", nil),
+		awserr.New("ClientException", "denied", nil),
+		errors.New("connection reset"),
+	} {
+		handlers.CompleteAttempt.Run(&request.Request{
+			ClientInfo:  metadata.ClientInfo{ServiceName: "ecs"},
+			Operation:   &request.Operation{Name: "ListClusters"},
+			Error:       err,
+			AttemptTime: time.Now(),
+		})
+	}
+
+	for code, expected := range map[string]float64{"": 1, "ThrottlingException": 1, "ClientException": 1, "Unknown": 1} {
+		if result := testutil.ToFloat64(m.apiCalls.WithLabelValues("ecs", "ListClusters", code)); result != expected {
+			t.Errorf("Expected %v calls with error code %q, got %v\n", expected, code, result)
+		}
+	}
+
+	if result := testutil.ToFloat64(m.throttles.WithLabelValues("ecs", "ListClusters")); result != 1 {
+		t.Errorf("Expected 1 throttle, got %v\n", result)
+	}
+
+	if result := testutil.CollectAndCount(m.apiCallDuration); result != 1 {
+		t.Errorf("Expected 1 latency series, got %v\n", result)
+	}
+}
+
+func Test_Nil(t *testing.T) {
+	var m *Metrics
+
+	// an uninstrumented ECSClient calls these freely
+	m.Instrument(&request.Handlers{})
+	m.ObserveBackoff(time.Second)
+	m.SetTaskDefinitions(Kept, 1)
+	m.ResetTaskDefinitions()
+	m.AddDeregistrations(1)
+	m.ObserveRun(time.Now(), nil)
+}
+
+func Test_ResetTaskDefinitions(t *testing.T) {
+	m := New()
+	m.SetTaskDefinitions(Deregistered, 3)
+	m.ResetTaskDefinitions()
+
+	for _, outcome := range []string{Discovered, Kept, Pending, Planned, Deregistered, Failed, Skipped} {
+		if result := testutil.ToFloat64(m.taskDefinitions.WithLabelValues(outcome)); result != 0 {
+			t.Errorf("Expected no %s task definitions, got %v\n", outcome, result)
+		}
+	}
+}
+
+func Test_Handler(t *testing.T) {
+	m := New()
+	m.SetTaskDefinitions(Deregistered, 3)
+	m.AddDeregistrations(3)
+	m.ObserveBackoff(time.Second)
+	m.ObserveRun(time.Now(), nil)
+
+	recorder := httptest.NewRecorder()
+	m.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	for _, expected := range []string{
+		`ecs_cleaner_task_definitions{outcome="deregistered"} 3`,
+		`ecs_cleaner_deregistrations_total 3`,
+		`ecs_cleaner_backoff_seconds_count 1`,
+		`ecs_cleaner_run_duration_seconds_count{result="success"} 1`,
+		`ecs_cleaner_last_success_timestamp_seconds `,
+	} {
+		if !strings.Contains(recorder.Body.String(), expected) {
+			t.Errorf("Expected %q in:\n%s", expected, recorder.Body.String())
+		}
+	}
+}
+
+func Test_Push(t *testing.T) {
+	var method, path, body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		method, path, body = r.Method, r.URL.Path, string(b)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	m := New()
+	m.ObserveRun(time.Now(), errors.New("boom"))
+
+	if err := m.Push(server.URL, "go-ecs-cleaner"); err != nil {
+		t.Fatal(err)
+	}
+
+	if method != "PUT" || path != "/metrics/job/go-ecs-cleaner" {
+		t.Errorf("Expected PUT /metrics/job/go-ecs-cleaner, got %s %s\n", method, path)
+	}
+
+	if !strings.Contains(body, "ecs_cleaner_run_duration_seconds") {
+		t.Error("Expected the run duration to be pushed")
+	}
+}
@@ -0,0 +1,238 @@
+// Package notify tells people what a cleanup run did, by sending a Summary of it to Sinks
+// such as a Slack channel, a webhook, an SNS topic or an EventBridge bus.
+package notify
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/quintilesims/go-ecs-cleaner/planner"
+)
+
+// When a Notifier sends a Summary.
+const (
+	// Always: after every run.
+	Always = "always"
+	// OnApply: after runs with `--apply`, whatever their result.
+	OnApply = "apply"
+	// OnFailure: after runs that fail, or fail to deregister any task definition.
+	OnFailure = "failure"
+)
+
+// The Results of a run.
+const (
+	Succeeded = "success"
+	Failed    = "failure"
+)
+
+// TopFamilies is how many of the families with the most task definitions affected a Summary
+// lists.
+const TopFamilies = 5
+
+// MaxFailures is how many failures a Summary lists, so that it stays well within the 256 KB
+// that SNS and EventBridge accept.
+const MaxFailures = 100
+
+// Counts are how many task definitions had each outcome in a run.
+type Counts struct {
+	Discovered   int `json:"discovered"`
+	Kept         int `json:"kept"`
+	Pending      int `json:"pending"`
+	Planned      int `json:"planned"`
+	Deregistered int `json:"deregistered"`
+	Failed       int `json:"failed"`
+	Skipped      int `json:"skipped"`
+}
+
+// Family is a family and how many of its task definitions a run affected.
+type Family struct {
+	Family string `json:"family"`
+	Count  int    `json:"count"`
+}
+
+// Failure is a task definition a run failed to deregister.
+type Failure struct {
+	ARN   string `json:"arn"`
+	Error string `json:"error"`
+}
+
+// Summary describes a cleanup run.
+type Summary struct {
+	Region     string    `json:"region,omitempty"`
+	Apply      bool      `json:"apply"`
+	StartedAt  time.Time `json:"startedAt"`
+	FinishedAt time.Time `json:"finishedAt"`
+	Result     string    `json:"result"`
+	Error      string    `json:"error,omitempty"`
+
+	// LimitTripped is the safety limit the plan tripped, if it tripped one.
+	LimitTripped string `json:"limitTripped,omitempty"`
+
+	Counts Counts `json:"counts"`
+
+	// Families are the families with the most task definitions deregistered, or, in a dry
+	// run, planned for deregistration.
+	Families []Family `json:"topFamilies"`
+
+	Failures []Failure `json:"failures"`
+
+	// MoreFailures is how many failures there were beyond those listed.
+	MoreFailures int `json:"moreFailures,omitempty"`
+}
+
+// SetFamilies sets the Summary's Families to the TopFamilies of the task definitions ARNs,
+// most affected first.
+func (s *Summary) SetFamilies(arns []string) {
+	counts := make(map[string]int)
+	for _, arn := range arns {
+		family := arn
+		if parsed, err := planner.ParseTaskDefinitionARN(arn); err == nil {
+			family = parsed.Family
+		}
+
+		counts[family]++
+	}
+
+	families := make([]Family, 0, len(counts))
+	for family, count := range counts {
+		families = append(families, Family{Family: family, Count: count})
+	}
+
+	sort.Slice(families, func(i, j int) bool {
+		if families[i].Count != families[j].Count {
+			return families[i].Count > families[j].Count
+		}
+
+		return families[i].Family < families[j].Family
+	})
+
+	if len(families) > TopFamilies {
+		families = families[:TopFamilies]
+	}
+
+	s.Families = families
+}
+
+// SetFailures sets the Summary's Failures to the first MaxFailures of failures, and counts the
+// rest in MoreFailures.
+func (s *Summary) SetFailures(failures []Failure) {
+	s.Failures, s.MoreFailures = failures, 0
+
+	if len(failures) > MaxFailures {
+		s.Failures, s.MoreFailures = failures[:MaxFailures], len(failures)-MaxFailures
+	}
+}
+
+// Title is a one-line description of the run.
+func (s Summary) Title() string {
+	mode := "dry run"
+	if s.Apply {
+		mode = "run"
+	}
+
+	where := ""
+	if s.Region != "" {
+		where = " in " + s.Region
+	}
+
+	return fmt.Sprintf("go-ecs-cleaner %s%s: %s", mode, where, s.Result)
+}
+
+// Text describes the run in a few lines of plain text.
+func (s Summary) Text() string {
+	var b strings.Builder
+	fmt.Fprintln(&b, s.Title())
+
+	c := s.Counts
+	if s.Apply {
+		fmt.Fprintf(&b, "%d deregistered, %d failed, %d skipped of %d planned", c.Deregistered, c.Failed, c.Skipped, c.Planned)
+	} else {
+		fmt.Fprintf(&b, "%d planned for deregistration", c.Planned)
+	}
+
+	fmt.Fprintf(&b, "; %d discovered, %d kept, %d pending.\n", c.Discovered, c.Kept, c.Pending)
+
+	if len(s.Families) > 0 {
+		families := make([]string, len(s.Families))
+		for i, f := range s.Families {
+			families[i] = fmt.Sprintf("%s (%d)", f.Family, f.Count)
+		}
+
+		fmt.Fprintf(&b, "Top families: %s\n", strings.Join(families, ", "))
+	}
+
+	if s.LimitTripped != "" {
+		fmt.Fprintf(&b, "Safety limit tripped: %s\n", s.LimitTripped)
+	} else if s.Error != "" {
+		fmt.Fprintf(&b, "Error: %s\n", s.Error)
+	}
+
+	for _, f := range s.Failures {
+		fmt.Fprintf(&b, "Failed: %s (%s)\n", f.ARN, f.Error)
+	}
+
+	if s.MoreFailures > 0 {
+		fmt.Fprintf(&b, "...and %d more failures.\n", s.MoreFailures)
+	}
+
+	return b.String()
+}
+
+// Sink delivers Summaries somewhere.
+type Sink interface {
+	// Name identifies the Sink in errors.
+	Name() string
+	Send(s Summary) error
+}
+
+// Notifier sends Summaries to its Sinks, On the runs chosen.
+type Notifier struct {
+	On    string
+	Sinks []Sink
+}
+
+// ParseOn checks that on is one of Always, OnApply and OnFailure.
+func ParseOn(on string) (string, error) {
+	switch on {
+	case Always, OnApply, OnFailure:
+		return on, nil
+	}
+
+	return "", fmt.Errorf("invalid notify-on %q: must be %s, %s or %s", on, Always, OnApply, OnFailure)
+}
+
+// Wants reports whether the Notifier sends the Summary.
+func (n *Notifier) Wants(s Summary) bool {
+	switch n.On {
+	case OnApply:
+		return s.Apply
+	case OnFailure:
+		// failed deregistrations don't fail the run, but are failures all the same
+		return s.Result == Failed || s.Counts.Failed > 0
+	}
+
+	return true
+}
+
+// Notify sends the Summary to every Sink, if the Notifier Wants it. A Sink that fails doesn't
+// stop the others; their errors are returned together.
+func (n *Notifier) Notify(s Summary) error {
+	if !n.Wants(s) {
+		return nil
+	}
+
+	var errs []string
+	for _, sink := range n.Sinks {
+		if err := sink.Send(s); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", sink.Name(), err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("unable to notify %s", strings.Join(errs, "; "))
+	}
+
+	return nil
+}
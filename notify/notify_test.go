@@ -0,0 +1,279 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+)
+
+func testSummary() Summary {
+	s := Summary{
+		Region:     "us-west-2",
+		Apply:      true,
+		StartedAt:  time.Date(2026, 1, 1, 3, 0, 0, 0, time.UTC),
+		FinishedAt: time.Date(2026, 1, 1, 3, 5, 0, 0, time.UTC),
+		Result:     Succeeded,
+		Counts:     Counts{Discovered: 10, Kept: 6, Planned: 4, Deregistered: 3, Failed: 1},
+		Failures:   []Failure{{ARN: "arn:aws:ecs:us-west-2:000000000000:task-definition/web:1", Error: "ClientException: denied"}},
+	}
+
+	s.SetFamilies([]string{
+		"arn:aws:ecs:us-west-2:000000000000:task-definition/web:1",
+		"arn:aws:ecs:us-west-2:000000000000:task-definition/web:2",
+		"arn:aws:ecs:us-west-2:000000000000:task-definition/worker:1",
+		"arn:aws:ecs:us-west-2:000000000000:task-definition/api:1",
+	})
+
+	return s
+}
+
+func Test_SetFamilies(t *testing.T) {
+	expected := []Family{{Family: "web", Count: 2}, {Family: "api", Count: 1}, {Family: "worker", Count: 1}}
+	if result := testSummary().Families; !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, got %v\n", expected, result)
+	}
+
+	var arns []string
+	for i := 0; i < TopFamilies+2; i++ {
+		arns = append(arns, fmt.Sprintf("arn:aws:ecs:us-west-2:000000000000:task-definition/family%d:1", i))
+	}
+
+	var s Summary
+	s.SetFamilies(arns)
+
+	if len(s.Families) != TopFamilies {
+		t.Errorf("Expected %d families, got %v\n", TopFamilies, s.Families)
+	}
+}
+
+func Test_SetFailures(t *testing.T) {
+	failures := make([]Failure, MaxFailures+3)
+	for i := range failures {
+		failures[i] = Failure{ARN: fmt.Sprintf("arn:aws:ecs:us-west-2:000000000000:task-definition/web:%d", i+1), Error: "ClientException: denied"}
+	}
+
+	s := testSummary()
+	s.SetFailures(failures)
+
+	if len(s.Failures) != MaxFailures || s.MoreFailures != 3 {
+		t.Errorf("Expected %d failures and 3 more, got %d and %d\n", MaxFailures, len(s.Failures), s.MoreFailures)
+	}
+
+	if text := s.Text(); !strings.Contains(text, "...and 3 more failures.") {
+		t.Errorf("Expected the rest counted in:\n%s", text)
+	}
+
+	s.SetFailures(failures[:1])
+
+	if len(s.Failures) != 1 || s.MoreFailures != 0 {
+		t.Errorf("Expected 1 failure and no more, got %d and %d\n", len(s.Failures), s.MoreFailures)
+	}
+}
+
+func Test_Text(t *testing.T) {
+	text := testSummary().Text()
+
+	for _, expected := range []string{
+		"go-ecs-cleaner run in us-west-2: success",
+		"3 deregistered, 1 failed, 0 skipped of 4 planned",
+		"Top families: web (2), api (1), worker (1)",
+		"Failed: arn:aws:ecs:us-west-2:000000000000:task-definition/web:1 (ClientException: denied)",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected %q in:\n%s", expected, text)
+		}
+	}
+}
+
+func Test_Wants(t *testing.T) {
+	testCases := map[string]struct {
+		on       string
+		apply    bool
+		result   string
+		failed   int
+		expected bool
+	}{
+		"always":             {on: Always, result: Succeeded, expected: true},
+		"apply, dry run":     {on: OnApply, result: Failed, expected: false},
+		"apply, applied":     {on: OnApply, apply: true, result: Succeeded, expected: true},
+		"failure, succeeded": {on: OnFailure, apply: true, result: Succeeded, expected: false},
+		"failure, failed":    {on: OnFailure, result: Failed, expected: true},
+		"failure, failures":  {on: OnFailure, apply: true, result: Succeeded, failed: 2, expected: true},
+	}
+
+	for name, testCase := range testCases {
+		n := &Notifier{On: testCase.on}
+		if result := n.Wants(Summary{Apply: testCase.apply, Result: testCase.result, Counts: Counts{Failed: testCase.failed}}); result != testCase.expected {
+			t.Errorf("%s: expected %t, got %t\n", name, testCase.expected, result)
+		}
+	}
+
+	if _, err := ParseOn("sometimes"); err == nil {
+		t.Error("Expected an error for an unknown notify-on")
+	}
+}
+
+// standIn records the bodies posted to it, and answers with status.
+func standIn(status int) (*httptest.Server, *[][]byte) {
+	var bodies [][]byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, body)
+		w.WriteHeader(status)
+	}))
+
+	return server, &bodies
+}
+
+func Test_SlackSink(t *testing.T) {
+	server, bodies := standIn(http.StatusOK)
+	defer server.Close()
+
+	if err := (&SlackSink{URL: server.URL}).Send(testSummary()); err != nil {
+		t.Fatal(err)
+	}
+
+	var message struct{ Text string }
+	if err := json.Unmarshal((*bodies)[0], &message); err != nil {
+		t.Fatal(err)
+	}
+
+	if message.Text != testSummary().Text() {
+		t.Errorf("Expected the summary's text, got %q\n", message.Text)
+	}
+}
+
+func Test_WebhookSink(t *testing.T) {
+	server, bodies := standIn(http.StatusAccepted)
+	defer server.Close()
+
+	if err := (&WebhookSink{URL: server.URL}).Send(testSummary()); err != nil {
+		t.Fatal(err)
+	}
+
+	var result Summary
+	if err := json.Unmarshal((*bodies)[0], &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := testSummary(); !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, got %v\n", expected, result)
+	}
+
+	failing, _ := standIn(http.StatusInternalServerError)
+	defer failing.Close()
+
+	if err := (&WebhookSink{URL: failing.URL}).Send(testSummary()); err == nil {
+		t.Error("Expected an error for a 500")
+	}
+}
+
+type fakeSNS struct {
+	snsiface.SNSAPI
+	inputs []*sns.PublishInput
+}
+
+func (f *fakeSNS) Publish(input *sns.PublishInput) (*sns.PublishOutput, error) {
+	f.inputs = append(f.inputs, input)
+	return &sns.PublishOutput{}, nil
+}
+
+func Test_SNSSink(t *testing.T) {
+	svc := &fakeSNS{}
+	topic := "arn:aws:sns:us-west-2:000000000000:cleanups"
+
+	if err := (&SNSSink{Svc: svc, TopicARN: topic}).Send(testSummary()); err != nil {
+		t.Fatal(err)
+	}
+
+	input := svc.inputs[0]
+	if aws.StringValue(input.TopicArn) != topic || aws.StringValue(input.Subject) != testSummary().Title() {
+		t.Errorf("Expected a message titled %q to %s, got %v\n", testSummary().Title(), topic, input)
+	}
+
+	var result Summary
+	if err := json.Unmarshal([]byte(aws.StringValue(input.Message)), &result); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type fakeEventBridge struct {
+	eventbridgeiface.EventBridgeAPI
+	inputs []*eventbridge.PutEventsInput
+	failed bool
+}
+
+func (f *fakeEventBridge) PutEvents(input *eventbridge.PutEventsInput) (*eventbridge.PutEventsOutput, error) {
+	f.inputs = append(f.inputs, input)
+
+	if f.failed {
+		return &eventbridge.PutEventsOutput{
+			FailedEntryCount: aws.Int64(1),
+			Entries:          []*eventbridge.PutEventsResultEntry{{ErrorCode: aws.String("InternalFailure"), ErrorMessage: aws.String("boom")}},
+		}, nil
+	}
+
+	return &eventbridge.PutEventsOutput{FailedEntryCount: aws.Int64(0)}, nil
+}
+
+func Test_EventBridgeSink(t *testing.T) {
+	svc := &fakeEventBridge{}
+
+	if err := (&EventBridgeSink{Svc: svc, EventBus: "default"}).Send(testSummary()); err != nil {
+		t.Fatal(err)
+	}
+
+	entry := svc.inputs[0].Entries[0]
+	if aws.StringValue(entry.Source) != EventSource || aws.StringValue(entry.DetailType) != EventDetailType || aws.StringValue(entry.EventBusName) != "default" {
+		t.Errorf("Expected a %s event from %s on the default bus, got %v\n", EventDetailType, EventSource, entry)
+	}
+
+	svc.failed = true
+	if err := (&EventBridgeSink{Svc: svc, EventBus: "default"}).Send(testSummary()); err == nil {
+		t.Error("Expected an error for a failed entry")
+	}
+}
+
+type failingSink struct{}
+
+func (failingSink) Name() string         { return "failing" }
+func (failingSink) Send(s Summary) error { return errors.New("boom") }
+
+func Test_Notify(t *testing.T) {
+	server, bodies := standIn(http.StatusOK)
+	defer server.Close()
+
+	n := &Notifier{On: OnFailure, Sinks: []Sink{failingSink{}, &WebhookSink{URL: server.URL}}}
+
+	succeeded := testSummary()
+	succeeded.Counts.Failed, succeeded.Failures = 0, nil
+
+	if err := n.Notify(succeeded); err != nil || len(*bodies) != 0 {
+		t.Errorf("Expected nothing sent for a successful run, got %v, %d\n", err, len(*bodies))
+	}
+
+	failed := testSummary()
+	failed.Result = Failed
+
+	err := n.Notify(failed)
+	if err == nil || !strings.Contains(err.Error(), "failing: boom") {
+		t.Errorf("Expected the failing sink's error, got %v\n", err)
+	}
+
+	if len(*bodies) != 1 {
+		t.Errorf("Expected the webhook to be sent despite the failing sink, got %d\n", len(*bodies))
+	}
+}
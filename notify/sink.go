@@ -0,0 +1,171 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/eventbridge"
+	"github.com/aws/aws-sdk-go/service/eventbridge/eventbridgeiface"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sns/snsiface"
+)
+
+// The Source and DetailType of the events EventBridgeSink puts.
+const (
+	EventSource     = "go-ecs-cleaner"
+	EventDetailType = "ECS Cleanup Run"
+)
+
+// PostTimeout bounds each post by a SlackSink or WebhookSink without a Client of its own, so
+// an endpoint that never answers can't hang a run.
+const PostTimeout = 30 * time.Second
+
+// defaultClient posts for Sinks without a Client.
+var defaultClient = &http.Client{Timeout: PostTimeout}
+
+// SlackSink posts the Summary's Text to a Slack incoming webhook.
+type SlackSink struct {
+	URL string
+
+	// Client posts the Summary; without one, posts time out after PostTimeout.
+	Client *http.Client
+}
+
+// Name is "slack".
+func (s *SlackSink) Name() string {
+	return "slack"
+}
+
+// Send posts the Summary.
+func (s *SlackSink) Send(summary Summary) error {
+	body, err := json.Marshal(map[string]string{"text": summary.Text()})
+	if err != nil {
+		return err
+	}
+
+	return post(s.Client, s.URL, body)
+}
+
+// WebhookSink posts the Summary, as JSON, to a URL.
+type WebhookSink struct {
+	URL string
+
+	// Client posts the Summary; without one, posts time out after PostTimeout.
+	Client *http.Client
+}
+
+// Name is "webhook".
+func (s *WebhookSink) Name() string {
+	return "webhook"
+}
+
+// Send posts the Summary.
+func (s *WebhookSink) Send(summary Summary) error {
+	body, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+
+	return post(s.Client, s.URL, body)
+}
+
+// post sends a JSON body to a URL, and fails unless the response is a 2xx.
+func post(client *http.Client, url string, body []byte) error {
+	if client == nil {
+		client = defaultClient
+	}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		text, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(text))
+	}
+
+	return nil
+}
+
+// SNSSink publishes the Summary, as JSON, to an SNS topic, with its Title as the subject.
+type SNSSink struct {
+	Svc      snsiface.SNSAPI
+	TopicARN string
+}
+
+// Name is "sns".
+func (s *SNSSink) Name() string {
+	return "sns"
+}
+
+// Send publishes the Summary.
+func (s *SNSSink) Send(summary Summary) error {
+	body, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+
+	// SNS subjects are limited to 100 characters
+	subject := summary.Title()
+	if len(subject) > 100 {
+		subject = subject[:100]
+	}
+
+	_, err = s.Svc.Publish(&sns.PublishInput{
+		Message:  aws.String(string(body)),
+		Subject:  aws.String(subject),
+		TopicArn: aws.String(s.TopicARN),
+	})
+
+	return err
+}
+
+// EventBridgeSink puts the Summary, as an event's detail, on an EventBridge bus.
+type EventBridgeSink struct {
+	Svc eventbridgeiface.EventBridgeAPI
+
+	// EventBus is the bus's name or ARN; "default" is the account's default bus.
+	EventBus string
+}
+
+// Name is "eventbridge".
+func (s *EventBridgeSink) Name() string {
+	return "eventbridge"
+}
+
+// Send puts the Summary.
+func (s *EventBridgeSink) Send(summary Summary) error {
+	body, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+
+	output, err := s.Svc.PutEvents(&eventbridge.PutEventsInput{
+		Entries: []*eventbridge.PutEventsRequestEntry{{
+			Detail:       aws.String(string(body)),
+			DetailType:   aws.String(EventDetailType),
+			EventBusName: aws.String(s.EventBus),
+			Source:       aws.String(EventSource),
+			Time:         aws.Time(summary.FinishedAt),
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	// PutEvents reports entries it couldn't put in its output, not as an error
+	if aws.Int64Value(output.FailedEntryCount) > 0 && len(output.Entries) > 0 {
+		entry := output.Entries[0]
+		return fmt.Errorf("%s: %s", aws.StringValue(entry.ErrorCode), aws.StringValue(entry.ErrorMessage))
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package planner
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TaskDefinitionARN is a parsed task definition ARN, e.g.
+// "arn:aws:ecs:us-west-2:123456789012:task-definition/web:5".
+type TaskDefinitionARN struct {
+	Partition string
+	Region    string
+	Account   string
+	Family    string
+	Revision  int64
+}
+
+// ParseTaskDefinitionARN parses a task definition ARN.
+func ParseTaskDefinitionARN(arn string) (TaskDefinitionARN, error) {
+	fields := strings.SplitN(arn, ":", 6)
+	if len(fields) != 6 || fields[0] != "arn" || fields[2] != "ecs" {
+		return TaskDefinitionARN{}, fmt.Errorf("%q is not an ECS ARN", arn)
+	}
+
+	resource := fields[5]
+	if !strings.HasPrefix(resource, "task-definition/") {
+		return TaskDefinitionARN{}, fmt.Errorf("%q is not a task definition ARN", arn)
+	}
+
+	family, revision, err := parseFamilyRevision(strings.TrimPrefix(resource, "task-definition/"))
+	if err != nil {
+		return TaskDefinitionARN{}, fmt.Errorf("%q: %v", arn, err)
+	}
+
+	return TaskDefinitionARN{
+		Partition: fields[1],
+		Region:    fields[3],
+		Account:   fields[4],
+		Family:    family,
+		Revision:  revision,
+	}, nil
+}
+
+// String formats the ARN.
+func (a TaskDefinitionARN) String() string {
+	return fmt.Sprintf("arn:%s:ecs:%s:%s:task-definition/%s:%d", a.Partition, a.Region, a.Account, a.Family, a.Revision)
+}
+
+// parseFamilyRevision splits "family:revision", validating both halves.
+func parseFamilyRevision(s string) (string, int64, error) {
+	i := strings.LastIndex(s, ":")
+	if i < 0 {
+		return "", 0, fmt.Errorf("missing revision")
+	}
+
+	family := s[:i]
+	if !validFamily(family) {
+		return "", 0, fmt.Errorf("invalid family %q", family)
+	}
+
+	revision, err := strconv.ParseInt(s[i+1:], 10, 64)
+	if err != nil || revision < 1 {
+		return "", 0, fmt.Errorf("invalid revision %q", s[i+1:])
+	}
+
+	return family, revision, nil
+}
+
+// validFamily reports whether s is a legal family name: up to 255 letters, numbers, hyphens
+// and underscores.
+func validFamily(s string) bool {
+	if len(s) == 0 || len(s) > 255 {
+		return false
+	}
+
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
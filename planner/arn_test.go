@@ -0,0 +1,54 @@
+package planner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_ParseTaskDefinitionARN(t *testing.T) {
+	testCases := []struct {
+		arn      string
+		expected TaskDefinitionARN
+		valid    bool
+	}{
+		{
+			arn:      "arn:aws:ecs:us-west-2:123456789012:task-definition/web:5",
+			expected: TaskDefinitionARN{Partition: "aws", Region: "us-west-2", Account: "123456789012", Family: "web", Revision: 5},
+			valid:    true,
+		},
+		{
+			arn:      "arn:aws-cn:ecs:cn-north-1:123456789012:task-definition/my_app-2:120",
+			expected: TaskDefinitionARN{Partition: "aws-cn", Region: "cn-north-1", Account: "123456789012", Family: "my_app-2", Revision: 120},
+			valid:    true,
+		},
+		{arn: "web:5"},
+		{arn: "arn:aws:ecs:us-west-2:123456789012:service/web"},
+		{arn: "arn:aws:lambda:us-west-2:123456789012:task-definition/web:5"},
+		{arn: "arn:aws:ecs:us-west-2:123456789012:task-definition/web"},
+		{arn: "arn:aws:ecs:us-west-2:123456789012:task-definition/web:0"},
+		{arn: "arn:aws:ecs:us-west-2:123456789012:task-definition/web:latest"},
+		{arn: "arn:aws:ecs:us-west-2:123456789012:task-definition/we.b:5"},
+		{arn: "arn:aws:ecs:us-west-2:123456789012:task-definition/:5"},
+	}
+
+	for _, testCase := range testCases {
+		result, err := ParseTaskDefinitionARN(testCase.arn)
+
+		if valid := err == nil; valid != testCase.valid {
+			t.Errorf("TestCase '%s': expected valid %t, got error %v\n", testCase.arn, testCase.valid, err)
+			continue
+		}
+
+		if !testCase.valid {
+			continue
+		}
+
+		if equal := reflect.DeepEqual(testCase.expected, result); !equal {
+			t.Errorf("TestCase '%s': expected %+v, got %+v\n", testCase.arn, testCase.expected, result)
+		}
+
+		if result.String() != testCase.arn {
+			t.Errorf("TestCase '%s': String() returned %s\n", testCase.arn, result.String())
+		}
+	}
+}
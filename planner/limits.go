@@ -0,0 +1,158 @@
+package planner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Limits are safety guards on how much a Plan may deregister, for when a discovery bug or a
+// missing permission makes nearly everything look unused. A zero field disables its guard.
+// Deregistering every revision of a family is refused whatever the Limits, unless
+// RetireFamilies is set and no service uses the family.
+type Limits struct {
+	// MaxDeregistrations caps the number of task definitions deregistered.
+	MaxDeregistrations int
+
+	// MaxFraction caps the share of all task definitions deregistered, between 0 and 1.
+	MaxFraction float64
+
+	// MaxFamilyFraction caps the share of any one family's revisions deregistered, between 0
+	// and 1. It also refuses to deregister every revision of a family, even when set to 1,
+	// including a family the orphan policy retires.
+	MaxFamilyFraction float64
+
+	// RetireFamilies lets the orphan policy deregister every revision of a family no service
+	// uses. A discovery that misses every service makes every family look like one, so it's
+	// off unless asked for.
+	RetireFamilies bool
+}
+
+// Violation is a Limit that a Plan exceeds.
+type Violation struct {
+	Limit  string
+	Detail string
+}
+
+// LimitError reports every Limit a Plan exceeds.
+type LimitError struct {
+	Violations []Violation
+}
+
+func (l *LimitError) Error() string {
+	var lines []string
+	for _, violation := range l.Violations {
+		lines = append(lines, fmt.Sprintf("  %s: %s", violation.Limit, violation.Detail))
+	}
+
+	return fmt.Sprintf("refusing to deregister anything; safety limits tripped:\n%s", strings.Join(lines, "\n"))
+}
+
+// CheckLimits returns a *LimitError if the Plan deregisters more than the Limits allow, and
+// nil otherwise.
+func (p *Plan) CheckLimits(limits Limits) error {
+	var violations []Violation
+
+	total := len(p.Decisions)
+	deregister := len(p.ARNs(Deregister))
+
+	if limits.MaxDeregistrations > 0 && deregister > limits.MaxDeregistrations {
+		violations = append(violations, Violation{
+			Limit:  "max-deregistrations",
+			Detail: fmt.Sprintf("would deregister %d task definitions; the limit is %d", deregister, limits.MaxDeregistrations),
+		})
+	}
+
+	if limits.MaxFraction > 0 && total > 0 && float64(deregister)/float64(total) > limits.MaxFraction {
+		violations = append(violations, Violation{
+			Limit:  "max-fraction",
+			Detail: fmt.Sprintf("would deregister %d of %d task definitions (%.0f%%); the limit is %.0f%%", deregister, total, percent(deregister, total), limits.MaxFraction*100),
+		})
+	}
+
+	violations = append(violations, p.familyViolations(limits.MaxFamilyFraction, limits.RetireFamilies)...)
+
+	if len(violations) > 0 {
+		return &LimitError{Violations: violations}
+	}
+
+	return nil
+}
+
+// familyViolations checks, in family order, that no family loses every revision, unless
+// retireFamilies is set and it loses them all to the orphan policy, and each family against
+// MaxFamilyFraction when it's set.
+func (p *Plan) familyViolations(maxFraction float64, retireFamilies bool) []Violation {
+	totals := make(map[string]int)
+	deregister := make(map[string]int)
+
+	// a family is retired when everything it loses goes to the orphan policy
+	retired := make(map[string]bool)
+
+	for _, decision := range p.Decisions {
+		if decision.Family == "" {
+			continue
+		}
+
+		totals[decision.Family]++
+		if decision.Action != Deregister {
+			continue
+		}
+
+		first := deregister[decision.Family] == 0
+		deregister[decision.Family]++
+		retired[decision.Family] = (first || retired[decision.Family]) && hasReason(decision, FamilyNotInUse)
+	}
+
+	var families []string
+	for family := range deregister {
+		families = append(families, family)
+	}
+
+	sort.Strings(families)
+
+	var violations []Violation
+	for _, family := range families {
+		lost, total := deregister[family], totals[family]
+
+		switch {
+		case lost == total && maxFraction > 0:
+			violations = append(violations, Violation{
+				Limit:  "max-family-fraction",
+				Detail: fmt.Sprintf("family %s would lose every one of its %d revisions", family, total),
+			})
+
+		case lost == total && !(retireFamilies && retired[family]):
+			violations = append(violations, Violation{
+				Limit:  "every-revision",
+				Detail: fmt.Sprintf("family %s would lose every one of its %d revisions", family, total),
+			})
+
+		case maxFraction == 0:
+			// only the every-revision guard applies
+
+		case float64(lost)/float64(total) > maxFraction:
+			violations = append(violations, Violation{
+				Limit:  "max-family-fraction",
+				Detail: fmt.Sprintf("family %s would lose %d of %d revisions (%.0f%%); the limit is %.0f%%", family, lost, total, percent(lost, total), maxFraction*100),
+			})
+		}
+	}
+
+	return violations
+}
+
+// hasReason reports whether a rule with the code fired for the Decision.
+func hasReason(decision Decision, code ReasonCode) bool {
+	for _, reason := range decision.Reasons {
+		if reason.Code == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+func percent(n, total int) float64 {
+	return float64(n) / float64(total) * 100
+}
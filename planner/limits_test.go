@@ -0,0 +1,98 @@
+package planner
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/quintilesims/go-ecs-cleaner/inventory"
+)
+
+func Test_CheckLimits(t *testing.T) {
+	// a: 4 revisions, 3 deregistered; b: 2 revisions, both deregistered; c: 1 revision, kept
+	inv := inventory.New("us-west-2")
+	inv.TaskDefinitionARNs = []string{arn("a:1"), arn("a:2"), arn("a:3"), arn("a:4"), arn("b:1"), arn("b:2"), arn("c:1")}
+	inv.Services = []ecs.Service{
+		{TaskDefinition: aws.String(arn("a:4"))},
+		{TaskDefinition: aws.String(arn("c:1"))},
+	}
+
+	plan := Build(inv, Policy{})
+
+	testCases := map[string]struct {
+		limits   Limits
+		expected []string
+	}{
+		"no limits":                      {Limits{}, []string{"every-revision"}},
+		"retiring families":              {Limits{RetireFamilies: true}, nil},
+		"within the limits":              {Limits{MaxDeregistrations: 5, MaxFraction: 0.75, RetireFamilies: true}, nil},
+		"too many deregistrations":       {Limits{MaxDeregistrations: 4, RetireFamilies: true}, []string{"max-deregistrations"}},
+		"too large a share":              {Limits{MaxFraction: 0.5, RetireFamilies: true}, []string{"max-fraction"}},
+		"family fraction":                {Limits{MaxFamilyFraction: 0.5}, []string{"max-family-fraction", "max-family-fraction"}},
+		"family wipeout at a limit of 1": {Limits{MaxFamilyFraction: 1}, []string{"max-family-fraction"}},
+		"every guard":                    {Limits{MaxDeregistrations: 1, MaxFraction: 0.1, MaxFamilyFraction: 1}, []string{"max-deregistrations", "max-fraction", "max-family-fraction"}},
+	}
+
+	for name, testCase := range testCases {
+		err := plan.CheckLimits(testCase.limits)
+
+		var result []string
+		if err != nil {
+			limitErr, ok := err.(*LimitError)
+			if !ok {
+				t.Fatalf("TestCase '%s': expected a *LimitError, got %T\n", name, err)
+			}
+
+			for _, violation := range limitErr.Violations {
+				result = append(result, violation.Limit)
+			}
+		}
+
+		if equal := reflect.DeepEqual(testCase.expected, result); !equal {
+			t.Errorf("TestCase '%s': expected %v, got %v (%v)\n", name, testCase.expected, result, err)
+		}
+	}
+}
+
+func Test_CheckLimits_EveryRevision(t *testing.T) {
+	beyondCutoff := []Reason{{Code: BeyondCutoff}}
+	notInUse := []Reason{{Code: FamilyNotInUse}}
+
+	// a loses every revision, b every one of its orphan revisions; neither is guarded by a
+	// fraction
+	plan := &Plan{Decisions: []Decision{
+		{ARN: arn("a:1"), Family: "a", Revision: 1, Action: Deregister, Reasons: beyondCutoff},
+		{ARN: arn("a:2"), Family: "a", Revision: 2, Action: Deregister, Reasons: notInUse},
+		{ARN: arn("b:1"), Family: "b", Revision: 1, Action: Deregister, Reasons: notInUse},
+		{ARN: arn("b:2"), Family: "b", Revision: 2, Action: Deregister, Reasons: notInUse},
+	}}
+
+	testCases := map[string]struct {
+		limits   Limits
+		expected []Violation
+	}{
+		"default": {
+			limits: Limits{},
+			expected: []Violation{
+				{Limit: "every-revision", Detail: "family a would lose every one of its 2 revisions"},
+				{Limit: "every-revision", Detail: "family b would lose every one of its 2 revisions"},
+			},
+		},
+		"retire-families": {
+			limits:   Limits{RetireFamilies: true},
+			expected: []Violation{{Limit: "every-revision", Detail: "family a would lose every one of its 2 revisions"}},
+		},
+	}
+
+	for name, testCase := range testCases {
+		err, ok := plan.CheckLimits(testCase.limits).(*LimitError)
+		if !ok {
+			t.Fatalf("TestCase '%s': expected a *LimitError, got %v\n", name, err)
+		}
+
+		if equal := reflect.DeepEqual(testCase.expected, err.Violations); !equal {
+			t.Errorf("TestCase '%s': expected %v, got %v\n", name, testCase.expected, err.Violations)
+		}
+	}
+}
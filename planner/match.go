@@ -0,0 +1,48 @@
+package planner
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Match returns the Decisions for the task definitions a target names. The target may be a
+// task definition ARN, "family:revision", or a family name, which matches every revision of
+// the family.
+func (p *Plan) Match(target string) ([]Decision, error) {
+	family, revision, err := parseTarget(target)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []Decision
+	for _, decision := range p.Decisions {
+		if decision.Family == family && (revision == 0 || decision.Revision == revision) {
+			matches = append(matches, decision)
+		}
+	}
+
+	return matches, nil
+}
+
+// parseTarget splits a Match target into a family and revision; the revision is 0 when the
+// target names a whole family.
+func parseTarget(target string) (string, int64, error) {
+	if strings.HasPrefix(target, "arn:") {
+		parsed, err := ParseTaskDefinitionARN(target)
+		if err != nil {
+			return "", 0, err
+		}
+
+		return parsed.Family, parsed.Revision, nil
+	}
+
+	if !strings.Contains(target, ":") {
+		if !validFamily(target) {
+			return "", 0, fmt.Errorf("invalid family %q", target)
+		}
+
+		return target, 0, nil
+	}
+
+	return parseFamilyRevision(target)
+}
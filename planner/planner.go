@@ -0,0 +1,560 @@
+// Package planner decides which task definitions to keep and which to deregister. A Plan is
+// a pure function of an Inventory and a Policy: building one makes no AWS calls, and the
+// same inputs always produce the same Plan.
+package planner
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/quintilesims/go-ecs-cleaner/inventory"
+)
+
+// Policy holds the retention rules a Plan applies.
+type Policy struct {
+	// Cutoff is how many of the most recent revisions, besides those in use, to keep for each
+	// family that a service uses.
+	Cutoff int `json:"cutoff"`
+
+	// KeepBeforeActive is how many revisions immediately preceding each service's current
+	// revision to keep as rollback targets, computed separately for each service.
+	KeepBeforeActive int `json:"keepBeforeActive,omitempty"`
+
+	// OrphanKeep is how many of the most recent revisions, besides those in use, to keep for
+	// each family that no service uses.
+	OrphanKeep int `json:"orphanKeep,omitempty"`
+
+	// OrphanKeepDays also keeps revisions of families that no service uses when they were
+	// registered within this many days of the Inventory being captured. When both it and
+	// `OrphanKeep` are 0, such families are retired completely.
+	OrphanKeepDays int `json:"orphanKeepDays,omitempty"`
+
+	// GracePeriod, when set, makes removal two-phase: eligible task definitions are first
+	// marked with the PendingTag, and only deregistered once they've carried it for this long.
+	GracePeriod time.Duration `json:"gracePeriod,omitempty"`
+
+	// ProtectTags keeps every task definition carrying one of these tags. An empty value
+	// matches any value of the tag.
+	ProtectTags map[string]string `json:"protectTags,omitempty"`
+}
+
+// PendingTag is the tag that marks a task definition pending deregistration under a
+// GracePeriod. Its value is the RFC 3339 time it was marked.
+const PendingTag = "ecs-cleaner:pending-deregistration"
+
+// Action is what a Plan does with a task definition.
+type Action string
+
+// The possible Actions.
+const (
+	Keep       Action = "keep"
+	Deregister Action = "deregister"
+	// Pending: eligible for deregistration, but still within its GracePeriod.
+	Pending Action = "pending"
+)
+
+// TagChange is a change a Plan makes to a task definition's PendingTag.
+type TagChange string
+
+// The possible TagChanges.
+const (
+	AddPendingTag    TagChange = "add"
+	RemovePendingTag TagChange = "remove"
+)
+
+// ReasonCode identifies a rule that contributed to a Decision.
+type ReasonCode string
+
+// The rules a Plan applies.
+const (
+	// InUseByService: a service's current task definition.
+	InUseByService ReasonCode = "in-use-by-service"
+	// InUseByTask: the task definition of a running task.
+	InUseByTask ReasonCode = "in-use-by-task"
+	// Referenced: referenced from outside ECS, e.g. by a schedule.
+	Referenced ReasonCode = "referenced"
+	// RollbackTarget: among the `KeepBeforeActive` revisions immediately preceding a service's
+	// current revision.
+	RollbackTarget ReasonCode = "rollback-target"
+	// ProtectedByTag: carries one of the Policy's `ProtectTags`.
+	ProtectedByTag ReasonCode = "protected-by-tag"
+	// WithinCutoff: among the `Cutoff` most recent revisions of a family a service uses.
+	WithinCutoff ReasonCode = "within-cutoff"
+	// BeyondCutoff: older than the `Cutoff` most recent revisions of a family a service uses.
+	BeyondCutoff ReasonCode = "beyond-cutoff"
+	// FamilyNotInUse: no service uses any revision of the family, and the orphan policy
+	// doesn't keep this one.
+	FamilyNotInUse ReasonCode = "family-not-in-use"
+	// OrphanWithinKeep: among the `OrphanKeep` most recent revisions of a family no service
+	// uses.
+	OrphanWithinKeep ReasonCode = "orphan-within-keep"
+	// OrphanRecent: registered within `OrphanKeepDays` days, in a family no service uses.
+	OrphanRecent ReasonCode = "orphan-recent"
+	// RegistrationUnknown: in a family no service uses, with no recorded registration date to
+	// hold against `OrphanKeepDays`, so it's kept.
+	RegistrationUnknown ReasonCode = "registration-unknown"
+	// InGracePeriod: eligible for deregistration, but hasn't carried the PendingTag for the
+	// `GracePeriod` yet.
+	InGracePeriod ReasonCode = "in-grace-period"
+	// GracePeriodElapsed: has carried the PendingTag for the `GracePeriod`.
+	GracePeriodElapsed ReasonCode = "grace-period-elapsed"
+	// NoLongerEligible: carries the PendingTag, but is kept now.
+	NoLongerEligible ReasonCode = "no-longer-eligible"
+	// UnparseableARN: the ARN couldn't be parsed, so the task definition is left alone.
+	UnparseableARN ReasonCode = "unparseable-arn"
+	// Targeted: named for deregistration by `ecs-task deregister`; see Target.
+	Targeted ReasonCode = "targeted"
+)
+
+// Reason is a rule that fired for a task definition, with human-readable detail.
+type Reason struct {
+	Code   ReasonCode `json:"code"`
+	Detail string     `json:"detail"`
+}
+
+// Decision is a Plan's verdict on one task definition, with every rule that fired for it.
+type Decision struct {
+	ARN        string    `json:"arn"`
+	Family     string    `json:"family"`
+	Revision   int64     `json:"revision"`
+	Action     Action    `json:"action"`
+	Reasons    []Reason  `json:"reasons"`
+	PendingTag TagChange `json:"pendingTag,omitempty"`
+}
+
+// OrphanFamily summarizes what a Plan does with a family that no service uses.
+type OrphanFamily struct {
+	Family                   string     `json:"family"`
+	LatestRevision           int64      `json:"latestRevision"`
+	LatestRevisionRegistered *time.Time `json:"latestRevisionRegistered,omitempty"`
+	Kept                     int        `json:"kept"`
+	Pending                  int        `json:"pending,omitempty"`
+	Deregistered             int        `json:"deregistered"`
+}
+
+// Plan holds a Decision for every task definition in an Inventory, sorted by family and then
+// revision, and a summary of each family that no service uses, sorted by family.
+type Plan struct {
+	Policy         Policy         `json:"policy"`
+	Decisions      []Decision     `json:"decisions"`
+	OrphanFamilies []OrphanFamily `json:"orphanFamilies,omitempty"`
+}
+
+// Build decides what to do with each task definition in the Inventory under the Policy.
+func Build(inv *inventory.Inventory, policy Policy) *Plan {
+	plan := &Plan{Policy: policy}
+	inUse := inUseReasons(inv)
+	protected := protectedReasons(inv, policy)
+
+	serviceFamilies := serviceFamilies(inUse)
+	orphanCutoff := inv.CapturedAt.AddDate(0, 0, -policy.OrphanKeepDays)
+
+	revisionsByFamily := make(map[string][]TaskDefinitionARN)
+	seen := make(map[string]bool)
+
+	for _, arn := range inv.TaskDefinitionARNs {
+		if seen[arn] {
+			continue
+		}
+
+		seen[arn] = true
+
+		parsed, err := ParseTaskDefinitionARN(arn)
+		if err != nil {
+			plan.Decisions = append(plan.Decisions, Decision{
+				ARN:     arn,
+				Action:  Keep,
+				Reasons: []Reason{{Code: UnparseableARN, Detail: err.Error()}},
+			})
+
+			continue
+		}
+
+		revisionsByFamily[parsed.Family] = append(revisionsByFamily[parsed.Family], parsed)
+	}
+
+	for _, revisions := range revisionsByFamily {
+		sort.Slice(revisions, func(i, j int) bool { return revisions[i].Revision > revisions[j].Revision })
+	}
+
+	rollback := rollbackReasons(inv, policy, revisionsByFamily)
+
+	for family, revisions := range revisionsByFamily {
+
+		var orphan *OrphanFamily
+		if !serviceFamilies[family] {
+			orphan = &OrphanFamily{Family: family, LatestRevision: revisions[0].Revision}
+			if registered, ok := inv.TaskDefinitionRegisteredAt[revisions[0].String()]; ok {
+				orphan.LatestRevisionRegistered = &registered
+			}
+		}
+
+		var rank int
+		for _, parsed := range revisions {
+			arn := parsed.String()
+			decision := Decision{ARN: arn, Family: family, Revision: parsed.Revision}
+
+			switch {
+			case len(inUse[arn]) > 0 || len(rollback[arn]) > 0 || len(protected[arn]) > 0:
+				decision.Action = Keep
+				decision.Reasons = append(append(append([]Reason{}, inUse[arn]...), rollback[arn]...), protected[arn]...)
+
+			case serviceFamilies[family]:
+				rank++
+
+				if rank <= policy.Cutoff {
+					decision.Action = Keep
+					decision.Reasons = []Reason{{
+						Code:   WithinCutoff,
+						Detail: fmt.Sprintf("rank %d of the unused revisions; cutoff keeps %d", rank, policy.Cutoff),
+					}}
+				} else {
+					decision.Action = Deregister
+					decision.Reasons = []Reason{{
+						Code:   BeyondCutoff,
+						Detail: fmt.Sprintf("rank %d of the unused revisions; cutoff keeps %d", rank, policy.Cutoff),
+					}}
+				}
+
+			default:
+				rank++
+
+				registered, ok := inv.TaskDefinitionRegisteredAt[arn]
+				decision.Action, decision.Reasons = orphanDecision(policy, family, rank, registered, ok, orphanCutoff)
+			}
+
+			if policy.GracePeriod > 0 {
+				applyGracePeriod(&decision, policy.GracePeriod, inv.TaskDefinitionTags[arn], inv.CapturedAt)
+			}
+
+			if orphan != nil {
+				switch decision.Action {
+				case Keep:
+					orphan.Kept++
+				case Pending:
+					orphan.Pending++
+				case Deregister:
+					orphan.Deregistered++
+				}
+			}
+
+			plan.Decisions = append(plan.Decisions, decision)
+		}
+
+		if orphan != nil {
+			plan.OrphanFamilies = append(plan.OrphanFamilies, *orphan)
+		}
+	}
+
+	sort.Slice(plan.OrphanFamilies, func(i, j int) bool {
+		return plan.OrphanFamilies[i].Family < plan.OrphanFamilies[j].Family
+	})
+
+	sort.Slice(plan.Decisions, func(i, j int) bool {
+		a, b := plan.Decisions[i], plan.Decisions[j]
+		if a.Family != b.Family {
+			return a.Family < b.Family
+		}
+
+		if a.Revision != b.Revision {
+			return a.Revision < b.Revision
+		}
+
+		return a.ARN < b.ARN
+	})
+
+	return plan
+}
+
+// OrphanFamilies returns the families in the Inventory that no service uses, sorted.
+func OrphanFamilies(inv *inventory.Inventory) []string {
+	serviceFamilies := serviceFamilies(inUseReasons(inv))
+
+	seen := make(map[string]bool)
+	var families []string
+
+	for _, arn := range inv.TaskDefinitionARNs {
+		parsed, err := ParseTaskDefinitionARN(arn)
+		if err != nil || serviceFamilies[parsed.Family] || seen[parsed.Family] {
+			continue
+		}
+
+		seen[parsed.Family] = true
+		families = append(families, parsed.Family)
+	}
+
+	sort.Strings(families)
+	return families
+}
+
+// ARNs returns the ARNs of the task definitions the Plan takes the given action on, sorted.
+func (p *Plan) ARNs(action Action) []string {
+	var arns []string
+	for _, decision := range p.Decisions {
+		if decision.Action == action {
+			arns = append(arns, decision.ARN)
+		}
+	}
+
+	sort.Strings(arns)
+	return arns
+}
+
+// PendingTagARNs returns the ARNs of the task definitions whose PendingTag the Plan makes the
+// given change to, sorted.
+func (p *Plan) PendingTagARNs(change TagChange) []string {
+	var arns []string
+	for _, decision := range p.Decisions {
+		if decision.PendingTag == change {
+			arns = append(arns, decision.ARN)
+		}
+	}
+
+	sort.Strings(arns)
+	return arns
+}
+
+// Write encodes the Plan as indented JSON.
+func (p *Plan) Write(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(p)
+}
+
+// applyGracePeriod holds back a Decision to deregister until the task definition has carried
+// the PendingTag for `gracePeriod`, marking it if necessary, and clears the tag from task
+// definitions that are kept.
+func applyGracePeriod(decision *Decision, gracePeriod time.Duration, tags map[string]string, now time.Time) {
+	value, tagged := tags[PendingTag]
+	since, err := time.Parse(time.RFC3339, value)
+
+	if decision.Action == Keep && tagged {
+		decision.PendingTag = RemovePendingTag
+		decision.Reasons = append(decision.Reasons, Reason{
+			Code:   NoLongerEligible,
+			Detail: fmt.Sprintf("marked pending deregistration at %s", value),
+		})
+	}
+
+	if decision.Action != Deregister {
+		return
+	}
+
+	switch {
+	case !tagged || err != nil:
+		decision.Action = Pending
+		decision.PendingTag = AddPendingTag
+		decision.Reasons = append(decision.Reasons, Reason{
+			Code:   InGracePeriod,
+			Detail: fmt.Sprintf("marked pending deregistration now; eligible after %s", now.Add(gracePeriod).Format(time.RFC3339)),
+		})
+
+	case now.Sub(since) < gracePeriod:
+		decision.Action = Pending
+		decision.Reasons = append(decision.Reasons, Reason{
+			Code:   InGracePeriod,
+			Detail: fmt.Sprintf("marked pending deregistration at %s; eligible after %s", value, since.Add(gracePeriod).Format(time.RFC3339)),
+		})
+
+	default:
+		decision.Reasons = append(decision.Reasons, Reason{
+			Code:   GracePeriodElapsed,
+			Detail: fmt.Sprintf("marked pending deregistration at %s; grace period is %s", value, gracePeriod),
+		})
+	}
+}
+
+// orphanDecision applies the orphan policy to a revision of a family that no service uses,
+// ranked `rank` among the family's unused revisions, newest first. `ok` reports whether its
+// registration date is known.
+func orphanDecision(policy Policy, family string, rank int, registered time.Time, ok bool, cutoff time.Time) (Action, []Reason) {
+	if rank <= policy.OrphanKeep {
+		return Keep, []Reason{{
+			Code:   OrphanWithinKeep,
+			Detail: fmt.Sprintf("rank %d of the unused revisions; orphan policy keeps %d", rank, policy.OrphanKeep),
+		}}
+	}
+
+	if policy.OrphanKeepDays > 0 && !ok {
+		return Keep, []Reason{{
+			Code:   RegistrationUnknown,
+			Detail: fmt.Sprintf("registration date unknown; orphan policy keeps the last %d days", policy.OrphanKeepDays),
+		}}
+	}
+
+	if policy.OrphanKeepDays > 0 && registered.After(cutoff) {
+		return Keep, []Reason{{
+			Code:   OrphanRecent,
+			Detail: fmt.Sprintf("registered %s; orphan policy keeps the last %d days", registered.Format(time.RFC3339), policy.OrphanKeepDays),
+		}}
+	}
+
+	return Deregister, []Reason{{
+		Code:   FamilyNotInUse,
+		Detail: fmt.Sprintf("no service uses family %s", family),
+	}}
+}
+
+// serviceFamilies returns the families that some service uses a revision of.
+func serviceFamilies(inUse map[string][]Reason) map[string]bool {
+	families := make(map[string]bool)
+
+	for arn, reasons := range inUse {
+		parsed, err := ParseTaskDefinitionARN(arn)
+		if err != nil {
+			continue
+		}
+
+		for _, reason := range reasons {
+			if reason.Code == InUseByService {
+				families[parsed.Family] = true
+			}
+		}
+	}
+
+	return families
+}
+
+// inUseReasons maps each task definition ARN that a service, task or external reference uses
+// to the reasons describing those uses, sorted for determinism.
+func inUseReasons(inv *inventory.Inventory) map[string][]Reason {
+	inUse := make(map[string][]Reason)
+
+	for _, service := range inv.Services {
+		if service.TaskDefinition == nil {
+			continue
+		}
+
+		name := aws.StringValue(service.ServiceName)
+		if name == "" {
+			name = lastSegment(aws.StringValue(service.ServiceArn))
+		}
+
+		inUse[*service.TaskDefinition] = append(inUse[*service.TaskDefinition], Reason{
+			Code:   InUseByService,
+			Detail: fmt.Sprintf("service %s in cluster %s", name, lastSegment(aws.StringValue(service.ClusterArn))),
+		})
+	}
+
+	for _, task := range inv.Tasks {
+		if task.TaskDefinitionArn == nil {
+			continue
+		}
+
+		detail := fmt.Sprintf("task %s in cluster %s", lastSegment(aws.StringValue(task.TaskArn)), lastSegment(aws.StringValue(task.ClusterArn)))
+		if task.StartedBy != nil {
+			detail += fmt.Sprintf(" (started by %s)", *task.StartedBy)
+		}
+
+		inUse[*task.TaskDefinitionArn] = append(inUse[*task.TaskDefinitionArn], Reason{
+			Code:   InUseByTask,
+			Detail: detail,
+		})
+	}
+
+	for _, reference := range inv.References {
+		inUse[reference.TaskDefinitionARN] = append(inUse[reference.TaskDefinitionARN], Reason{
+			Code:   Referenced,
+			Detail: fmt.Sprintf("%s %s", reference.Source, reference.ID),
+		})
+	}
+
+	for _, reasons := range inUse {
+		sort.Slice(reasons, func(i, j int) bool {
+			if reasons[i].Code != reasons[j].Code {
+				return reasons[i].Code < reasons[j].Code
+			}
+
+			return reasons[i].Detail < reasons[j].Detail
+		})
+	}
+
+	return inUse
+}
+
+// rollbackReasons maps each task definition ARN among the `KeepBeforeActive` revisions
+// preceding some service's current revision to a reason per such service, sorted for
+// determinism. `revisionsByFamily` must be sorted newest first.
+func rollbackReasons(inv *inventory.Inventory, policy Policy, revisionsByFamily map[string][]TaskDefinitionARN) map[string][]Reason {
+	rollback := make(map[string][]Reason)
+	if policy.KeepBeforeActive <= 0 {
+		return rollback
+	}
+
+	for _, service := range inv.Services {
+		active, err := ParseTaskDefinitionARN(aws.StringValue(service.TaskDefinition))
+		if err != nil {
+			continue
+		}
+
+		name := aws.StringValue(service.ServiceName)
+		if name == "" {
+			name = lastSegment(aws.StringValue(service.ServiceArn))
+		}
+
+		var kept int
+		for _, parsed := range revisionsByFamily[active.Family] {
+			if parsed.Revision >= active.Revision {
+				continue
+			}
+
+			kept++
+			if kept > policy.KeepBeforeActive {
+				break
+			}
+
+			arn := parsed.String()
+			rollback[arn] = append(rollback[arn], Reason{
+				Code: RollbackTarget,
+				Detail: fmt.Sprintf("%d of %d before %s:%d, which service %s in cluster %s runs",
+					kept, policy.KeepBeforeActive, active.Family, active.Revision, name, lastSegment(aws.StringValue(service.ClusterArn))),
+			})
+		}
+	}
+
+	for _, reasons := range rollback {
+		sort.Slice(reasons, func(i, j int) bool { return reasons[i].Detail < reasons[j].Detail })
+	}
+
+	return rollback
+}
+
+// protectedReasons maps each task definition ARN carrying one of the Policy's protected tags
+// to a reason per matching tag, sorted for determinism.
+func protectedReasons(inv *inventory.Inventory, policy Policy) map[string][]Reason {
+	protected := make(map[string][]Reason)
+
+	var keys []string
+	for key := range policy.ProtectTags {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for arn, tags := range inv.TaskDefinitionTags {
+		for _, key := range keys {
+			value, ok := tags[key]
+			if !ok || (policy.ProtectTags[key] != "" && policy.ProtectTags[key] != value) {
+				continue
+			}
+
+			protected[arn] = append(protected[arn], Reason{
+				Code:   ProtectedByTag,
+				Detail: fmt.Sprintf("tagged %s=%s", key, value),
+			})
+		}
+	}
+
+	return protected
+}
+
+// lastSegment returns the part of an ARN after its last slash, e.g. a cluster's name.
+func lastSegment(arn string) string {
+	return arn[strings.LastIndex(arn, "/")+1:]
+}
@@ -0,0 +1,309 @@
+package planner
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/quintilesims/go-ecs-cleaner/inventory"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/")
+
+func arn(familyRevision string) string {
+	return "arn:aws:ecs:us-west-2:000000000000:task-definition/" + familyRevision
+}
+
+func Test_Build(t *testing.T) {
+	testCases := map[string]struct {
+		taskDefinitions []string
+		services        []string
+		tasks           []string
+		references      []string
+		tags            map[string]map[string]string
+		registered      map[string]int
+		policy          Policy
+		deregister      []string
+	}{
+		"no services": {
+			taskDefinitions: []string{arn("a:1"), arn("a:2")},
+			policy:          Policy{Cutoff: 5},
+			deregister:      []string{arn("a:1"), arn("a:2")},
+		},
+		"cutoff counts only unused revisions": {
+			taskDefinitions: []string{arn("a:1"), arn("a:2"), arn("a:3"), arn("a:4")},
+			services:        []string{arn("a:4")},
+			policy:          Policy{Cutoff: 2},
+			deregister:      []string{arn("a:1")},
+		},
+		"cutoff of zero keeps only what's in use": {
+			taskDefinitions: []string{arn("a:1"), arn("a:2"), arn("a:3")},
+			services:        []string{arn("a:2")},
+			policy:          Policy{Cutoff: 0},
+			deregister:      []string{arn("a:1"), arn("a:3")},
+		},
+		"in-use revision older than the cutoff": {
+			taskDefinitions: []string{arn("a:1"), arn("a:2"), arn("a:3"), arn("a:4")},
+			services:        []string{arn("a:1")},
+			policy:          Policy{Cutoff: 1},
+			deregister:      []string{arn("a:2"), arn("a:3")},
+		},
+		"tasks and references keep only their revision": {
+			taskDefinitions: []string{arn("a:1"), arn("a:2"), arn("b:1"), arn("b:2")},
+			tasks:           []string{arn("a:1")},
+			references:      []string{arn("b:2")},
+			policy:          Policy{Cutoff: 5},
+			deregister:      []string{arn("a:2"), arn("b:1")},
+		},
+		"revision numbers sort numerically": {
+			taskDefinitions: []string{arn("a:9"), arn("a:10"), arn("a:11")},
+			services:        []string{arn("a:11")},
+			policy:          Policy{Cutoff: 1},
+			deregister:      []string{arn("a:9")},
+		},
+		"keep before active keeps rollback targets past the cutoff": {
+			taskDefinitions: []string{arn("a:1"), arn("a:2"), arn("a:3"), arn("a:4"), arn("a:5"), arn("a:6")},
+			services:        []string{arn("a:3")},
+			policy:          Policy{Cutoff: 1, KeepBeforeActive: 1},
+			deregister:      []string{arn("a:1"), arn("a:4"), arn("a:5")},
+		},
+		"keep before active is computed per service": {
+			taskDefinitions: []string{arn("a:1"), arn("a:2"), arn("a:3"), arn("a:4"), arn("a:5"), arn("a:6")},
+			services:        []string{arn("a:6"), arn("a:3")},
+			policy:          Policy{Cutoff: 0, KeepBeforeActive: 1},
+			deregister:      []string{arn("a:1"), arn("a:4")},
+		},
+		"keep before active skips revisions that are already gone": {
+			taskDefinitions: []string{arn("a:1"), arn("a:2"), arn("a:5"), arn("a:9")},
+			services:        []string{arn("a:9")},
+			policy:          Policy{Cutoff: 0, KeepBeforeActive: 2},
+			deregister:      []string{arn("a:1")},
+		},
+		"protect tags match on key, and on value when one is given": {
+			taskDefinitions: []string{arn("a:1"), arn("a:2"), arn("a:3"), arn("b:1")},
+			tags: map[string]map[string]string{
+				arn("a:1"): {"keep": "anything"},
+				arn("a:2"): {"env": "prod"},
+				arn("a:3"): {"env": "dev"},
+				arn("b:1"): {"other": "x"},
+			},
+			policy:     Policy{ProtectTags: map[string]string{"keep": "", "env": "prod"}},
+			deregister: []string{arn("a:3"), arn("b:1")},
+		},
+		"orphan keep counts only unused revisions": {
+			taskDefinitions: []string{arn("a:1"), arn("a:2"), arn("a:3"), arn("a:4")},
+			tasks:           []string{arn("a:4")},
+			policy:          Policy{Cutoff: 5, OrphanKeep: 2},
+			deregister:      []string{arn("a:1")},
+		},
+		"orphan keep days keeps recent and undated revisions": {
+			taskDefinitions: []string{arn("a:1"), arn("a:2"), arn("a:3")},
+			registered:      map[string]int{arn("a:1"): 40, arn("a:2"): 10},
+			policy:          Policy{OrphanKeepDays: 30},
+			deregister:      []string{arn("a:1")},
+		},
+		"orphan keep and keep days combine": {
+			taskDefinitions: []string{arn("a:1"), arn("a:2"), arn("a:3")},
+			registered:      map[string]int{arn("a:1"): 90, arn("a:2"): 60, arn("a:3"): 45},
+			policy:          Policy{OrphanKeep: 1, OrphanKeepDays: 30},
+			deregister:      []string{arn("a:1"), arn("a:2")},
+		},
+		"orphan policy leaves service families to the cutoff": {
+			taskDefinitions: []string{arn("a:1"), arn("a:2"), arn("a:3")},
+			services:        []string{arn("a:3")},
+			policy:          Policy{OrphanKeep: 5},
+			deregister:      []string{arn("a:1"), arn("a:2")},
+		},
+		"unparseable ARNs are kept": {
+			taskDefinitions: []string{"a:1"},
+			policy:          Policy{Cutoff: 0},
+		},
+	}
+
+	for name, testCase := range testCases {
+		inv := inventory.New("us-west-2")
+		inv.TaskDefinitionARNs = testCase.taskDefinitions
+		inv.TaskDefinitionTags = testCase.tags
+
+		for arn, daysAgo := range testCase.registered {
+			if inv.TaskDefinitionRegisteredAt == nil {
+				inv.TaskDefinitionRegisteredAt = make(map[string]time.Time)
+			}
+
+			inv.TaskDefinitionRegisteredAt[arn] = inv.CapturedAt.AddDate(0, 0, -daysAgo)
+		}
+
+		for _, arn := range testCase.services {
+			inv.Services = append(inv.Services, ecs.Service{TaskDefinition: aws.String(arn)})
+		}
+
+		for _, arn := range testCase.tasks {
+			inv.Tasks = append(inv.Tasks, ecs.Task{TaskDefinitionArn: aws.String(arn)})
+		}
+
+		for _, arn := range testCase.references {
+			inv.References = append(inv.References, inventory.Reference{TaskDefinitionARN: arn, Source: "test"})
+		}
+
+		plan := Build(inv, testCase.policy)
+
+		if result := plan.ARNs(Deregister); !reflect.DeepEqual(testCase.deregister, result) {
+			t.Errorf("TestCase '%s': expected %v, got %v\n", name, testCase.deregister, result)
+		}
+
+		if len(plan.Decisions) != len(testCase.taskDefinitions) {
+			t.Errorf("TestCase '%s': expected %d decisions, got %d\n", name, len(testCase.taskDefinitions), len(plan.Decisions))
+		}
+
+		for _, decision := range plan.Decisions {
+			if len(decision.Reasons) == 0 {
+				t.Errorf("TestCase '%s': %s has no reasons\n", name, decision.ARN)
+			}
+		}
+	}
+}
+
+func Test_Build_Golden(t *testing.T) {
+	inv, err := inventory.Load("testdata/account.inventory.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Build(inv, Policy{Cutoff: 2}).Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// the plan must not depend on the order discovery returned things in
+	inv.TaskDefinitionARNs[0], inv.TaskDefinitionARNs[9] = inv.TaskDefinitionARNs[9], inv.TaskDefinitionARNs[0]
+	inv.Services[0], inv.Services[1] = inv.Services[1], inv.Services[0]
+
+	var reordered bytes.Buffer
+	if err := Build(inv, Policy{Cutoff: 2}).Write(&reordered); err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), reordered.Bytes()) {
+		t.Error("Expected the plan to be independent of inventory order")
+	}
+
+	golden := "testdata/account.plan.golden.json"
+	if *update {
+		if err := ioutil.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(expected, buf.Bytes()) {
+		t.Errorf("Plan differs from %s (run `go test ./planner -update` to accept):\n%s", golden, buf.String())
+	}
+}
+
+func Test_Match(t *testing.T) {
+	inv := inventory.New("us-west-2")
+	inv.TaskDefinitionARNs = []string{arn("a:1"), arn("a:2"), arn("ab:1")}
+	plan := Build(inv, Policy{})
+
+	testCases := map[string][]string{
+		"a":         []string{arn("a:1"), arn("a:2")},
+		"a:2":       []string{arn("a:2")},
+		arn("ab:1"): []string{arn("ab:1")},
+		"a:3":       nil,
+		"b":         nil,
+	}
+
+	for target, expected := range testCases {
+		matches, err := plan.Match(target)
+		if err != nil {
+			t.Errorf("TestCase '%s': %v\n", target, err)
+			continue
+		}
+
+		var result []string
+		for _, decision := range matches {
+			result = append(result, decision.ARN)
+		}
+
+		if equal := reflect.DeepEqual(expected, result); !equal {
+			t.Errorf("TestCase '%s': expected %v, got %v\n", target, expected, result)
+		}
+	}
+
+	for _, target := range []string{"", "a:b", "a.b", "arn:aws:ecs:us-west-2:0:service/a"} {
+		if _, err := plan.Match(target); err == nil {
+			t.Errorf("TestCase '%s': expected an error\n", target)
+		}
+	}
+}
+
+func Test_OrphanFamilies(t *testing.T) {
+	inv := inventory.New("us-west-2")
+	inv.TaskDefinitionARNs = []string{arn("c:1"), arn("a:1"), arn("b:1"), arn("a:2"), "unparseable"}
+	inv.Services = []ecs.Service{{TaskDefinition: aws.String(arn("b:1"))}}
+	inv.Tasks = []ecs.Task{{TaskDefinitionArn: aws.String(arn("c:1"))}}
+
+	expected := []string{"a", "c"}
+	if result := OrphanFamilies(inv); !reflect.DeepEqual(expected, result) {
+		t.Errorf("Expected %v, got %v\n", expected, result)
+	}
+
+	plan := Build(inv, Policy{OrphanKeep: 1})
+	expectedSummaries := []OrphanFamily{
+		{Family: "a", LatestRevision: 2, Kept: 1, Deregistered: 1},
+		{Family: "c", LatestRevision: 1, Kept: 1},
+	}
+
+	if !reflect.DeepEqual(expectedSummaries, plan.OrphanFamilies) {
+		t.Errorf("Expected %v, got %v\n", expectedSummaries, plan.OrphanFamilies)
+	}
+}
+
+func Test_Build_GracePeriod(t *testing.T) {
+	inv := inventory.New("us-west-2")
+	inv.TaskDefinitionARNs = []string{arn("a:1"), arn("a:2"), arn("a:3"), arn("a:4"), arn("a:5")}
+	inv.Services = []ecs.Service{{TaskDefinition: aws.String(arn("a:5"))}}
+
+	marked := func(daysAgo int) map[string]string {
+		return map[string]string{PendingTag: inv.CapturedAt.AddDate(0, 0, -daysAgo).Format(time.RFC3339)}
+	}
+
+	inv.TaskDefinitionTags = map[string]map[string]string{
+		arn("a:1"): marked(10),
+		arn("a:2"): marked(1),
+		arn("a:3"): {PendingTag: "garbage"},
+		arn("a:5"): marked(10),
+	}
+
+	plan := Build(inv, Policy{GracePeriod: 7 * 24 * time.Hour})
+
+	expected := map[string]struct {
+		action Action
+		tag    TagChange
+	}{
+		arn("a:1"): {Deregister, ""},
+		arn("a:2"): {Pending, ""},
+		arn("a:3"): {Pending, AddPendingTag},
+		arn("a:4"): {Pending, AddPendingTag},
+		arn("a:5"): {Keep, RemovePendingTag},
+	}
+
+	for _, decision := range plan.Decisions {
+		if decision.Action != expected[decision.ARN].action || decision.PendingTag != expected[decision.ARN].tag {
+			t.Errorf("%s: expected %v, got %s/%s\n", decision.ARN, expected[decision.ARN], decision.Action, decision.PendingTag)
+		}
+	}
+
+	if result := plan.PendingTagARNs(AddPendingTag); !reflect.DeepEqual([]string{arn("a:3"), arn("a:4")}, result) {
+		t.Errorf("Expected a:3 and a:4 to be marked, got %v\n", result)
+	}
+}
@@ -0,0 +1,106 @@
+package planner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/quintilesims/go-ecs-cleaner/inventory"
+)
+
+// Target builds a Plan that deregisters the task definitions in the Inventory that the
+// targets name, each read as Match reads it. Those a service, task or reference uses are kept
+// unless force is set; CheckInUse reports them. Targets that name nothing in the Inventory
+// are returned.
+func Target(inv *inventory.Inventory, targets []string, force bool) (*Plan, []string, error) {
+	candidates := &Plan{}
+	for _, arn := range inv.TaskDefinitionARNs {
+		parsed, err := ParseTaskDefinitionARN(arn)
+		if err != nil {
+			continue
+		}
+
+		candidates.Decisions = append(candidates.Decisions, Decision{ARN: arn, Family: parsed.Family, Revision: parsed.Revision})
+	}
+
+	inUse := inUseReasons(inv)
+	plan := &Plan{}
+	seen := make(map[string]bool)
+	var unmatched []string
+
+	for _, target := range targets {
+		matches, err := candidates.Match(target)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(matches) == 0 {
+			unmatched = append(unmatched, target)
+			continue
+		}
+
+		for _, decision := range matches {
+			if seen[decision.ARN] {
+				continue
+			}
+
+			seen[decision.ARN] = true
+			decision.Action = Deregister
+			decision.Reasons = append([]Reason{{Code: Targeted, Detail: fmt.Sprintf("named by %s", target)}}, inUse[decision.ARN]...)
+
+			if len(inUse[decision.ARN]) > 0 && !force {
+				decision.Action = Keep
+			}
+
+			plan.Decisions = append(plan.Decisions, decision)
+		}
+	}
+
+	sort.Slice(plan.Decisions, func(i, j int) bool {
+		a, b := plan.Decisions[i], plan.Decisions[j]
+		if a.Family != b.Family {
+			return a.Family < b.Family
+		}
+
+		return a.Revision < b.Revision
+	})
+
+	return plan, unmatched, nil
+}
+
+// InUseError reports the targeted task definitions that are in use.
+type InUseError struct {
+	Decisions []Decision
+}
+
+func (u *InUseError) Error() string {
+	var lines []string
+	for _, decision := range u.Decisions {
+		lines = append(lines, fmt.Sprintf("  %s", decision.ARN))
+
+		for _, reason := range decision.Reasons {
+			if reason.Code != Targeted {
+				lines = append(lines, fmt.Sprintf("    - %s: %s", reason.Code, reason.Detail))
+			}
+		}
+	}
+
+	return fmt.Sprintf("refusing to deregister anything; targeted task definitions are in use (use --force to deregister them anyway):\n%s", strings.Join(lines, "\n"))
+}
+
+// CheckInUse returns an *InUseError if a Plan built by Target keeps any of its targets
+// because they're in use, and nil otherwise.
+func (p *Plan) CheckInUse() error {
+	var kept []Decision
+	for _, decision := range p.Decisions {
+		if decision.Action == Keep {
+			kept = append(kept, decision)
+		}
+	}
+
+	if len(kept) > 0 {
+		return &InUseError{Decisions: kept}
+	}
+
+	return nil
+}
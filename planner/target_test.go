@@ -0,0 +1,100 @@
+package planner
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/quintilesims/go-ecs-cleaner/inventory"
+)
+
+func Test_Target(t *testing.T) {
+	// a:3 runs as a service, b:1 as a task, and a schedule references c:2
+	inv := inventory.New("us-west-2")
+	inv.TaskDefinitionARNs = []string{arn("a:1"), arn("a:2"), arn("a:3"), arn("b:1"), arn("b:2"), arn("c:1"), arn("c:2")}
+	inv.Services = []ecs.Service{{TaskDefinition: aws.String(arn("a:3"))}}
+	inv.Tasks = []ecs.Task{{TaskDefinitionArn: aws.String(arn("b:1"))}}
+	inv.References = []inventory.Reference{{TaskDefinitionARN: arn("c:2"), Source: "events-rule", ID: "nightly"}}
+
+	testCases := map[string]struct {
+		targets    []string
+		force      bool
+		deregister []string
+		keep       []string
+		unmatched  []string
+	}{
+		"unused ARNs": {
+			targets:    []string{arn("a:2"), arn("a:1")},
+			deregister: []string{arn("a:1"), arn("a:2")},
+		},
+		"family with a service": {
+			targets:    []string{"a"},
+			deregister: []string{arn("a:1"), arn("a:2")},
+			keep:       []string{arn("a:3")},
+		},
+		"forced": {
+			targets:    []string{"a", "b:1", "c"},
+			force:      true,
+			deregister: []string{arn("a:1"), arn("a:2"), arn("a:3"), arn("b:1"), arn("c:1"), arn("c:2")},
+		},
+		"scheduled": {
+			targets: []string{"c:2"},
+			keep:    []string{arn("c:2")},
+		},
+		"overlapping targets": {
+			targets:    []string{"b", arn("b:2")},
+			deregister: []string{arn("b:2")},
+			keep:       []string{arn("b:1")},
+		},
+		"nothing matched": {
+			targets:   []string{"d", arn("a:9")},
+			unmatched: []string{"d", arn("a:9")},
+		},
+	}
+
+	for name, testCase := range testCases {
+		plan, unmatched, err := Target(inv, testCase.targets, testCase.force)
+		if err != nil {
+			t.Fatalf("TestCase '%s': %v", name, err)
+		}
+
+		if result := plan.ARNs(Deregister); !reflect.DeepEqual(testCase.deregister, result) {
+			t.Errorf("TestCase '%s': expected %v deregistered, got %v\n", name, testCase.deregister, result)
+		}
+
+		if result := plan.ARNs(Keep); !reflect.DeepEqual(testCase.keep, result) {
+			t.Errorf("TestCase '%s': expected %v kept, got %v\n", name, testCase.keep, result)
+		}
+
+		if !reflect.DeepEqual(testCase.unmatched, unmatched) {
+			t.Errorf("TestCase '%s': expected %v unmatched, got %v\n", name, testCase.unmatched, unmatched)
+		}
+
+		err = plan.CheckInUse()
+		if inUse := len(testCase.keep) > 0; inUse != (err != nil) {
+			t.Errorf("TestCase '%s': expected in-use error %t, got %v\n", name, inUse, err)
+		}
+	}
+
+	if _, _, err := Target(inv, []string{"not a target!"}, false); err == nil {
+		t.Error("Expected an error for an invalid target")
+	}
+}
+
+func Test_InUseError(t *testing.T) {
+	inv := inventory.New("us-west-2")
+	inv.TaskDefinitionARNs = []string{arn("a:1")}
+	inv.Services = []ecs.Service{{ServiceName: aws.String("web"), ClusterArn: aws.String("cluster/prod"), TaskDefinition: aws.String(arn("a:1"))}}
+
+	plan, _, err := Target(inv, []string{"a"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := arn("a:1") + "\n    - in-use-by-service: service web in cluster prod"
+	if err := plan.CheckInUse(); err == nil || !strings.HasSuffix(err.Error(), expected) {
+		t.Errorf("Expected an error ending %q, got %v\n", expected, err)
+	}
+}
@@ -0,0 +1,258 @@
+// Package review lets an operator prune the task definitions a plan would deregister, family
+// by family, at the terminal before any are deregistered.
+package review
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/quintilesims/go-ecs-cleaner/planner"
+	"golang.org/x/term"
+)
+
+const help = `Commands:
+  list              show the task definitions to deregister, by family
+  /TEXT             show only families whose name contains TEXT ("/" shows all)
+  drop FAMILY[:REV] keep a family, or one revision of it; FAMILY may be its number in the list
+  add FAMILY[:REV]  deregister a family, or one revision of it, again
+  apply             deregister what's selected, after confirming
+  quit              deregister nothing
+`
+
+// IsTerminal reports whether f is an interactive terminal, rather than e.g. a pipe, a file or
+// another character device such as /dev/null.
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+type revision struct {
+	arn      string
+	number   int64
+	selected bool
+}
+
+type family struct {
+	name      string
+	revisions []*revision
+}
+
+func (f *family) selected() int {
+	var n int
+	for _, r := range f.revisions {
+		if r.selected {
+			n++
+		}
+	}
+
+	return n
+}
+
+// session is one review of a list of ARNs.
+type session struct {
+	in       *bufio.Scanner
+	out      io.Writer
+	families []*family
+	search   string
+	shown    []*family
+}
+
+// Run shows the ARNs grouped by family on out, takes commands from in until the operator
+// applies or quits, and returns the ARNs still selected. Everything starts selected. Quitting,
+// or reaching the end of in, returns none.
+func Run(in io.Reader, out io.Writer, arns []string) ([]string, error) {
+	s := &session{in: bufio.NewScanner(in), out: out, families: group(arns)}
+
+	fmt.Fprint(out, help)
+	s.list()
+
+	for {
+		line, ok := s.prompt("review> ")
+		if !ok {
+			return nil, s.in.Err()
+		}
+
+		command, arg := line, ""
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			command, arg = line[:i], strings.TrimSpace(line[i+1:])
+		}
+
+		switch {
+		case command == "":
+
+		case strings.HasPrefix(command, "/"):
+			s.search = strings.TrimSpace(line[1:])
+			s.list()
+
+		case command == "list" || command == "l":
+			s.list()
+
+		case command == "drop" || command == "d":
+			s.mark(arg, false)
+
+		case command == "add" || command == "a":
+			s.mark(arg, true)
+
+		case command == "apply":
+			if selected, ok := s.confirm(); ok {
+				return selected, nil
+			}
+
+		case command == "quit" || command == "q":
+			return nil, nil
+
+		default:
+			fmt.Fprint(out, help)
+		}
+	}
+}
+
+// group sorts ARNs into families, in name order, with revisions in ascending order.
+func group(arns []string) []*family {
+	byName := make(map[string]*family)
+	var families []*family
+
+	for _, arn := range arns {
+		name, number := arn, int64(0)
+		if parsed, err := planner.ParseTaskDefinitionARN(arn); err == nil {
+			name, number = parsed.Family, parsed.Revision
+		}
+
+		f, ok := byName[name]
+		if !ok {
+			f = &family{name: name}
+			byName[name] = f
+			families = append(families, f)
+		}
+
+		f.revisions = append(f.revisions, &revision{arn: arn, number: number, selected: true})
+	}
+
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+	for _, f := range families {
+		sort.Slice(f.revisions, func(i, j int) bool { return f.revisions[i].number < f.revisions[j].number })
+	}
+
+	return families
+}
+
+func (s *session) prompt(prompt string) (string, bool) {
+	fmt.Fprint(s.out, prompt)
+	if !s.in.Scan() {
+		fmt.Fprintln(s.out)
+		return "", false
+	}
+
+	return strings.TrimSpace(s.in.Text()), true
+}
+
+// list prints the families matching the search, numbering them for drop and add.
+func (s *session) list() {
+	s.shown = nil
+	for _, f := range s.families {
+		if strings.Contains(f.name, s.search) {
+			s.shown = append(s.shown, f)
+		}
+	}
+
+	for i, f := range s.shown {
+		fmt.Fprintf(s.out, "%3d. %s (%d of %d selected)\n", i+1, f.name, f.selected(), len(f.revisions))
+
+		marks := make([]string, len(f.revisions))
+		for j, r := range f.revisions {
+			mark := " "
+			if r.selected {
+				mark = "x"
+			}
+
+			marks[j] = fmt.Sprintf("[%s] %d", mark, r.number)
+		}
+
+		fmt.Fprintf(s.out, "       %s\n", strings.Join(marks, "  "))
+	}
+
+	fmt.Fprintf(s.out, "%d of %d families shown; %d task definitions selected.\n", len(s.shown), len(s.families), s.count())
+}
+
+// mark selects or deselects the family, or revision of a family, named by arg.
+func (s *session) mark(arg string, selected bool) {
+	name, rev := arg, ""
+	if i := strings.LastIndex(arg, ":"); i >= 0 {
+		name, rev = arg[:i], arg[i+1:]
+	}
+
+	f := s.find(name)
+	if f == nil {
+		fmt.Fprintf(s.out, "No family %q; use a name or a number from the list.\n", name)
+		return
+	}
+
+	var found bool
+	for _, r := range f.revisions {
+		if rev == "" || strconv.FormatInt(r.number, 10) == rev {
+			r.selected = selected
+			found = true
+		}
+	}
+
+	if !found {
+		fmt.Fprintf(s.out, "No revision %s of %s is a candidate.\n", rev, f.name)
+		return
+	}
+
+	fmt.Fprintf(s.out, "%s: %d of %d selected.\n", f.name, f.selected(), len(f.revisions))
+}
+
+func (s *session) find(name string) *family {
+	if i, err := strconv.Atoi(name); err == nil && i >= 1 && i <= len(s.shown) {
+		return s.shown[i-1]
+	}
+
+	for _, f := range s.families {
+		if f.name == name {
+			return f
+		}
+	}
+
+	return nil
+}
+
+func (s *session) count() int {
+	var n int
+	for _, f := range s.families {
+		n += f.selected()
+	}
+
+	return n
+}
+
+// confirm asks the operator to type the number of selected task definitions, and returns
+// them if they do.
+func (s *session) confirm() ([]string, bool) {
+	n := s.count()
+	if n == 0 {
+		fmt.Fprintln(s.out, "Nothing is selected; use quit to leave.")
+		return nil, false
+	}
+
+	answer, _ := s.prompt(fmt.Sprintf("Deregister %d task definitions? Type %d to confirm: ", n, n))
+	if answer != strconv.Itoa(n) {
+		fmt.Fprintln(s.out, "Not confirmed; nothing deregistered yet.")
+		return nil, false
+	}
+
+	var selected []string
+	for _, f := range s.families {
+		for _, r := range f.revisions {
+			if r.selected {
+				selected = append(selected, r.arn)
+			}
+		}
+	}
+
+	return selected, true
+}
@@ -0,0 +1,116 @@
+package review
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func arn(familyRevision string) string {
+	return "arn:aws:ecs:us-east-1:000000000000:task-definition/" + familyRevision
+}
+
+var testARNs = []string{arn("web:2"), arn("worker:5"), arn("web:1"), arn("worker:4"), arn("api:7")}
+
+func Test_Run(t *testing.T) {
+	testCases := map[string]struct {
+		Input    string
+		Expected []string
+	}{
+		"apply all": {
+			Input:    "apply\n5\n",
+			Expected: []string{arn("api:7"), arn("web:1"), arn("web:2"), arn("worker:4"), arn("worker:5")},
+		},
+		"drop family by name": {
+			Input:    "drop web\napply\n3\n",
+			Expected: []string{arn("api:7"), arn("worker:4"), arn("worker:5")},
+		},
+		"drop revision": {
+			Input:    "d worker:5\napply\n4\n",
+			Expected: []string{arn("api:7"), arn("web:1"), arn("web:2"), arn("worker:4")},
+		},
+		"drop by number after search": {
+			Input:    "/work\ndrop 1\napply\n3\n",
+			Expected: []string{arn("api:7"), arn("web:1"), arn("web:2")},
+		},
+		"add back": {
+			Input:    "drop web\nadd web:2\napply\n4\n",
+			Expected: []string{arn("api:7"), arn("web:2"), arn("worker:4"), arn("worker:5")},
+		},
+		"wrong confirmation": {
+			Input:    "apply\nyes\napply\n5\n",
+			Expected: []string{arn("api:7"), arn("web:1"), arn("web:2"), arn("worker:4"), arn("worker:5")},
+		},
+		"nothing selected": {
+			Input:    "drop 1\ndrop 2\ndrop 3\napply\nquit\n",
+			Expected: nil,
+		},
+		"unknown family": {
+			Input:    "drop db\ndrop web:9\napply\n5\n",
+			Expected: []string{arn("api:7"), arn("web:1"), arn("web:2"), arn("worker:4"), arn("worker:5")},
+		},
+		"quit": {
+			Input:    "drop web\nquit\n",
+			Expected: nil,
+		},
+		"end of input": {
+			Input:    "apply\n",
+			Expected: nil,
+		},
+	}
+
+	for name, testCase := range testCases {
+		var out bytes.Buffer
+
+		result, err := Run(strings.NewReader(testCase.Input), &out, testARNs)
+		if err != nil {
+			t.Fatalf("TestCase '%s': %v", name, err)
+		}
+
+		if !reflect.DeepEqual(testCase.Expected, result) {
+			t.Errorf("TestCase '%s': expected %v, got %v\n%s", name, testCase.Expected, result, out.String())
+		}
+	}
+}
+
+func Test_Run_List(t *testing.T) {
+	var out bytes.Buffer
+	if _, err := Run(strings.NewReader("drop worker:4\n/w\n"), &out, testARNs); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{
+		"  1. api (1 of 1 selected)\n       [x] 7\n",
+		"  1. web (2 of 2 selected)\n       [x] 1  [x] 2\n  2. worker (1 of 2 selected)\n       [ ] 4  [x] 5\n2 of 3 families shown; 4 task definitions selected.\n",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in:\n%s", expected, out.String())
+		}
+	}
+}
+
+func Test_IsTerminal(t *testing.T) {
+	f, err := ioutil.TempFile("", "review")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if IsTerminal(f) {
+		t.Error("Expected a regular file not to be a terminal")
+	}
+
+	null, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer null.Close()
+
+	if IsTerminal(null) {
+		t.Errorf("Expected %s not to be a terminal\n", os.DevNull)
+	}
+}
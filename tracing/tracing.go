@@ -0,0 +1,216 @@
+// Package tracing records OpenTelemetry spans of cleaner runs: one for each phase of a run,
+// each AWS API call it makes and each wait after throttling. They're exported over OTLP to a
+// collector, or as JSON lines to a local file for debugging without one.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"github.com/quintilesims/go-ecs-cleaner/planner"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.7.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Name names the cleaner's Tracer, and the service its spans come from.
+const Name = "go-ecs-cleaner"
+
+// The attributes the cleaner's spans carry, besides the standard RPC ones.
+const (
+	// Cluster is the ECS cluster an API call is about.
+	Cluster = attribute.Key("ecs.cluster")
+	// Family is the task definition family an API call or wait is about.
+	Family = attribute.Key("ecs.task_definition.family")
+	// TaskDefinition is the task definition an API call or wait is about.
+	TaskDefinition = attribute.Key("ecs.task_definition.arn")
+	// RequestID is the ID AWS gave an API call.
+	RequestID = attribute.Key("aws.request_id")
+	// RetryCount is how many times the SDK retried an API call on its own.
+	RetryCount = attribute.Key("aws.retry_count")
+	// BackoffAttempt is how many throttled calls in a row a wait follows.
+	BackoffAttempt = attribute.Key("backoff.attempt")
+	// BackoffDuration is how long a wait after throttling was, in milliseconds.
+	BackoffDuration = attribute.Key("backoff.duration_ms")
+)
+
+// NewProvider creates a TracerProvider that batches spans to each exporter. Shut it down to
+// flush the last of them.
+func NewProvider(exporters ...sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(Name))),
+	}
+
+	for _, exporter := range exporters {
+		options = append(options, sdktrace.WithBatcher(exporter))
+	}
+
+	return sdktrace.NewTracerProvider(options...)
+}
+
+// NewOTLPExporter creates an exporter that sends spans over OTLP/HTTP to a collector at
+// rawURL, e.g. "http://localhost:4318". The path defaults to "/v1/traces".
+func NewOTLPExporter(rawURL string) (sdktrace.SpanExporter, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("invalid OTLP endpoint %q: expected http://host:port or https://host:port", rawURL)
+	}
+
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(u.Host)}
+	if u.Scheme == "http" {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+
+	if u.Path != "" && u.Path != "/" {
+		options = append(options, otlptracehttp.WithURLPath(u.Path))
+	}
+
+	return otlptracehttp.New(context.Background(), options...)
+}
+
+// fileExporter writes spans as JSON lines to a file, and closes it on Shutdown.
+type fileExporter struct {
+	*stdouttrace.Exporter
+	f *os.File
+}
+
+// NewFileExporter creates an exporter that appends spans to the named file as JSON, one per
+// line, creating the file if it doesn't exist.
+func NewFileExporter(name string) (sdktrace.SpanExporter, error) {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &fileExporter{Exporter: exporter, f: f}, nil
+}
+
+// Shutdown stops the exporter and closes its file.
+func (e *fileExporter) Shutdown(ctx context.Context) error {
+	if err := e.Exporter.Shutdown(ctx); err != nil {
+		return err
+	}
+
+	return e.f.Close()
+}
+
+// Instrument gives every API call made by AWS clients with these handlers, e.g. a session's,
+// a span of its own, covering any retries the SDK makes. Calls made without a span in their
+// context are children of the span in the context parent returns, e.g. the phase of the run
+// that made them.
+func Instrument(handlers *request.Handlers, tracer trace.Tracer, parent func() context.Context) {
+	handlers.Validate.PushFront(func(r *request.Request) {
+		ctx := r.Context()
+		if !trace.SpanContextFromContext(ctx).IsValid() {
+			if p := parent(); p != nil {
+				ctx = p
+			}
+		}
+
+		service, operation := r.ClientInfo.ServiceName, r.Operation.Name
+
+		attrs := []attribute.KeyValue{
+			semconv.RPCSystemKey.String("aws-api"),
+			semconv.RPCServiceKey.String(service),
+			semconv.RPCMethodKey.String(operation),
+		}
+
+		ctx, _ = tracer.Start(ctx, service+"."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(append(attrs, Attributes(r.Params)...)...),
+		)
+
+		r.SetContext(ctx)
+	})
+
+	handlers.Complete.PushBack(func(r *request.Request) {
+		span := trace.SpanFromContext(r.Context())
+		span.SetAttributes(RetryCount.Int(r.RetryCount))
+
+		if r.RequestID != "" {
+			span.SetAttributes(RequestID.String(r.RequestID))
+		}
+
+		if r.Error != nil {
+			span.RecordError(r.Error)
+
+			if awsErr, ok := r.Error.(awserr.Error); ok {
+				span.SetStatus(codes.Error, awsErr.Code())
+			} else {
+				span.SetStatus(codes.Error, r.Error.Error())
+			}
+		}
+
+		span.End()
+	})
+}
+
+// Attributes describes what the input to an ECS API call is about: its cluster, or its task
+// definition and family.
+func Attributes(params interface{}) []attribute.KeyValue {
+	switch input := params.(type) {
+	case *ecs.ListServicesInput:
+		return clusterAttributes(input.Cluster)
+	case *ecs.DescribeServicesInput:
+		return clusterAttributes(input.Cluster)
+	case *ecs.ListTasksInput:
+		return clusterAttributes(input.Cluster)
+	case *ecs.DescribeTasksInput:
+		return clusterAttributes(input.Cluster)
+	case *ecs.ListTaskDefinitionsInput:
+		if input.FamilyPrefix != nil {
+			return []attribute.KeyValue{Family.String(*input.FamilyPrefix)}
+		}
+	case *ecs.DescribeTaskDefinitionInput:
+		return TaskDefinitionAttributes(aws.StringValue(input.TaskDefinition))
+	case *ecs.DeregisterTaskDefinitionInput:
+		return TaskDefinitionAttributes(aws.StringValue(input.TaskDefinition))
+	case *ecs.ListTagsForResourceInput:
+		return TaskDefinitionAttributes(aws.StringValue(input.ResourceArn))
+	case *ecs.TagResourceInput:
+		return TaskDefinitionAttributes(aws.StringValue(input.ResourceArn))
+	case *ecs.UntagResourceInput:
+		return TaskDefinitionAttributes(aws.StringValue(input.ResourceArn))
+	}
+
+	return nil
+}
+
+func clusterAttributes(cluster *string) []attribute.KeyValue {
+	if cluster == nil {
+		return nil
+	}
+
+	return []attribute.KeyValue{Cluster.String(*cluster)}
+}
+
+// TaskDefinitionAttributes describes a task definition, by ARN or "family:revision".
+func TaskDefinitionAttributes(arn string) []attribute.KeyValue {
+	if arn == "" {
+		return nil
+	}
+
+	attrs := []attribute.KeyValue{TaskDefinition.String(arn)}
+	if parsed, err := planner.ParseTaskDefinitionARN(arn); err == nil {
+		attrs = append(attrs, Family.String(parsed.Family))
+	}
+
+	return attrs
+}
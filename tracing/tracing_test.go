@@ -0,0 +1,125 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/ecs"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func Test_Attributes(t *testing.T) {
+	arn := "arn:aws:ecs:us-west-2:000000000000:task-definition/web:3"
+
+	testCases := map[string]struct {
+		params   interface{}
+		expected []attribute.KeyValue
+	}{
+		"cluster": {
+			params:   &ecs.ListServicesInput{Cluster: aws.String("cluster0")},
+			expected: []attribute.KeyValue{Cluster.String("cluster0")},
+		},
+		"task definition": {
+			params:   &ecs.DeregisterTaskDefinitionInput{TaskDefinition: aws.String(arn)},
+			expected: []attribute.KeyValue{TaskDefinition.String(arn), Family.String("web")},
+		},
+		"family prefix": {
+			params:   &ecs.ListTaskDefinitionsInput{FamilyPrefix: aws.String("web")},
+			expected: []attribute.KeyValue{Family.String("web")},
+		},
+		"neither": {
+			params: &ecs.ListClustersInput{},
+		},
+	}
+
+	for name, testCase := range testCases {
+		if result := Attributes(testCase.params); !reflect.DeepEqual(testCase.expected, result) {
+			t.Errorf("%s: expected %v, got %v\n", name, testCase.expected, result)
+		}
+	}
+}
+
+func Test_NewFileExporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tracing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "spans.jsonl")
+
+	exporter, err := NewFileExporter(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider := NewProvider(exporter)
+	_, span := provider.Tracer(Name).Start(context.Background(), "Discover")
+	span.End()
+
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	body, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result struct{ Name string }
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Name != "Discover" {
+		t.Errorf("Expected the Discover span, got %s\n", body)
+	}
+}
+
+func Test_NewOTLPExporter(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+	}))
+	defer collector.Close()
+
+	exporter, err := NewOTLPExporter(collector.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider := NewProvider(exporter)
+	_, span := provider.Tracer(Name).Start(context.Background(), "Discover")
+	span.End()
+
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if expected := []string{"/v1/traces"}; !reflect.DeepEqual(expected, paths) {
+		t.Errorf("Expected %v, got %v\n", expected, paths)
+	}
+
+	for _, rawURL := range []string{"localhost:4318", "grpc://localhost:4317", "http://"} {
+		if _, err := NewOTLPExporter(rawURL); err == nil || !strings.Contains(err.Error(), "invalid OTLP endpoint") {
+			t.Errorf("Expected an error for %q, got %v\n", rawURL, err)
+		}
+	}
+}